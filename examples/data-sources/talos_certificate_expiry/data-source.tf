@@ -0,0 +1,22 @@
+resource "talos_machine_secrets" "this" {}
+
+resource "talos_cluster_kubeconfig" "this" {
+  client_configuration = talos_machine_secrets.this.client_configuration
+  node                 = "10.5.0.2"
+}
+
+data "talos_certificate_expiry" "this" {
+  machine_secrets      = talos_machine_secrets.this.machine_secrets
+  client_configuration = talos_machine_secrets.this.client_configuration
+  kubeconfig_raw       = talos_cluster_kubeconfig.this.kubeconfig_raw
+  nodes                = ["10.5.0.2"]
+}
+
+check "certificates" {
+  assert {
+    condition = data.talos_certificate_expiry.this.min_days_remaining > 30
+    error_message = "certificates expiring within 30 days: ${join(", ", [
+      for c in data.talos_certificate_expiry.this.certificates : "${c.source} (${c.not_after})" if c.days_remaining <= 30
+    ])}"
+  }
+}
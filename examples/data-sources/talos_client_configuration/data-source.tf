@@ -5,3 +5,13 @@ data "talos_client_configuration" "this" {
   client_configuration = talos_machine_secrets.this.client_configuration
   nodes                = ["10.5.0.2"]
 }
+
+# read-only talosconfig for monitoring, valid for 30 days from not_before
+data "talos_client_configuration" "monitoring" {
+  cluster_name    = "example-cluster"
+  machine_secrets = talos_machine_secrets.this.machine_secrets
+  roles           = ["os:reader"]
+  not_before      = "2025-01-01T00:00:00Z"
+  crt_ttl         = "720h"
+  nodes           = ["10.5.0.2"]
+}
@@ -0,0 +1,28 @@
+resource "talos_machine_secrets" "this" {}
+
+data "talos_cluster_kubernetes_upgrade_plan" "this" {
+  client_configuration = talos_machine_secrets.this.client_configuration
+  endpoint             = "10.5.0.2"
+  kubernetes_version   = "v1.33.0"
+}
+
+resource "talos_cluster" "this" {
+  node                 = "10.5.0.2"
+  client_configuration = talos_machine_secrets.this.client_configuration
+  kubernetes_version   = "v1.32.0"
+
+  # pull the new images ahead of the maintenance window, the upgrade then skips that step
+  pre_pull_kubernetes_version = data.talos_cluster_kubernetes_upgrade_plan.this.to_version
+  pre_pull_images             = false
+
+  lifecycle {
+    precondition {
+      condition     = length(data.talos_cluster_kubernetes_upgrade_plan.this.removed_items) == 0
+      error_message = "Kubernetes upgrade is blocked by removed flags or API resources still in use."
+    }
+  }
+}
+
+output "manifest_changes" {
+  value = data.talos_cluster_kubernetes_upgrade_plan.this.manifest_changes
+}
@@ -0,0 +1,30 @@
+resource "talos_machine_secrets" "this" {}
+
+data "talos_etcd_members" "this" {
+  client_configuration = talos_machine_secrets.this.client_configuration
+  nodes                = ["10.5.0.2", "10.5.0.3", "10.5.0.4"]
+}
+
+resource "talos_cluster" "this" {
+  node                 = "10.5.0.2"
+  control_plane_nodes  = ["10.5.0.2", "10.5.0.3"]
+  client_configuration = talos_machine_secrets.this.client_configuration
+  kubernetes_version   = "v1.32.0"
+
+  lifecycle {
+    precondition {
+      # removing a control plane node needs every remaining voting member to be healthy
+      condition     = data.talos_etcd_members.this.healthy_voting_members == data.talos_etcd_members.this.voting_members
+      error_message = "Not all etcd members are healthy, refusing to change control plane membership."
+    }
+
+    precondition {
+      condition     = alltrue([for m in data.talos_etcd_members.this.members : length(m.alarms) == 0])
+      error_message = "etcd has active alarms."
+    }
+  }
+}
+
+output "etcd_leader" {
+  value = data.talos_etcd_members.this.leader
+}
@@ -0,0 +1,24 @@
+resource "talos_machine_secrets" "this" {}
+
+resource "talos_image_factory_schematic" "this" {
+  schematic = yamlencode({
+    customization = {
+      systemExtensions = {
+        officialExtensions = ["siderolabs/iscsi-tools", "siderolabs/util-linux-tools"]
+      }
+    }
+  })
+}
+
+data "talos_machine_extensions" "this" {
+  client_configuration = talos_machine_secrets.this.client_configuration
+  node                 = "10.5.0.2"
+  schematic_id         = talos_image_factory_schematic.this.id
+
+  lifecycle {
+    postcondition {
+      condition     = length(self.missing_extensions) == 0
+      error_message = "node is missing extensions: ${join(", ", self.missing_extensions)}"
+    }
+  }
+}
@@ -0,0 +1,23 @@
+resource "talos_machine_secrets" "this" {}
+
+data "talos_machine_info" "this" {
+  client_configuration = talos_machine_secrets.this.client_configuration
+  node                 = "10.5.0.2"
+}
+
+data "talos_machine_configuration" "this" {
+  cluster_name     = "example-cluster"
+  machine_type     = "controlplane"
+  cluster_endpoint = "https://10.5.0.2:6443"
+  machine_secrets  = talos_machine_secrets.this.machine_secrets
+  config_patches = [
+    yamlencode({
+      machine = {
+        network = {
+          # derive the hostname from the hardware serial number
+          hostname = "node-${lower(coalesce(data.talos_machine_info.this.serial_number, data.talos_machine_info.this.node_id))}"
+        }
+      }
+    })
+  ]
+}
@@ -0,0 +1,18 @@
+resource "talos_machine_secrets" "this" {}
+
+data "talos_machine_network" "this" {
+  client_configuration = talos_machine_secrets.this.client_configuration
+  node                 = "10.5.0.2"
+  link_selector        = "link.kind == \"\" && link.type == \"ether\""
+  address_family       = "inet4"
+  address_scope        = "global"
+}
+
+# e.g. register the node in DNS
+output "fqdn" {
+  value = join(".", compact([data.talos_machine_network.this.hostname, data.talos_machine_network.this.domainname]))
+}
+
+output "ipv4_addresses" {
+  value = data.talos_machine_network.this.addresses[*].ip
+}
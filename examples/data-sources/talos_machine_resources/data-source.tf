@@ -0,0 +1,23 @@
+resource "talos_machine_secrets" "this" {}
+
+data "talos_machine_resources" "addresses" {
+  client_configuration = talos_machine_secrets.this.client_configuration
+  node                 = "10.5.0.2"
+  type                 = "addresses"
+  label_selector       = "!talos.dev/managed-by"
+}
+
+data "talos_machine_resources" "hostname" {
+  client_configuration = talos_machine_secrets.this.client_configuration
+  node                 = "10.5.0.2"
+  type                 = "hostnamestatus"
+  resource_id          = "hostname"
+}
+
+output "addresses" {
+  value = [for r in data.talos_machine_resources.addresses.resources : jsondecode(r.spec_json).address]
+}
+
+output "hostname" {
+  value = data.talos_machine_resources.hostname.decoded[0].spec.hostname
+}
@@ -0,0 +1,20 @@
+resource "talos_machine_secrets" "this" {}
+
+# wait for etcd and the kubelet before reading the kubeconfig
+data "talos_machine_services" "controlplane" {
+  client_configuration = talos_machine_secrets.this.client_configuration
+  node                 = "10.5.0.2"
+
+  wait_for = {
+    services = ["etcd", "kubelet"]
+  }
+
+  timeouts = {
+    read = "15m"
+  }
+}
+
+data "talos_cluster_kubeconfig" "this" {
+  client_configuration = talos_machine_secrets.this.client_configuration
+  node                 = data.talos_machine_services.controlplane.node
+}
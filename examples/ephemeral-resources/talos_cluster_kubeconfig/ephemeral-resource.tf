@@ -23,3 +23,14 @@ ephemeral "talos_cluster_kubeconfig" "stable" {
   not_before      = terraform_data.kubeconfig_nbf.output
   crt_ttl         = "87600h"
 }
+
+# Kubeconfig for a non-admin user; bind the "dev" group to a namespaced Role in Kubernetes RBAC.
+ephemeral "talos_cluster_kubeconfig" "dev" {
+  cluster_name    = "example-cluster"
+  machine_secrets = ephemeral.talos_machine_secrets.this.machine_secrets
+  endpoint        = "https://10.5.0.2:6443"
+  username        = "jane"
+  groups          = ["dev"]
+  not_before      = terraform_data.kubeconfig_nbf.output
+  crt_ttl         = "720h"
+}
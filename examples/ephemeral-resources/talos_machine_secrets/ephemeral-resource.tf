@@ -0,0 +1,21 @@
+resource "talos_machine_secrets" "this" {
+  age_recipients = ["age1ql3z7hjy54pw3hyww5ayyfg7zqgvc7w3j2elw8zmrj2kg5sfn9aqmcac8p"]
+}
+
+# decrypt the secrets in memory, with the age identity in SOPS_AGE_KEY or SOPS_AGE_KEY_FILE
+ephemeral "talos_machine_secrets" "this" {
+  encrypted_secrets = talos_machine_secrets.this.encrypted_secrets
+}
+
+ephemeral "talos_client_configuration" "this" {
+  cluster_name         = "example-cluster"
+  client_configuration = ephemeral.talos_machine_secrets.this.client_configuration
+  nodes                = ["10.5.0.2"]
+}
+
+# derive the same secrets on every run from a seed kept in a secret manager
+ephemeral "talos_machine_secrets" "seeded" {
+  talos_version   = "v1.11"
+  seed            = var.machine_secrets_seed
+  seed_not_before = "2025-01-01T00:00:00Z"
+}
@@ -0,0 +1,27 @@
+resource "talos_machine_secrets" "this" {}
+
+resource "talos_ca_rotation" "os" {
+  ca                   = "os"
+  control_plane_nodes  = ["10.5.0.2", "10.5.0.3", "10.5.0.4"]
+  worker_nodes         = ["10.5.0.5"]
+  client_configuration = talos_machine_secrets.this.client_configuration
+
+  # rotate again by changing the value
+  triggers = {
+    generation = "1"
+  }
+}
+
+# once the rotation completed, generate machine configurations from the new CA
+locals {
+  machine_secrets = merge(talos_machine_secrets.this.machine_secrets, {
+    certs = merge(talos_machine_secrets.this.machine_secrets.certs, {
+      os = talos_ca_rotation.os.new_ca
+    })
+  })
+}
+
+output "talos_client_configuration" {
+  value     = talos_ca_rotation.os.new_client_configuration
+  sensitive = true
+}
@@ -44,3 +44,29 @@ resource "talos_cluster_kubeconfig" "this" {
   client_configuration = talos_machine_secrets.this.client_configuration
   node                 = "10.5.0.2"
 }
+
+# a kubeconfig for developers, with short-lived credentials from an exec plugin
+# and a second context talking to a single node instead of the VIP
+resource "talos_cluster_kubeconfig" "developers" {
+  depends_on = [
+    talos_machine_bootstrap.this
+  ]
+  client_configuration = talos_machine_secrets.this.client_configuration
+  node                 = "10.5.0.2"
+
+  exec = {
+    command = "kubectl"
+    args    = ["oidc-login", "get-token", "--oidc-issuer-url=https://issuer.example.com", "--oidc-client-id=kubernetes"]
+  }
+
+  contexts = [
+    {
+      name      = "example-cluster"
+      namespace = "apps"
+    },
+    {
+      name   = "example-cluster-node"
+      server = "https://10.5.0.2:6443"
+    },
+  ]
+}
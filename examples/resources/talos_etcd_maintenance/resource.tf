@@ -0,0 +1,20 @@
+resource "talos_machine_secrets" "this" {}
+
+resource "time_rotating" "etcd_defrag" {
+  rotation_days = 7
+}
+
+resource "talos_etcd_maintenance" "weekly" {
+  nodes                = ["10.5.0.2", "10.5.0.3", "10.5.0.4"]
+  client_configuration = talos_machine_secrets.this.client_configuration
+  disarm_alarms        = true
+
+  # defragment again every week
+  triggers = {
+    rotation = time_rotating.etcd_defrag.id
+  }
+}
+
+output "etcd_freed_bytes" {
+  value = { for m in talos_etcd_maintenance.weekly.members : m.hostname => m.freed_bytes }
+}
@@ -0,0 +1,21 @@
+resource "talos_machine_secrets" "this" {}
+
+resource "talos_etcd_snapshot" "pre_upgrade" {
+  node                 = "10.5.0.2"
+  client_configuration = talos_machine_secrets.this.client_configuration
+  path                 = "${path.root}/backups/etcd-pre-upgrade.db.gz.age"
+  compress             = true
+  age_recipients       = ["age1ql3z7hjy54pw3hyww5ayyfg7zqgvc7w3j2elw8zmrj2kg5sfn9aqmcac8p"]
+
+  # Take a fresh snapshot whenever the target Kubernetes version changes.
+  triggers = {
+    kubernetes_version = "v1.32.0"
+  }
+}
+
+resource "talos_cluster" "this" {
+  depends_on           = [talos_etcd_snapshot.pre_upgrade]
+  node                 = "10.5.0.2"
+  client_configuration = talos_machine_secrets.this.client_configuration
+  kubernetes_version   = "v1.32.0"
+}
@@ -1,2 +1,12 @@
 # machine secrets can be imported from an existing secrets file
 terraform import talos_machine_secrets.this <path-to-secrets.yaml>
+
+# or extracted from a control plane machine configuration
+terraform import talos_machine_secrets.this config:<path-to-controlplane.yaml>
+
+# or from the active configuration of a running control plane node,
+# authenticating with the default talosconfig (TALOSCONFIG or ~/.talos/config)
+terraform import talos_machine_secrets.this node:<address>
+
+# talos_version is set to the Talos version the node runs for node imports,
+# otherwise to the oldest Talos version the secrets could have been generated for
@@ -1 +1,40 @@
 resource "talos_machine_secrets" "machine_secrets" {}
+
+# the secrets in the format consumed by `talosctl gen config --with-secrets`
+output "secrets_yaml" {
+  value     = talos_machine_secrets.machine_secrets.secrets_yaml
+  sensitive = true
+}
+
+# use CAs issued by an external PKI, the chain up to the root follows the intermediate certificate
+resource "talos_machine_secrets" "external_ca" {
+  certificate_authorities = {
+    k8s = {
+      cert = file("${path.module}/kubernetes-ca-chain.pem")
+      key  = file("${path.module}/kubernetes-ca.key")
+    }
+    os = {
+      cert = file("${path.module}/talos-ca-chain.pem")
+      key  = file("${path.module}/talos-ca.key")
+    }
+  }
+}
+
+# keep only age encrypted secrets in the state, decrypted in memory by the ephemeral resource
+# with the age identity in SOPS_AGE_KEY or SOPS_AGE_KEY_FILE
+resource "talos_machine_secrets" "encrypted" {
+  age_recipients = ["age1ql3z7hjy54pw3hyww5ayyfg7zqgvc7w3j2elw8zmrj2kg5sfn9aqmcac8p"]
+}
+
+# use secrets.yaml from `talosctl gen secrets`, encrypted with `sops --encrypt --age`
+resource "talos_machine_secrets" "sops" {
+  sops_file      = "${path.module}/secrets.enc.yaml"
+  age_recipients = ["age1ql3z7hjy54pw3hyww5ayyfg7zqgvc7w3j2elw8zmrj2kg5sfn9aqmcac8p"]
+}
+
+# derive the secrets from a seed kept in a secret manager, so they can be derived again if the state is lost
+resource "talos_machine_secrets" "seeded" {
+  talos_version   = "v1.11"
+  seed_wo         = var.machine_secrets_seed
+  seed_not_before = "2025-01-01T00:00:00Z"
+}
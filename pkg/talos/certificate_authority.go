@@ -0,0 +1,268 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package talos
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	stdlibx509 "crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-framework/types/basetypes"
+	"github.com/siderolabs/crypto/x509"
+	"github.com/siderolabs/talos/pkg/machinery/config/generate/secrets"
+)
+
+// certificateAuthorityNames lists the CAs of the machine secrets which can be supplied externally.
+var certificateAuthorityNames = []string{"etcd", "k8s", "k8s_aggregator", "os"}
+
+// certificateAuthorityKeyTypes lists the key types supported for each CA of the machine secrets.
+//
+// The Kubernetes CA is restricted to ECDSA, as kubeconfigs are signed with deterministic ECDSA (RFC 6979),
+// and the Talos API CA to Ed25519 and ECDSA, the key types supported for generated client configurations.
+var certificateAuthorityKeyTypes = map[string][]string{
+	"etcd":           {"ECDSA", "RSA"},
+	"k8s":            {"ECDSA"},
+	"k8s_aggregator": {"ECDSA", "RSA"},
+	"os":             {"Ed25519", "ECDSA"},
+}
+
+// parseCertificateAuthority validates an externally supplied CA for the machine secrets and returns it in the encoding Talos uses.
+//
+// certPEM holds the CA certificate, optionally followed by the intermediate certificates up to the root;
+// the chain is kept as is, so it ends up in the generated machine configurations.
+// The CA must be valid at now, have the CA basic constraint and the certificate signing key usage, and match keyPEM.
+func parseCertificateAuthority(name, certPEM, keyPEM string, now time.Time) (*x509.PEMEncodedCertificateAndKey, error) {
+	chain, chainPEM, err := parseCertificateChain([]byte(certPEM))
+	if err != nil {
+		return nil, err
+	}
+
+	ca := chain[0]
+
+	if !ca.BasicConstraintsValid || !ca.IsCA {
+		return nil, fmt.Errorf("certificate %q is not a CA", ca.Subject)
+	}
+
+	if ca.KeyUsage&stdlibx509.KeyUsageCertSign == 0 {
+		return nil, fmt.Errorf("certificate %q is not allowed to sign certificates", ca.Subject)
+	}
+
+	for _, cert := range chain {
+		if now.Before(cert.NotBefore) || now.After(cert.NotAfter) {
+			return nil, fmt.Errorf("certificate %q is only valid from %s to %s", cert.Subject, cert.NotBefore.Format(time.RFC3339), cert.NotAfter.Format(time.RFC3339))
+		}
+	}
+
+	for i := range len(chain) - 1 {
+		if err = chain[i].CheckSignatureFrom(chain[i+1]); err != nil {
+			return nil, fmt.Errorf("certificate %q is not signed by the next certificate in the chain %q: %w", chain[i].Subject, chain[i+1].Subject, err)
+		}
+	}
+
+	key, keyType, normalizedKeyPEM, err := parseCertificateAuthorityKey([]byte(keyPEM))
+	if err != nil {
+		return nil, err
+	}
+
+	if allowed := certificateAuthorityKeyTypes[name]; !slices.Contains(allowed, keyType) {
+		return nil, fmt.Errorf("%s keys are not supported for the %s CA, use one of %q", keyType, name, allowed)
+	}
+
+	public, ok := key.Public().(interface{ Equal(crypto.PublicKey) bool })
+	if !ok || !public.Equal(ca.PublicKey) {
+		return nil, fmt.Errorf("the key doesn't match the certificate %q", ca.Subject)
+	}
+
+	return &x509.PEMEncodedCertificateAndKey{
+		Crt: chainPEM,
+		Key: normalizedKeyPEM,
+	}, nil
+}
+
+// parseCertificateChain parses all certificates in data, dropping anything that is not a certificate block.
+func parseCertificateChain(data []byte) ([]*stdlibx509.Certificate, []byte, error) {
+	var (
+		chain    []*stdlibx509.Certificate
+		chainPEM bytes.Buffer
+	)
+
+	for {
+		var block *pem.Block
+
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+
+		if block.Type != x509.PEMTypeCertificate {
+			continue
+		}
+
+		cert, err := stdlibx509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error parsing certificate: %w", err)
+		}
+
+		chain = append(chain, cert)
+
+		if err = pem.Encode(&chainPEM, &pem.Block{Type: x509.PEMTypeCertificate, Bytes: block.Bytes}); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if len(chain) == 0 {
+		return nil, nil, errors.New("no PEM encoded certificate found")
+	}
+
+	return chain, chainPEM.Bytes(), nil
+}
+
+// parseCertificateAuthorityKey parses a PEM encoded private key in PKCS#1, SEC 1 or PKCS#8 form,
+// and re-encodes it the way Talos generates keys of that type.
+func parseCertificateAuthorityKey(data []byte) (key crypto.Signer, keyType string, keyPEM []byte, err error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, "", nil, errors.New("no PEM encoded private key found")
+	}
+
+	var parsed any
+
+	switch block.Type {
+	case x509.PEMTypeRSAPrivate:
+		parsed, err = stdlibx509.ParsePKCS1PrivateKey(block.Bytes)
+	case x509.PEMTypeECPrivate:
+		parsed, err = stdlibx509.ParseECPrivateKey(block.Bytes)
+	case x509.PEMTypePrivate, x509.PEMTypeEd25519Private:
+		parsed, err = stdlibx509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		return nil, "", nil, fmt.Errorf("unsupported private key PEM type %q", block.Type)
+	}
+
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("error parsing private key: %w", err)
+	}
+
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		keyPEM = pem.EncodeToMemory(&pem.Block{Type: x509.PEMTypeRSAPrivate, Bytes: stdlibx509.MarshalPKCS1PrivateKey(k)})
+
+		return k, "RSA", keyPEM, nil
+	case *ecdsa.PrivateKey:
+		der, marshalErr := stdlibx509.MarshalECPrivateKey(k)
+		if marshalErr != nil {
+			return nil, "", nil, marshalErr
+		}
+
+		return k, "ECDSA", pem.EncodeToMemory(&pem.Block{Type: x509.PEMTypeECPrivate, Bytes: der}), nil
+	case ed25519.PrivateKey:
+		der, marshalErr := stdlibx509.MarshalPKCS8PrivateKey(k)
+		if marshalErr != nil {
+			return nil, "", nil, marshalErr
+		}
+
+		return k, "Ed25519", pem.EncodeToMemory(&pem.Block{Type: x509.PEMTypeEd25519Private, Bytes: der}), nil
+	default:
+		return nil, "", nil, fmt.Errorf("unsupported private key type %T", parsed)
+	}
+}
+
+// certificateAuthoritiesAt reads the certificate_authorities attribute using getAttribute (a config, plan or state getter).
+// CAs which are not set are omitted, unknown CAs are returned with an unknown certificate and key.
+func certificateAuthoritiesAt(
+	ctx context.Context,
+	getAttribute func(context.Context, path.Path, any) diag.Diagnostics,
+) (map[string]machineSecretsCertKeyPair, diag.Diagnostics) {
+	var diags diag.Diagnostics
+
+	cas := map[string]machineSecretsCertKeyPair{}
+
+	for _, name := range certificateAuthorityNames {
+		var obj types.Object
+
+		diags.Append(getAttribute(ctx, path.Root("certificate_authorities").AtName(name), &obj)...)
+
+		if diags.HasError() {
+			return nil, diags
+		}
+
+		switch {
+		case obj.IsNull():
+		case obj.IsUnknown():
+			cas[name] = machineSecretsCertKeyPair{Cert: types.StringUnknown(), Key: types.StringUnknown()}
+		default:
+			var ca machineSecretsCertKeyPair
+
+			diags.Append(obj.As(ctx, &ca, basetypes.ObjectAsOptions{})...)
+
+			cas[name] = ca
+		}
+	}
+
+	return cas, diags
+}
+
+// changedCertificateAuthorities returns the names of the CAs set in plan which differ from state.
+// A CA removed from the plan is not a change, the CA in use is kept.
+func changedCertificateAuthorities(plan, state map[string]machineSecretsCertKeyPair) []string {
+	var changed []string
+
+	for _, name := range certificateAuthorityNames {
+		planCA, ok := plan[name]
+		if !ok {
+			continue
+		}
+
+		if stateCA := state[name]; !planCA.Cert.Equal(stateCA.Cert) || !planCA.Key.Equal(stateCA.Key) {
+			changed = append(changed, name)
+		}
+	}
+
+	return changed
+}
+
+// setBundleCertificateAuthority replaces the named CA in the secrets bundle.
+func setBundleCertificateAuthority(bundle *secrets.Bundle, name string, ca *x509.PEMEncodedCertificateAndKey) {
+	switch name {
+	case "etcd":
+		bundle.Certs.Etcd = ca
+	case "k8s":
+		bundle.Certs.K8s = ca
+	case "k8s_aggregator":
+		bundle.Certs.K8sAggregator = ca
+	case "os":
+		bundle.Certs.OS = ca
+	}
+}
+
+// certificateAuthoritiesAttributeTypes returns the attribute types of the certificate_authorities attribute.
+func certificateAuthoritiesAttributeTypes() map[string]attr.Type {
+	caType := types.ObjectType{
+		AttrTypes: map[string]attr.Type{
+			"cert": types.StringType,
+			"key":  types.StringType,
+		},
+	}
+
+	attrTypes := map[string]attr.Type{}
+
+	for _, name := range certificateAuthorityNames {
+		attrTypes[name] = caType
+	}
+
+	return attrTypes
+}
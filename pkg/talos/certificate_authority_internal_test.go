@@ -0,0 +1,186 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package talos //nolint:testpackage // needs access to unexported certificate authority helpers
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/siderolabs/talos/pkg/machinery/config"
+	"github.com/siderolabs/talos/pkg/machinery/config/generate/secrets"
+)
+
+type testCertificate struct {
+	cert *x509.Certificate
+	key  crypto.Signer
+	pem  string
+}
+
+func newTestCertificate(t *testing.T, name string, isCA bool, notAfter time.Time, key crypto.Signer, parent *testCertificate) *testCertificate {
+	t.Helper()
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              notAfter,
+		BasicConstraintsValid: true,
+		IsCA:                  isCA,
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+	}
+
+	if isCA {
+		template.KeyUsage |= x509.KeyUsageCertSign
+	}
+
+	parentCert, parentKey := template, key
+	if parent != nil {
+		parentCert, parentKey = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parentCert, key.Public(), parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &testCertificate{
+		cert: cert,
+		key:  key,
+		pem:  string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+	}
+}
+
+func newTestECDSAKey(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return key
+}
+
+func pkcs8KeyPEM(t *testing.T, key crypto.Signer) string {
+	t.Helper()
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+}
+
+func TestParseCertificateAuthority(t *testing.T) {
+	t.Parallel()
+
+	notAfter := time.Now().AddDate(1, 0, 0)
+
+	root := newTestCertificate(t, "root", true, notAfter, newTestECDSAKey(t), nil)
+	intermediate := newTestCertificate(t, "intermediate", true, notAfter, newTestECDSAKey(t), root)
+	expired := newTestCertificate(t, "expired", true, time.Now().Add(-time.Minute), newTestECDSAKey(t), root)
+	leaf := newTestCertificate(t, "leaf", false, notAfter, newTestECDSAKey(t), root)
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rsaCA := newTestCertificate(t, "rsa", true, notAfter, rsaKey, nil)
+
+	for _, test := range []struct {
+		name          string
+		ca            string
+		cert          string
+		key           string
+		expectedError string
+	}{
+		{name: "intermediate with chain", ca: "k8s", cert: intermediate.pem + root.pem, key: pkcs8KeyPEM(t, intermediate.key)},
+		{name: "rsa etcd", ca: "etcd", cert: rsaCA.pem, key: pkcs8KeyPEM(t, rsaCA.key)},
+		{name: "rsa kubernetes", ca: "k8s", cert: rsaCA.pem, key: pkcs8KeyPEM(t, rsaCA.key), expectedError: "RSA keys are not supported for the k8s CA"},
+		{name: "not a ca", ca: "os", cert: leaf.pem, key: pkcs8KeyPEM(t, leaf.key), expectedError: "is not a CA"},
+		{name: "expired", ca: "os", cert: expired.pem, key: pkcs8KeyPEM(t, expired.key), expectedError: "is only valid from"},
+		{name: "key mismatch", ca: "os", cert: intermediate.pem, key: pkcs8KeyPEM(t, root.key), expectedError: "the key doesn't match"},
+		{name: "chain out of order", ca: "os", cert: root.pem + intermediate.pem, key: pkcs8KeyPEM(t, root.key), expectedError: "is not signed by the next certificate"},
+		{name: "no certificate", ca: "os", cert: "", key: pkcs8KeyPEM(t, root.key), expectedError: "no PEM encoded certificate found"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			ca, err := parseCertificateAuthority(test.ca, test.cert, test.key, time.Now())
+			if test.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), test.expectedError) {
+					t.Fatalf("expected error containing %q, got %v", test.expectedError, err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(ca.Crt) != test.cert {
+				t.Error("expected the certificate chain to be kept")
+			}
+
+			if block, _ := pem.Decode(ca.Key); block == nil || block.Type == "PRIVATE KEY" {
+				t.Errorf("expected the key to be re-encoded in the Talos format, got %q", ca.Key)
+			}
+		})
+	}
+}
+
+func TestSetBundleCertificateAuthority(t *testing.T) {
+	t.Parallel()
+
+	notAfter := time.Now().AddDate(1, 0, 0)
+
+	root := newTestCertificate(t, "root", true, notAfter, newTestECDSAKey(t), nil)
+	intermediate := newTestCertificate(t, "intermediate", true, notAfter, newTestECDSAKey(t), root)
+
+	ca, err := parseCertificateAuthority("os", intermediate.pem+root.pem, pkcs8KeyPEM(t, intermediate.key), time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	secretsBundle, err := secrets.NewBundle(secrets.NewFixedClock(time.Now()), config.TalosVersionCurrent)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	setBundleCertificateAuthority(secretsBundle, "os", ca)
+
+	model, err := secretsBundleTomachineSecrets(secretsBundle)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if model.MachineSecrets.Certs.OS.Cert.ValueString() != bytesToBase64([]byte(intermediate.pem+root.pem)) {
+		t.Error("expected the machine secrets to keep the certificate chain")
+	}
+
+	clientCert := parseTestClientCertificate(t, model.ClientConfiguration)
+
+	if err = clientCert.CheckSignatureFrom(intermediate.cert); err != nil {
+		t.Errorf("expected the client certificate to be signed by the intermediate CA: %v", err)
+	}
+}
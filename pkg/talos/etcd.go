@@ -0,0 +1,469 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package talos
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"filippo.io/age"
+	"github.com/cosi-project/runtime/pkg/safe"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/retry"
+	machineapi "github.com/siderolabs/talos/pkg/machinery/api/machine"
+	"github.com/siderolabs/talos/pkg/machinery/client"
+	etcdresource "github.com/siderolabs/talos/pkg/machinery/resources/etcd"
+)
+
+// etcdMemberList returns the etcd members as seen by node.
+func etcdMemberList(ctx context.Context, c *client.Client, node string) ([]*machineapi.EtcdMember, error) {
+	resp, err := c.EtcdMemberList(client.WithNode(ctx, node), &machineapi.EtcdMemberListRequest{})
+	if err != nil {
+		return nil, err
+	}
+
+	var members []*machineapi.EtcdMember
+
+	for _, msg := range resp.GetMessages() {
+		members = append(members, msg.GetMembers()...)
+	}
+
+	return members, nil
+}
+
+// etcdMemberState is a member together with the status reported by the member itself and its active alarms.
+type etcdMemberState struct {
+	member    *machineapi.EtcdMember
+	status    *machineapi.EtcdMemberStatus
+	statusErr error
+	alarms    []string
+}
+
+// healthy reports whether the member answered the status request without errors.
+func (m etcdMemberState) healthy() bool {
+	return m.statusErr == nil && m.status != nil && len(m.status.GetErrors()) == 0
+}
+
+// responsive reports whether the member answered the status request and follows a leader. Unlike healthy,
+// it tolerates alarms, which maintenance is expected to clear.
+func (m etcdMemberState) responsive() bool {
+	return m.statusErr == nil && m.status.GetLeader() != 0
+}
+
+// etcdClusterState lists etcd members through the first node that answers, then asks every member for its status.
+// Status failures are recorded per member rather than failing the whole call.
+func etcdClusterState(ctx context.Context, c *client.Client, nodes []string) ([]etcdMemberState, error) {
+	var (
+		members []*machineapi.EtcdMember
+		via     string
+		errs    []error
+	)
+
+	for _, node := range nodes {
+		var err error
+
+		if members, err = etcdMemberList(ctx, c, node); err == nil {
+			via = node
+
+			break
+		}
+
+		errs = append(errs, fmt.Errorf("%s: %w", node, err))
+	}
+
+	if via == "" {
+		return nil, fmt.Errorf("error listing etcd members: %w", errors.Join(errs...))
+	}
+
+	alarms := map[uint64][]string{}
+
+	if resp, err := c.EtcdAlarmList(client.WithNode(ctx, via)); err != nil {
+		tflog.Warn(ctx, "failed to list etcd alarms", map[string]any{"node": via, "error": err.Error()})
+	} else {
+		for _, msg := range resp.GetMessages() {
+			for _, alarm := range msg.GetMemberAlarms() {
+				if alarm.GetAlarm() == machineapi.EtcdMemberAlarm_NONE {
+					continue
+				}
+
+				if name := alarm.GetAlarm().String(); !slices.Contains(alarms[alarm.GetMemberId()], name) {
+					alarms[alarm.GetMemberId()] = append(alarms[alarm.GetMemberId()], name)
+				}
+			}
+		}
+	}
+
+	result := etcdMembersStatus(ctx, c, members, alarms)
+
+	slices.SortFunc(result, func(a, b etcdMemberState) int {
+		return strings.Compare(a.member.GetHostname(), b.member.GetHostname())
+	})
+
+	return result, nil
+}
+
+// etcdMembersStatus asks every member for its status, recording status failures per member.
+func etcdMembersStatus(ctx context.Context, c *client.Client, members []*machineapi.EtcdMember, alarms map[uint64][]string) []etcdMemberState {
+	result := make([]etcdMemberState, 0, len(members))
+
+	for _, member := range members {
+		state := etcdMemberState{
+			member: member,
+			alarms: alarms[member.GetId()],
+		}
+
+		resp, err := c.EtcdStatus(client.WithNode(ctx, etcdMemberHost(member)))
+		if err != nil {
+			state.statusErr = err
+		} else if msgs := resp.GetMessages(); len(msgs) > 0 {
+			state.status = msgs[0].GetMemberStatus()
+		} else {
+			state.statusErr = errors.New("empty etcd status response")
+		}
+
+		result = append(result, state)
+	}
+
+	return result
+}
+
+// etcdVoterHealth counts the voting members, ignoring those in excluding, and how many of them are healthy.
+func etcdVoterHealth(members []etcdMemberState, excluding []uint64) (voters, healthy int) {
+	for _, m := range members {
+		if m.member.GetIsLearner() || slices.Contains(excluding, m.member.GetId()) {
+			continue
+		}
+
+		voters++
+
+		if m.healthy() {
+			healthy++
+		}
+	}
+
+	return voters, healthy
+}
+
+// etcdMemberHost returns the host etcd advertises for clients, which is the node address Talos API is reachable on.
+func etcdMemberHost(member *machineapi.EtcdMember) string {
+	for _, rawURL := range slices.Concat(member.GetClientUrls(), member.GetPeerUrls()) {
+		if u, err := url.Parse(rawURL); err == nil && u.Hostname() != "" {
+			return u.Hostname()
+		}
+	}
+
+	return member.GetHostname()
+}
+
+// etcdFindMember returns the member for node, matched by hostname or any advertised URL host.
+func etcdFindMember(members []*machineapi.EtcdMember, node string) *machineapi.EtcdMember {
+	for _, member := range members {
+		if member.GetHostname() == node {
+			return member
+		}
+
+		for _, rawURL := range slices.Concat(member.GetClientUrls(), member.GetPeerUrls()) {
+			if u, err := url.Parse(rawURL); err == nil && u.Hostname() == node {
+				return member
+			}
+		}
+	}
+
+	return nil
+}
+
+// etcdQuorum returns the number of voting members required for quorum.
+func etcdQuorum(voters int) int {
+	return voters/2 + 1
+}
+
+// etcdCheckQuorum refuses to remove members unless the current voting members can commit the change
+// and the remaining voting members are healthy enough to keep quorum afterwards.
+func etcdCheckQuorum(ctx context.Context, c *client.Client, members []*machineapi.EtcdMember, removing []uint64) error {
+	states := etcdMembersStatus(ctx, c, members, nil)
+
+	for _, m := range states {
+		switch {
+		case m.member.GetIsLearner() || m.healthy():
+		case m.statusErr != nil:
+			tflog.Warn(ctx, "etcd member is unhealthy", map[string]any{"member": m.member.GetHostname(), "error": m.statusErr.Error()})
+		default:
+			tflog.Warn(ctx, "etcd member reports errors", map[string]any{"member": m.member.GetHostname(), "errors": m.status.GetErrors()})
+		}
+	}
+
+	return etcdCheckRemovable(states, removing)
+}
+
+// etcdCheckRemovable is the decision of etcdCheckQuorum for members whose status is known.
+func etcdCheckRemovable(states []etcdMemberState, removing []uint64) error {
+	voters, healthy := etcdVoterHealth(states, nil)
+	remaining, remainingHealthy := etcdVoterHealth(states, removing)
+
+	switch {
+	case remaining == 0:
+		return errors.New("refusing to remove the last voting etcd member")
+	case healthy < etcdQuorum(voters):
+		return fmt.Errorf("refusing to remove etcd members: only %d of %d voting members are healthy, %d required for quorum", healthy, voters, etcdQuorum(voters))
+	case remainingHealthy < etcdQuorum(remaining):
+		return fmt.Errorf("refusing to remove etcd members: %d of the remaining %d voting members are healthy, %d required for quorum", remainingHealthy, remaining, etcdQuorum(remaining))
+	}
+
+	return nil
+}
+
+// etcdWaitForRemovable waits until memberID can be removed without losing quorum, listing the members again on every attempt.
+// Control planes reset in parallel stay listed while they are unhealthy, until they have left, so a refusal is retried until the timeout.
+// It returns the members the removal was checked against, or nil when memberID is no longer listed or is the last member.
+func etcdWaitForRemovable(ctx context.Context, timeout time.Duration, memberID uint64, memberStates func(context.Context) ([]etcdMemberState, error)) ([]etcdMemberState, error) {
+	var result []etcdMemberState
+
+	err := retry.RetryContext(ctx, timeout, func() *retry.RetryError {
+		states, err := memberStates(ctx)
+		if err != nil {
+			return retry.RetryableError(err)
+		}
+
+		if len(states) == 1 || !slices.ContainsFunc(states, func(m etcdMemberState) bool { return m.member.GetId() == memberID }) {
+			result = nil
+
+			return nil
+		}
+
+		if err = etcdCheckRemovable(states, []uint64{memberID}); err != nil {
+			return retry.RetryableError(err)
+		}
+
+		result = states
+
+		return nil
+	})
+
+	return result, err
+}
+
+// etcdRemoveMember makes node leave the etcd cluster, falling back to removing its member by ID through viaNode
+// when node is unreachable, and waits until the member list reported by viaNode no longer contains it.
+func etcdRemoveMember(ctx context.Context, c *client.Client, node string, member *machineapi.EtcdMember, viaNode string) error {
+	if err := c.EtcdLeaveCluster(client.WithNode(ctx, node), &machineapi.EtcdLeaveClusterRequest{}); err != nil {
+		tflog.Warn(ctx, "etcd leave failed, removing member by ID", map[string]any{"node": node, "error": err.Error()})
+
+		if err = c.EtcdRemoveMemberByID(client.WithNode(ctx, viaNode), &machineapi.EtcdRemoveMemberByIDRequest{
+			MemberId: member.GetId(),
+		}); err != nil {
+			return fmt.Errorf("removing etcd member %s (%s): %w", member.GetHostname(), etcdresource.FormatMemberID(member.GetId()), err)
+		}
+	}
+
+	return retry.RetryContext(ctx, 2*time.Minute, func() *retry.RetryError {
+		members, err := etcdMemberList(ctx, c, viaNode)
+		if err != nil {
+			return retry.RetryableError(err)
+		}
+
+		if slices.ContainsFunc(members, func(m *machineapi.EtcdMember) bool { return m.GetId() == member.GetId() }) {
+			return retry.RetryableError(fmt.Errorf("etcd member %s is still listed", member.GetHostname()))
+		}
+
+		return nil
+	})
+}
+
+// etcdWaitForVoter waits until node has joined etcd and its member is no longer a learner.
+func etcdWaitForVoter(ctx context.Context, c *client.Client, node, viaNode string, timeout time.Duration) error {
+	return retry.RetryContext(ctx, timeout, func() *retry.RetryError {
+		local, err := safe.StateGet[*etcdresource.Member](client.WithNode(ctx, node), c.COSI, etcdresource.NewMember(etcdresource.NamespaceName, etcdresource.LocalMemberID).Metadata())
+		if err != nil {
+			return retry.RetryableError(fmt.Errorf("waiting for etcd to join on %s: %w", node, err))
+		}
+
+		memberID, err := etcdresource.ParseMemberID(local.TypedSpec().MemberID)
+		if err != nil {
+			return retry.NonRetryableError(err)
+		}
+
+		members, err := etcdMemberList(ctx, c, viaNode)
+		if err != nil {
+			return retry.RetryableError(err)
+		}
+
+		idx := slices.IndexFunc(members, func(m *machineapi.EtcdMember) bool { return m.GetId() == memberID })
+
+		switch {
+		case idx == -1:
+			return retry.RetryableError(fmt.Errorf("etcd member of %s is not listed yet", node))
+		case members[idx].GetIsLearner():
+			return retry.RetryableError(fmt.Errorf("etcd member of %s is still a learner", node))
+		}
+
+		return nil
+	})
+}
+
+// etcdBootstrapRequest returns the Bootstrap request for the given recovery options, which may be nil.
+func etcdBootstrapRequest(recoverFrom *etcdRecoverOptions) *machineapi.BootstrapRequest {
+	if recoverFrom == nil {
+		return &machineapi.BootstrapRequest{}
+	}
+
+	return &machineapi.BootstrapRequest{
+		RecoverEtcd:          true,
+		RecoverSkipHashCheck: recoverFrom.SkipHashCheck.ValueBool(),
+	}
+}
+
+// etcdUploadSnapshot uploads the snapshot referenced by recoverFrom to the node in ctx, so that a following
+// Bootstrap with RecoverEtcd set restores it.
+func etcdUploadSnapshot(ctx context.Context, c *client.Client, recoverFrom *etcdRecoverOptions) error {
+	f, err := os.Open(recoverFrom.Path.ValueString())
+	if err != nil {
+		return err
+	}
+
+	defer f.Close() //nolint:errcheck
+
+	r, err := etcdSnapshotReader(f, recoverFrom.ageIdentities)
+	if err != nil {
+		return fmt.Errorf("error reading etcd snapshot %s: %w", recoverFrom.Path.ValueString(), err)
+	}
+
+	if _, err = c.EtcdRecover(ctx, r); err != nil {
+		return fmt.Errorf("error uploading etcd snapshot: %w", err)
+	}
+
+	return nil
+}
+
+// etcdCheckSnapshot verifies that the snapshot referenced by recoverFrom exists and can be decrypted and
+// decompressed, so that local mistakes fail fast instead of being retried against the node.
+func etcdCheckSnapshot(recoverFrom *etcdRecoverOptions) error {
+	f, err := os.Open(recoverFrom.Path.ValueString())
+	if err != nil {
+		return err
+	}
+
+	defer f.Close() //nolint:errcheck
+
+	r, err := etcdSnapshotReader(f, recoverFrom.ageIdentities)
+	if err != nil {
+		return err
+	}
+
+	if _, err = r.Read(make([]byte, 1)); err != nil {
+		if errors.Is(err, io.EOF) {
+			return errors.New("snapshot is empty")
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// etcdSnapshotReader undoes the optional age encryption and gzip compression applied by talos_etcd_snapshot.
+// The age identities are only loaded if the snapshot is encrypted.
+func etcdSnapshotReader(r io.Reader, ageIdentities func() ([]age.Identity, error)) (io.Reader, error) {
+	br := bufio.NewReader(r)
+
+	if header, _ := br.Peek(len("age-encryption.org/")); string(header) == "age-encryption.org/" { //nolint:errcheck
+		identities, err := ageIdentities()
+		if err != nil {
+			return nil, err
+		}
+
+		if len(identities) == 0 {
+			return nil, errors.New("snapshot is age-encrypted, but no age identity is set")
+		}
+
+		decrypted, err := age.Decrypt(br, identities...)
+		if err != nil {
+			return nil, err
+		}
+
+		br = bufio.NewReader(decrypted)
+	}
+
+	if magic, _ := br.Peek(2); bytes.Equal(magic, []byte{0x1f, 0x8b}) { //nolint:errcheck
+		return gzip.NewReader(br)
+	}
+
+	return br, nil
+}
+
+// etcdWaitForHealthy waits until etcd on the node in ctx answers status requests without reporting errors.
+func etcdWaitForHealthy(ctx context.Context, c *client.Client, timeout time.Duration) error {
+	return retry.RetryContext(ctx, timeout, func() *retry.RetryError {
+		resp, err := c.EtcdStatus(ctx)
+		if err != nil {
+			return retry.RetryableError(err)
+		}
+
+		for _, msg := range resp.GetMessages() {
+			if errs := msg.GetMemberStatus().GetErrors(); len(errs) > 0 {
+				return retry.RetryableError(fmt.Errorf("etcd reports errors: %s", strings.Join(errs, ", ")))
+			}
+		}
+
+		return nil
+	})
+}
+
+// etcdDefragOrder returns the members in the order they should be defragmented: followers first, and the leader
+// last so that leadership does not move more than once while members block on the defragmentation.
+func etcdDefragOrder(members []etcdMemberState) []etcdMemberState {
+	leaders := map[uint64]struct{}{}
+
+	for _, m := range members {
+		if leader := m.status.GetLeader(); leader != 0 {
+			leaders[leader] = struct{}{}
+		}
+	}
+
+	ordered := slices.Clone(members)
+
+	slices.SortStableFunc(ordered, func(a, b etcdMemberState) int {
+		_, aLeader := leaders[a.member.GetId()]
+		_, bLeader := leaders[b.member.GetId()]
+
+		switch {
+		case aLeader == bLeader:
+			return 0
+		case aLeader:
+			return 1
+		default:
+			return -1
+		}
+	})
+
+	return ordered
+}
+
+// etcdWaitForClusterResponsive waits until every etcd member answers status requests and follows a leader.
+func etcdWaitForClusterResponsive(ctx context.Context, c *client.Client, nodes []string, timeout time.Duration) error {
+	return retry.RetryContext(ctx, timeout, func() *retry.RetryError {
+		members, err := etcdClusterState(ctx, c, nodes)
+		if err != nil {
+			return retry.RetryableError(err)
+		}
+
+		for _, m := range members {
+			if !m.responsive() {
+				return retry.RetryableError(fmt.Errorf("etcd member %s is not responding", m.member.GetHostname()))
+			}
+		}
+
+		return nil
+	})
+}
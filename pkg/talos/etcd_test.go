@@ -0,0 +1,264 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package talos //nolint:testpackage // needs access to unexported etcd helpers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"filippo.io/age"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	machineapi "github.com/siderolabs/talos/pkg/machinery/api/machine"
+	etcdresource "github.com/siderolabs/talos/pkg/machinery/resources/etcd"
+)
+
+func TestEtcdFindMember(t *testing.T) {
+	t.Parallel()
+
+	members := []*machineapi.EtcdMember{
+		{Id: 1, Hostname: "cp-1", PeerUrls: []string{"https://10.5.0.2:2380"}, ClientUrls: []string{"https://10.5.0.2:2379"}},
+		{Id: 2, Hostname: "cp-2", PeerUrls: []string{"https://[fd00::3]:2380"}},
+	}
+
+	for node, want := range map[string]uint64{
+		"cp-1":     1,
+		"10.5.0.2": 1,
+		"fd00::3":  2,
+		"10.5.0.9": 0,
+	} {
+		got := etcdFindMember(members, node)
+
+		if got.GetId() != want {
+			t.Errorf("etcdFindMember(%q) = %d, want %d", node, got.GetId(), want)
+		}
+	}
+
+	if host := etcdMemberHost(members[1]); host != "fd00::3" {
+		t.Errorf("etcdMemberHost() = %q, want %q", host, "fd00::3")
+	}
+}
+
+func TestEtcdQuorum(t *testing.T) {
+	t.Parallel()
+
+	for voters, want := range map[int]int{1: 1, 2: 2, 3: 2, 4: 3, 5: 3} {
+		if got := etcdQuorum(voters); got != want {
+			t.Errorf("etcdQuorum(%d) = %d, want %d", voters, got, want)
+		}
+	}
+}
+
+func TestEtcdVoterHealth(t *testing.T) {
+	t.Parallel()
+
+	members := []etcdMemberState{
+		{member: &machineapi.EtcdMember{Id: 1}, status: &machineapi.EtcdMemberStatus{}},
+		{member: &machineapi.EtcdMember{Id: 2}, status: &machineapi.EtcdMemberStatus{Errors: []string{"raft: no leader"}}},
+		{member: &machineapi.EtcdMember{Id: 3}, statusErr: errors.New("unreachable")},
+		{member: &machineapi.EtcdMember{Id: 4, IsLearner: true}, status: &machineapi.EtcdMemberStatus{}},
+	}
+
+	if voters, healthy := etcdVoterHealth(members, nil); voters != 3 || healthy != 1 {
+		t.Errorf("etcdVoterHealth() = %d, %d, want 3, 1", voters, healthy)
+	}
+
+	if voters, healthy := etcdVoterHealth(members, []uint64{1, 3}); voters != 1 || healthy != 0 {
+		t.Errorf("etcdVoterHealth() excluding 1 and 3 = %d, %d, want 1, 0", voters, healthy)
+	}
+}
+
+func TestEtcdSnapshotReader(t *testing.T) {
+	t.Parallel()
+
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payload := strings.Repeat("bbolt page ", 512)
+
+	for _, compress := range []bool{false, true} {
+		for _, encrypt := range []bool{false, true} {
+			var (
+				buf        bytes.Buffer
+				recipients []age.Recipient
+			)
+
+			if encrypt {
+				recipients = append(recipients, identity.Recipient())
+			}
+
+			if _, _, err = writeEtcdSnapshot(&buf, strings.NewReader(payload), compress, recipients); err != nil {
+				t.Fatal(err)
+			}
+
+			if encrypt {
+				if _, err = etcdSnapshotReader(bytes.NewReader(buf.Bytes()), func() ([]age.Identity, error) { return nil, nil }); err == nil {
+					t.Errorf("compress=%v: expected an error without an age identity", compress)
+				}
+			}
+
+			recoverFrom := &etcdRecoverOptions{AgeIdentityWO: types.StringValue(identity.String())}
+
+			r, err := etcdSnapshotReader(&buf, recoverFrom.ageIdentities)
+			if err != nil {
+				t.Fatalf("compress=%v encrypt=%v: %v", compress, encrypt, err)
+			}
+
+			out, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(out) != payload {
+				t.Errorf("compress=%v encrypt=%v: snapshot was not restored to the original", compress, encrypt)
+			}
+		}
+	}
+}
+
+func TestEtcdRecoverOptionsAgeIdentities(t *testing.T) { //nolint:paralleltest // sets environment variables
+	setTestSOPSEnvironment(t, testSOPSAgeKeyFile)
+
+	identities, err := (&etcdRecoverOptions{}).ageIdentities()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(identities) != 1 {
+		t.Errorf("expected the identity from the environment, got %d identities", len(identities))
+	}
+
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	identities, err = (&etcdRecoverOptions{AgeIdentityWO: types.StringValue(identity.String())}).ageIdentities()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if x25519, ok := identities[0].(*age.X25519Identity); len(identities) != 1 || !ok || x25519.String() != identity.String() {
+		t.Error("expected age_identity_wo to take precedence over the environment")
+	}
+
+	setTestSOPSEnvironment(t, "")
+
+	if _, err = (&etcdRecoverOptions{}).ageIdentities(); err == nil {
+		t.Error("expected an error without any age identity")
+	}
+}
+
+func TestEtcdMemberToTFTypes(t *testing.T) {
+	t.Parallel()
+
+	healthy := etcdMemberToTFTypes(etcdMemberState{
+		member: &machineapi.EtcdMember{Id: 0xabc, Hostname: "cp-1"},
+		status: &machineapi.EtcdMemberStatus{DbSize: 4096, RaftIndex: 42},
+		alarms: []string{"NOSPACE"},
+	}, true)
+
+	if !healthy.Healthy.ValueBool() || !healthy.IsLeader.ValueBool() || healthy.DBSize.ValueInt64() != 4096 || healthy.RaftIndex.ValueInt64() != 42 {
+		t.Errorf("unexpected member %+v", healthy)
+	}
+
+	if healthy.ID.ValueString() != "0000000000000abc" || len(healthy.Alarms) != 1 || len(healthy.Errors) != 0 {
+		t.Errorf("unexpected member %+v", healthy)
+	}
+
+	unreachable := etcdMemberToTFTypes(etcdMemberState{
+		member:    &machineapi.EtcdMember{Id: 2, Hostname: "cp-2"},
+		statusErr: errors.New("connection refused"),
+	}, false)
+
+	if unreachable.Healthy.ValueBool() || !unreachable.DBSize.IsNull() || len(unreachable.Errors) != 1 {
+		t.Errorf("unexpected member %+v", unreachable)
+	}
+}
+
+func TestEtcdDefragOrder(t *testing.T) {
+	t.Parallel()
+
+	member := func(id uint64, leader uint64) etcdMemberState {
+		return etcdMemberState{
+			member: &machineapi.EtcdMember{Id: id, Hostname: etcdresource.FormatMemberID(id)},
+			status: &machineapi.EtcdMemberStatus{MemberId: id, Leader: leader},
+		}
+	}
+
+	ordered := etcdDefragOrder([]etcdMemberState{member(1, 2), member(2, 2), member(3, 2)})
+
+	var ids []uint64
+
+	for _, m := range ordered {
+		ids = append(ids, m.member.GetId())
+	}
+
+	if !slices.Equal(ids, []uint64{1, 3, 2}) {
+		t.Errorf("etcdDefragOrder() = %v, want the leader last", ids)
+	}
+
+	if unresponsive := (etcdMemberState{member: &machineapi.EtcdMember{Id: 4}, statusErr: errors.New("timeout")}); unresponsive.responsive() {
+		t.Error("member without status must not be responsive")
+	}
+}
+
+func TestEtcdWaitForRemovable(t *testing.T) {
+	t.Parallel()
+
+	member := func(id uint64, err error) etcdMemberState {
+		return etcdMemberState{
+			member:    &machineapi.EtcdMember{Id: id},
+			status:    &machineapi.EtcdMemberStatus{MemberId: id, Leader: 3},
+			statusErr: err,
+		}
+	}
+
+	// cp-2 is reset in parallel: it stays listed but unhealthy until it has left the cluster.
+	calls := 0
+	parallelTeardown := func(context.Context) ([]etcdMemberState, error) {
+		calls++
+
+		if calls < 3 {
+			return []etcdMemberState{member(1, nil), member(2, errors.New("connection refused")), member(3, nil)}, nil
+		}
+
+		return []etcdMemberState{member(1, nil), member(3, nil)}, nil
+	}
+
+	states, err := etcdWaitForRemovable(t.Context(), time.Minute, 1, parallelTeardown)
+	if err != nil {
+		t.Fatalf("etcdWaitForRemovable() during parallel teardown: %v", err)
+	}
+
+	if len(states) != 2 || calls != 3 {
+		t.Errorf("etcdWaitForRemovable() = %d members after %d calls, want 2 members after 3 calls", len(states), calls)
+	}
+
+	degraded := func(context.Context) ([]etcdMemberState, error) {
+		return []etcdMemberState{member(1, nil), member(2, errors.New("connection refused")), member(3, nil)}, nil
+	}
+
+	if _, err = etcdWaitForRemovable(t.Context(), time.Second, 1, degraded); err == nil || !strings.Contains(err.Error(), "quorum") {
+		t.Errorf("etcdWaitForRemovable() = %v, want a quorum error once the timeout expires", err)
+	}
+
+	for name, list := range map[string][]etcdMemberState{
+		"already left": {member(2, nil), member(3, nil)},
+		"last member":  {member(1, nil)},
+	} {
+		states, err = etcdWaitForRemovable(t.Context(), time.Second, 1, func(context.Context) ([]etcdMemberState, error) { return list, nil })
+		if err != nil || states != nil {
+			t.Errorf("etcdWaitForRemovable() with %s = %v, %v, want nothing to remove", name, states, err)
+		}
+	}
+}
@@ -5,6 +5,9 @@
 package talos_test
 
 import (
+	"crypto/x509"
+	"encoding/pem"
+	"slices"
 	"testing"
 	"time"
 
@@ -44,3 +47,50 @@ func TestGenerateKubeconfigDeterminism(t *testing.T) {
 		t.Errorf("GenerateKubeconfig is not deterministic: two calls with identical inputs produced different output (%d vs %d bytes)", len(first.Raw), len(second.Raw))
 	}
 }
+
+func TestGenerateKubeconfigForUser(t *testing.T) {
+	bundle, err := secrets.NewBundle(secrets.NewFixedClock(time.Now()), nil)
+	if err != nil {
+		t.Fatalf("failed to create secrets bundle: %v", err)
+	}
+
+	notBefore := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	notAfter := notBefore.Add(24 * time.Hour)
+
+	admin, err := talos.GenerateKubeconfig(bundle, "scoped-test", "https://10.0.0.1:6443", notBefore, notAfter)
+	if err != nil {
+		t.Fatalf("GenerateKubeconfig failed: %v", err)
+	}
+
+	dev, err := talos.GenerateKubeconfigForUser(bundle, "scoped-test", "https://10.0.0.1:6443", "jane", []string{"dev", "ops"}, notBefore, notAfter)
+	if err != nil {
+		t.Fatalf("GenerateKubeconfigForUser failed: %v", err)
+	}
+
+	block, _ := pem.Decode(dev.ClientCertPEM)
+	if block == nil {
+		t.Fatal("failed to decode client certificate PEM")
+	}
+
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatalf("failed to parse client certificate: %v", err)
+	}
+
+	if cert.Subject.CommonName != "jane" || !slices.Equal(cert.Subject.Organization, []string{"dev", "ops"}) {
+		t.Errorf("unexpected certificate subject %q", cert.Subject)
+	}
+
+	if string(dev.ClientKeyPEM) == string(admin.ClientKeyPEM) {
+		t.Error("expected the user kubeconfig to use a different key than the admin kubeconfig")
+	}
+
+	again, err := talos.GenerateKubeconfigForUser(bundle, "scoped-test", "https://10.0.0.1:6443", "jane", []string{"dev", "ops"}, notBefore, notAfter)
+	if err != nil {
+		t.Fatalf("GenerateKubeconfigForUser failed: %v", err)
+	}
+
+	if again.Raw != dev.Raw {
+		t.Error("GenerateKubeconfigForUser is not deterministic")
+	}
+}
@@ -82,10 +82,18 @@ func (p *talosProvider) Configure(ctx context.Context, req provider.ConfigureReq
 func (p *talosProvider) DataSources(_ context.Context) []func() datasource.DataSource {
 	return []func() datasource.DataSource{
 		NewTalosMachineDisksDataSource,
+		NewTalosMachineExtensionsDataSource,
+		NewTalosMachineInfoDataSource,
+		NewTalosMachineNetworkDataSource,
+		NewTalosMachineResourcesDataSource,
+		NewTalosMachineServicesDataSource,
 		NewTalosMachineConfigurationDataSource,
+		NewTalosCertificateExpiryDataSource,
 		NewTalosClientConfigurationDataSource,
 		NewTalosClusterHealthDataSource,
 		NewTalosClusterKubeConfigDataSource,
+		NewTalosClusterKubernetesUpgradePlanDataSource,
+		NewTalosEtcdMembersDataSource,
 		NewTalosImageFactoryVersionsDataSource,
 		NewTalosImageFactoryExtensionsVersionsDataSource,
 		NewTalosImageFactoryOverlaysVersionsDataSource,
@@ -101,6 +109,9 @@ func (p *talosProvider) Resources(_ context.Context) []func() resource.Resource
 		NewTalosMachineBootstrapResource,
 		NewTalosClusterKubeConfigResource,
 		NewTalosClusterResource,
+		NewTalosEtcdSnapshotResource,
+		NewTalosEtcdMaintenanceResource,
+		NewTalosCARotationResource,
 		NewTalosImageFactorySchematicResource,
 		NewTalosMachineResource,
 	}
@@ -0,0 +1,127 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package talos
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"github.com/getsops/sops/v3/decrypt"
+)
+
+// Environment variables holding the age identities used to decrypt machine secrets, shared with SOPS.
+const (
+	ageKeyEnvVar     = "SOPS_AGE_KEY"
+	ageKeyFileEnvVar = "SOPS_AGE_KEY_FILE"
+)
+
+// ageIdentitiesFromEnvironment loads the age identities the same way SOPS does:
+// from SOPS_AGE_KEY, from the file in SOPS_AGE_KEY_FILE, and from sops/age/keys.txt in the user configuration directory.
+func ageIdentitiesFromEnvironment() ([]age.Identity, error) {
+	var identities []age.Identity
+
+	if key := os.Getenv(ageKeyEnvVar); key != "" {
+		parsed, err := age.ParseIdentities(strings.NewReader(key))
+		if err != nil {
+			return nil, fmt.Errorf("error parsing age identities from %s: %w", ageKeyEnvVar, err)
+		}
+
+		identities = append(identities, parsed...)
+	}
+
+	keyFiles := []string{os.Getenv(ageKeyFileEnvVar)}
+
+	if configDir, err := os.UserConfigDir(); err == nil {
+		keyFiles = append(keyFiles, filepath.Join(configDir, "sops", "age", "keys.txt"))
+	}
+
+	for i, keyFile := range keyFiles {
+		if keyFile == "" {
+			continue
+		}
+
+		keys, err := os.ReadFile(keyFile)
+		if err != nil {
+			// the default location is optional
+			if i > 0 && errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+
+			return nil, fmt.Errorf("error reading age identities: %w", err)
+		}
+
+		parsed, err := age.ParseIdentities(bytes.NewReader(keys))
+		if err != nil {
+			return nil, fmt.Errorf("error parsing age identities from %s: %w", keyFile, err)
+		}
+
+		identities = append(identities, parsed...)
+	}
+
+	if len(identities) == 0 {
+		return nil, fmt.Errorf("no age identity found, set %s or %s", ageKeyEnvVar, ageKeyFileEnvVar)
+	}
+
+	return identities, nil
+}
+
+// encryptSecretsYAML encrypts the secrets.yaml to the recipients as an ASCII armored age file, as `age --armor` does.
+func encryptSecretsYAML(secretsYAML string, recipients []age.Recipient) (string, error) {
+	var buf bytes.Buffer
+
+	armorWriter := armor.NewWriter(&buf)
+
+	w, err := age.Encrypt(armorWriter, recipients...)
+	if err != nil {
+		return "", err
+	}
+
+	if _, err = io.WriteString(w, secretsYAML); err != nil {
+		return "", err
+	}
+
+	if err = w.Close(); err != nil {
+		return "", err
+	}
+
+	if err = armorWriter.Close(); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+// decryptSecretsYAML decrypts an ASCII armored age file produced by encryptSecretsYAML.
+func decryptSecretsYAML(encrypted string, identities []age.Identity) (string, error) {
+	r, err := age.Decrypt(armor.NewReader(strings.NewReader(encrypted)), identities...)
+	if err != nil {
+		return "", fmt.Errorf("error decrypting machine secrets: %w", err)
+	}
+
+	secretsYAML, err := io.ReadAll(r)
+	if err != nil {
+		return "", fmt.Errorf("error decrypting machine secrets: %w", err)
+	}
+
+	return string(secretsYAML), nil
+}
+
+// decryptSOPSYAML decrypts a YAML document encrypted by SOPS and verifies its MAC.
+// The keys are looked up the same way the sops binary does, e.g. the age identities from SOPS_AGE_KEY or SOPS_AGE_KEY_FILE.
+func decryptSOPSYAML(data []byte) ([]byte, error) {
+	plain, err := decrypt.Data(data, "yaml")
+	if err != nil {
+		return nil, fmt.Errorf("error decrypting SOPS document: %w", err)
+	}
+
+	return plain, nil
+}
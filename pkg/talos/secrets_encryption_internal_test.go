@@ -0,0 +1,205 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package talos //nolint:testpackage // needs access to unexported secrets encryption helpers
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"filippo.io/age"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/siderolabs/talos/pkg/machinery/config"
+	"github.com/siderolabs/talos/pkg/machinery/config/generate/secrets"
+	"go.yaml.in/yaml/v4"
+)
+
+func newTestAgeIdentity(t *testing.T) *age.X25519Identity {
+	t.Helper()
+
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return identity
+}
+
+func newTestSecretsYAML(t *testing.T) []byte {
+	t.Helper()
+
+	secretsBundle, err := secrets.NewBundle(secrets.NewFixedClock(time.Now()), config.TalosVersionCurrent)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	secretsYAML, err := yaml.Marshal(secretsBundle)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return secretsYAML
+}
+
+func TestEncryptSecretsYAML(t *testing.T) {
+	t.Parallel()
+
+	identity := newTestAgeIdentity(t)
+	secretsYAML := string(newTestSecretsYAML(t))
+
+	encrypted, err := encryptSecretsYAML(secretsYAML, []age.Recipient{identity.Recipient()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(encrypted, "-----BEGIN AGE ENCRYPTED FILE-----") {
+		t.Fatalf("expected an armored age file, got %q", encrypted)
+	}
+
+	if strings.Contains(encrypted, "bootstraptoken") {
+		t.Fatal("expected the secrets to be encrypted")
+	}
+
+	decrypted, err := decryptSecretsYAML(encrypted, []age.Identity{identity})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if decrypted != secretsYAML {
+		t.Error("expected the decrypted secrets to match")
+	}
+
+	if _, err = decryptSecretsYAML(encrypted, []age.Identity{newTestAgeIdentity(t)}); err == nil {
+		t.Error("expected decryption with another identity to fail")
+	}
+}
+
+// The SOPS fixtures in testdata/sops are encrypted by the sops binary to the identity in age-key.txt:
+//
+//	sops encrypt --age <recipient> secrets.yaml > secrets.sops.yaml
+//	sops encrypt --age <recipient> --unencrypted-regex '^plain_' types.yaml > types.sops.yaml
+const testSOPSAgeKeyFile = "testdata/sops/age-key.txt"
+
+func setTestSOPSEnvironment(t *testing.T, ageKeyFile string) {
+	t.Helper()
+
+	t.Setenv(ageKeyFileEnvVar, ageKeyFile)
+	t.Setenv(ageKeyEnvVar, "")
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+}
+
+func TestDecryptSOPSYAML(t *testing.T) { //nolint:paralleltest // sets environment variables
+	setTestSOPSEnvironment(t, testSOPSAgeKeyFile)
+
+	encrypted, err := os.ReadFile("testdata/sops/types.sops.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decrypted, err := decryptSOPSYAML(encrypted)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var values map[string]any
+
+	if err = yaml.Unmarshal(decrypted, &values); err != nil {
+		t.Fatal(err)
+	}
+
+	for key, expected := range map[string]any{
+		"name":            "talos",
+		"enabled":         true,
+		"replicas":        3,
+		"ratio":           0.5,
+		"encrypted_flag":  false,
+		"encrypted_count": 42,
+		"plain_enabled":   false,
+		"plain_replicas":  7,
+		"plain_ratio":     1000,
+	} {
+		if values[key] != expected {
+			t.Errorf("%s: expected %v, got %v", key, expected, values[key])
+		}
+	}
+
+	if !bytes.Contains(decrypted, []byte("# an inline comment")) {
+		t.Error("expected the comments to be decrypted")
+	}
+
+	// the unencrypted values are covered by the MAC
+	tampered := bytes.Replace(encrypted, []byte("plain_enabled: false"), []byte("plain_enabled: true"), 1)
+	if _, err = decryptSOPSYAML(tampered); err == nil || !strings.Contains(err.Error(), "integrity") {
+		t.Errorf("expected decryption of a modified document to fail, got %v", err)
+	}
+
+	plain, err := os.ReadFile("testdata/secrets.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = decryptSOPSYAML(plain); err == nil {
+		t.Error("expected an error for a plain text document")
+	}
+}
+
+func TestSOPSSecretsBundle(t *testing.T) { //nolint:paralleltest // sets environment variables
+	const sopsFile = "testdata/sops/secrets.sops.yaml"
+
+	setTestSOPSEnvironment(t, "")
+
+	if _, err := sopsSecretsBundle(sopsFile); err == nil {
+		t.Fatal("expected an error without age identities")
+	}
+
+	setTestSOPSEnvironment(t, testSOPSAgeKeyFile)
+
+	secretsBundle, err := sopsSecretsBundle(sopsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plain, err := os.ReadFile("testdata/secrets.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected, err := secretsBundleFromYAML(plain)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if secretsBundle.Secrets.BootstrapToken != expected.Secrets.BootstrapToken || !bytes.Equal(secretsBundle.Certs.OS.Key, expected.Certs.OS.Key) {
+		t.Error("expected the decrypted secrets to match")
+	}
+
+	identities, err := ageIdentitiesFromEnvironment()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	model, err := secretsBundleTomachineSecrets(secretsBundle)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	model.AgeRecipients = []types.String{types.StringValue(identities[0].(*age.X25519Identity).Recipient().String())} //nolint:forcetypeassert
+
+	if err = encryptMachineSecrets(&model); err != nil {
+		t.Fatal(err)
+	}
+
+	decrypted := talosMachineSecretsResourceModelV1{EncryptedSecrets: model.EncryptedSecrets}
+
+	if err = decryptMachineSecrets(&decrypted); err != nil {
+		t.Fatal(err)
+	}
+
+	if decrypted.SecretsYAML.ValueString() != model.SecretsYAML.ValueString() {
+		t.Error("expected the decrypted machine secrets to match")
+	}
+}
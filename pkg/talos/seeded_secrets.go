@@ -0,0 +1,330 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package talos
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	stdlibx509 "crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"time"
+
+	"github.com/siderolabs/crypto/x509"
+	"github.com/siderolabs/talos/pkg/machinery/config"
+	"github.com/siderolabs/talos/pkg/machinery/config/generate/secrets"
+	"github.com/siderolabs/talos/pkg/machinery/constants"
+	"golang.org/x/crypto/hkdf"
+)
+
+// seededSecretsMinLength is the minimum length of a seed, so that it carries at least 256 bits of entropy when random.
+const seededSecretsMinLength = 32
+
+// newSeededSecretsBundle derives a secrets bundle from seed, as secrets.NewBundle generates one.
+//
+// Every key, token and secret is read from its own HKDF (RFC 5869) stream, labelled with the name of the secret,
+// and the CA certificates are signed with Ed25519 or RFC 6979 ECDSA, so the same seed and version contract
+// always produce a byte-identical bundle. No crypto/rand is used.
+//
+// The CAs can't be valid from the time they are generated at, so they are valid for secrets.CAValidityTime from notBefore,
+// which is part of the derivation like the seed.
+func newSeededSecretsBundle(seed []byte, notBefore time.Time, versionContract *config.VersionContract) (*secrets.Bundle, error) {
+	if len(seed) < seededSecretsMinLength {
+		return nil, fmt.Errorf("the seed must be at least %d characters long", seededSecretsMinLength)
+	}
+
+	stream := func(label string) io.Reader {
+		return hkdf.New(sha256.New, seed, []byte("talos-machine-secrets-v1"), []byte("talos-machine-secrets:v1:"+label))
+	}
+
+	bundle := &secrets.Bundle{
+		Clock:      secrets.NewFixedClock(time.Now()),
+		Cluster:    &secrets.Cluster{},
+		Secrets:    &secrets.Secrets{},
+		TrustdInfo: &secrets.TrustdInfo{},
+		Certs:      &secrets.Certs{},
+	}
+
+	var err error
+
+	if bundle.Certs.Etcd, err = newSeededECDSACA(stream("etcd-ca"), pkix.Name{Organization: []string{"etcd"}}, notBefore); err != nil {
+		return nil, fmt.Errorf("error deriving etcd CA: %w", err)
+	}
+
+	if bundle.Certs.K8s, err = newSeededECDSACA(stream("k8s-ca"), pkix.Name{Organization: []string{"kubernetes"}}, notBefore); err != nil {
+		return nil, fmt.Errorf("error deriving Kubernetes CA: %w", err)
+	}
+
+	if bundle.Certs.K8sAggregator, err = newSeededECDSACA(stream("k8s-aggregator-ca"), pkix.Name{CommonName: "front-proxy"}, notBefore); err != nil {
+		return nil, fmt.Errorf("error deriving Kubernetes aggregator CA: %w", err)
+	}
+
+	if bundle.Certs.OS, err = newSeededEd25519CA(stream("os-ca"), pkix.Name{Organization: []string{"talos"}}, notBefore); err != nil {
+		return nil, fmt.Errorf("error deriving Talos API CA: %w", err)
+	}
+
+	var serviceAccountKey []byte
+
+	if versionContract.UseRSAServiceAccountKey() {
+		serviceAccountKey, err = newSeededRSAKey(stream("k8s-serviceaccount-rsa-key"), 4096)
+	} else {
+		_, serviceAccountKey, err = newSeededECDSAKey(stream("k8s-serviceaccount-key"))
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("error deriving Kubernetes service account key: %w", err)
+	}
+
+	bundle.Certs.K8sServiceAccount = &x509.PEMEncodedKey{Key: serviceAccountKey}
+
+	if bundle.Secrets.BootstrapToken, err = newSeededToken(stream("bootstrap-token")); err != nil {
+		return nil, fmt.Errorf("error deriving bootstrap token: %w", err)
+	}
+
+	if bundle.TrustdInfo.Token, err = newSeededToken(stream("trustd-token")); err != nil {
+		return nil, fmt.Errorf("error deriving trustd token: %w", err)
+	}
+
+	if versionContract.Greater(config.TalosVersion1_2) {
+		bundle.Secrets.SecretboxEncryptionSecret, err = newSeededBase64(stream("secretbox-encryption-secret"), 32, base64.StdEncoding)
+	} else {
+		bundle.Secrets.AESCBCEncryptionSecret, err = newSeededBase64(stream("aescbc-encryption-secret"), 32, base64.StdEncoding)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("error deriving encryption secret: %w", err)
+	}
+
+	if bundle.Cluster.ID, err = newSeededBase64(stream("cluster-id"), constants.DefaultClusterIDSize, base64.URLEncoding); err != nil {
+		return nil, fmt.Errorf("error deriving cluster ID: %w", err)
+	}
+
+	if bundle.Cluster.Secret, err = newSeededBase64(stream("cluster-secret"), constants.DefaultClusterSecretSize, base64.StdEncoding); err != nil {
+		return nil, fmt.Errorf("error deriving cluster secret: %w", err)
+	}
+
+	return bundle, nil
+}
+
+// newSeededECDSAKey derives a P-256 key, encoded as x509.NewECDSAKey does.
+func newSeededECDSAKey(r io.Reader) (*ecdsa.PrivateKey, []byte, error) {
+	// a derived scalar which is not a valid P-256 key is skipped, which is astronomically unlikely
+	for {
+		keyBytes := make([]byte, 32)
+		if _, err := io.ReadFull(r, keyBytes); err != nil {
+			return nil, nil, err
+		}
+
+		key, err := ecdsa.ParseRawPrivateKey(elliptic.P256(), keyBytes)
+		if err != nil {
+			continue
+		}
+
+		der, err := stdlibx509.MarshalECPrivateKey(key)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return key, pem.EncodeToMemory(&pem.Block{Type: x509.PEMTypeECPrivate, Bytes: der}), nil
+	}
+}
+
+// newSeededRSAKey derives an RSA key with public exponent 65537, encoded as x509.NewRSAKey does.
+//
+// rsa.GenerateKey can't be used, as it ignores its reader since Go 1.26.
+func newSeededRSAKey(r io.Reader, bits int) ([]byte, error) {
+	const publicExponent = 65537
+
+	e := big.NewInt(publicExponent)
+	one := big.NewInt(1)
+
+	primes := make([]*big.Int, 2)
+
+	for i := range primes {
+		for {
+			p, err := newSeededPrime(r, bits/2)
+			if err != nil {
+				return nil, err
+			}
+
+			// e must be invertible modulo p-1, and the primes distinct
+			if new(big.Int).GCD(nil, nil, e, new(big.Int).Sub(p, one)).Cmp(one) != 0 || (i > 0 && p.Cmp(primes[0]) == 0) {
+				continue
+			}
+
+			primes[i] = p
+
+			break
+		}
+	}
+
+	n := new(big.Int).Mul(primes[0], primes[1])
+	totient := new(big.Int).Mul(new(big.Int).Sub(primes[0], one), new(big.Int).Sub(primes[1], one))
+
+	key := &rsa.PrivateKey{
+		PublicKey: rsa.PublicKey{N: n, E: publicExponent},
+		D:         new(big.Int).ModInverse(e, totient),
+		Primes:    primes,
+	}
+
+	key.Precompute()
+
+	if err := key.Validate(); err != nil {
+		return nil, err
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: x509.PEMTypeRSAPrivate, Bytes: stdlibx509.MarshalPKCS1PrivateKey(key)}), nil
+}
+
+// newSeededPrime reads a bits long candidate with the two top bits set, so the product of two primes has twice the bits,
+// and returns the first probable prime from it on.
+func newSeededPrime(r io.Reader, bits int) (*big.Int, error) {
+	candidateBytes := make([]byte, (bits+7)/8)
+	if _, err := io.ReadFull(r, candidateBytes); err != nil {
+		return nil, err
+	}
+
+	p := new(big.Int).SetBytes(candidateBytes)
+	p.SetBit(p, bits-1, 1)
+	p.SetBit(p, bits-2, 1)
+	p.SetBit(p, 0, 1)
+
+	two := big.NewInt(2)
+
+	for ; p.BitLen() == bits; p.Add(p, two) {
+		if p.ProbablyPrime(20) {
+			return p, nil
+		}
+	}
+
+	return nil, errors.New("no prime found for the derived candidate")
+}
+
+// newSeededECDSACA derives a self-signed ECDSA CA, as secrets.NewKubernetesCA generates one.
+func newSeededECDSACA(r io.Reader, subject pkix.Name, notBefore time.Time) (*x509.PEMEncodedCertificateAndKey, error) {
+	key, keyPEM, err := newSeededECDSAKey(r)
+	if err != nil {
+		return nil, err
+	}
+
+	template, err := newSeededCATemplate(r, subject, notBefore, stdlibx509.ECDSAWithSHA256)
+	if err != nil {
+		return nil, err
+	}
+
+	return newSeededCA(template, &deterministicECDSASigner{key: key}, keyPEM)
+}
+
+// newSeededEd25519CA derives a self-signed Ed25519 CA, as secrets.NewTalosCA generates one.
+func newSeededEd25519CA(r io.Reader, subject pkix.Name, notBefore time.Time) (*x509.PEMEncodedCertificateAndKey, error) {
+	keySeed := make([]byte, ed25519.SeedSize)
+	if _, err := io.ReadFull(r, keySeed); err != nil {
+		return nil, err
+	}
+
+	key := ed25519.NewKeyFromSeed(keySeed)
+
+	der, err := stdlibx509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	template, err := newSeededCATemplate(r, subject, notBefore, stdlibx509.PureEd25519)
+	if err != nil {
+		return nil, err
+	}
+
+	return newSeededCA(template, key, pem.EncodeToMemory(&pem.Block{Type: x509.PEMTypeEd25519Private, Bytes: der}))
+}
+
+// newSeededCATemplate builds the template of x509.NewSelfSignedCertificateAuthority, with a derived serial number and subject key ID.
+func newSeededCATemplate(r io.Reader, subject pkix.Name, notBefore time.Time, signatureAlgorithm stdlibx509.SignatureAlgorithm) (*stdlibx509.Certificate, error) {
+	serialBytes := make([]byte, 16)
+	if _, err := io.ReadFull(r, serialBytes); err != nil {
+		return nil, err
+	}
+
+	subjectKeyID := make([]byte, 20)
+	if _, err := io.ReadFull(r, subjectKeyID); err != nil {
+		return nil, err
+	}
+
+	return &stdlibx509.Certificate{
+		SerialNumber:          new(big.Int).SetBytes(serialBytes),
+		Subject:               subject,
+		SubjectKeyId:          subjectKeyID,
+		SignatureAlgorithm:    signatureAlgorithm,
+		NotBefore:             notBefore.UTC(),
+		NotAfter:              notBefore.UTC().Add(secrets.CAValidityTime),
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		KeyUsage:              stdlibx509.KeyUsageCertSign | stdlibx509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []stdlibx509.ExtKeyUsage{
+			stdlibx509.ExtKeyUsageServerAuth,
+			stdlibx509.ExtKeyUsageClientAuth,
+		},
+	}, nil
+}
+
+func newSeededCA(template *stdlibx509.Certificate, key crypto.Signer, keyPEM []byte) (*x509.PEMEncodedCertificateAndKey, error) {
+	// the rand reader is unused: the serial number is set and both signers are deterministic
+	der, err := stdlibx509.CreateCertificate(nil, template, template, key.Public(), key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &x509.PEMEncodedCertificateAndKey{
+		Crt: pem.EncodeToMemory(&pem.Block{Type: x509.PEMTypeCertificate, Bytes: der}),
+		Key: keyPEM,
+	}, nil
+}
+
+// newSeededToken derives a token in the `[a-z0-9]{6}.[a-z0-9]{16}` format of kubeadm bootstrap tokens.
+func newSeededToken(r io.Reader) (string, error) {
+	const (
+		tokenChars = "0123456789abcdefghijklmnopqrstuvwxyz"
+		// bytes from 252 on are skipped, so that the characters are evenly distributed
+		maxByteValue = 252
+	)
+
+	token := make([]byte, 0, 6+1+16)
+	b := make([]byte, 1)
+
+	for len(token) < cap(token) {
+		if len(token) == 6 {
+			token = append(token, '.')
+
+			continue
+		}
+
+		if _, err := io.ReadFull(r, b); err != nil {
+			return "", err
+		}
+
+		if b[0] < maxByteValue {
+			token = append(token, tokenChars[int(b[0])%len(tokenChars)])
+		}
+	}
+
+	return string(token), nil
+}
+
+func newSeededBase64(r io.Reader, size int, encoding *base64.Encoding) (string, error) {
+	b := make([]byte, size)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return "", err
+	}
+
+	return encoding.EncodeToString(b), nil
+}
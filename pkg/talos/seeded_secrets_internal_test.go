@@ -0,0 +1,207 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package talos //nolint:testpackage // needs access to unexported seeded secrets helpers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/pem"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/siderolabs/crypto/x509"
+	"github.com/siderolabs/talos/pkg/machinery/config"
+	"github.com/siderolabs/talos/pkg/machinery/config/generate/secrets"
+	"go.yaml.in/yaml/v4"
+)
+
+var testSeededSecretsNotBefore = time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+func TestNewSeededSecretsBundle(t *testing.T) {
+	t.Parallel()
+
+	const seed = "0123456789abcdef0123456789abcdef"
+
+	for _, test := range []struct {
+		name    string
+		version string
+		rsa     bool
+	}{
+		{name: "ecdsa service account key", version: "v1.6"},
+		{name: "rsa service account key", version: "v1.7", rsa: true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			versionContract, err := config.ParseContractFromVersion(test.version)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			bundle, err := newSeededSecretsBundle([]byte(seed), testSeededSecretsNotBefore, versionContract)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err = validateSecretsBundle(bundle); err != nil {
+				t.Fatal(err)
+			}
+
+			if err = bundle.Validate(); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err = bundle.Certs.K8sServiceAccount.GetKey(); err != nil {
+				t.Fatal(err)
+			}
+
+			if block, _ := pem.Decode(bundle.Certs.K8sServiceAccount.Key); (block.Type == "RSA PRIVATE KEY") != test.rsa {
+				t.Errorf("expected an RSA service account key to be %t, got %q", test.rsa, block.Type)
+			}
+
+			tokenFormat := regexp.MustCompile(`^[a-z0-9]{6}\.[a-z0-9]{16}$`)
+
+			if !tokenFormat.MatchString(bundle.Secrets.BootstrapToken) || !tokenFormat.MatchString(bundle.TrustdInfo.Token) {
+				t.Errorf("unexpected token format %q, %q", bundle.Secrets.BootstrapToken, bundle.TrustdInfo.Token)
+			}
+
+			first, err := yaml.Marshal(bundle)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			again, err := newSeededSecretsBundle([]byte(seed), testSeededSecretsNotBefore, versionContract)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			second, err := yaml.Marshal(again)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(first) != string(second) {
+				t.Error("expected the same seed to derive byte-identical secrets")
+			}
+
+			other, err := newSeededSecretsBundle([]byte(seed+"!"), testSeededSecretsNotBefore, versionContract)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if other.Secrets.BootstrapToken == bundle.Secrets.BootstrapToken || string(other.Certs.OS.Key) == string(bundle.Certs.OS.Key) {
+				t.Error("expected another seed to derive other secrets")
+			}
+
+			// the client configuration is signed by the derived Talos API CA
+			model, err := secretsBundleTomachineSecrets(bundle)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			osCA, err := bundle.Certs.OS.GetCert()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err = parseTestClientCertificate(t, model.ClientConfiguration).CheckSignatureFrom(osCA); err != nil {
+				t.Error(err)
+			}
+
+			aggregatorCA, err := bundle.Certs.K8sAggregator.GetCert()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if aggregatorCA.Subject.CommonName != "front-proxy" {
+				t.Errorf("expected the aggregator CA common name to be %q, got %q", "front-proxy", aggregatorCA.Subject.CommonName)
+			}
+		})
+	}
+}
+
+func TestNewSeededSecretsBundleNotBefore(t *testing.T) {
+	t.Parallel()
+
+	const seed = "0123456789abcdef0123456789abcdef"
+
+	notBefore := time.Date(2030, time.June, 1, 12, 0, 0, 0, time.UTC)
+
+	bundle, err := newSeededSecretsBundle([]byte(seed), notBefore, config.TalosVersionCurrent)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, ca := range map[string]*x509.PEMEncodedCertificateAndKey{
+		"etcd":       bundle.Certs.Etcd,
+		"kubernetes": bundle.Certs.K8s,
+		"aggregator": bundle.Certs.K8sAggregator,
+		"talos api":  bundle.Certs.OS,
+	} {
+		crt, err := ca.GetCert()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !crt.NotBefore.Equal(notBefore) || !crt.NotAfter.Equal(notBefore.Add(secrets.CAValidityTime)) {
+			t.Errorf("%s: expected the CA to be valid from %s for %s, got %s to %s", name, notBefore, secrets.CAValidityTime, crt.NotBefore, crt.NotAfter)
+		}
+	}
+
+	other, err := newSeededSecretsBundle([]byte(seed), testSeededSecretsNotBefore, config.TalosVersionCurrent)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(other.Certs.K8s.Crt) == string(bundle.Certs.K8s.Crt) {
+		t.Error("expected another not_before to derive other CAs")
+	}
+}
+
+// TestNewSeededSecretsBundleGolden pins the derivation: secrets derived by earlier provider versions must be derived again.
+func TestNewSeededSecretsBundleGolden(t *testing.T) {
+	t.Parallel()
+
+	versionContract, err := config.ParseContractFromVersion("v1.6")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bundle, err := newSeededSecretsBundle([]byte("0123456789abcdef0123456789abcdef"), testSeededSecretsNotBefore, versionContract)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sum := func(b []byte) string {
+		s := sha256.Sum256(b)
+
+		return hex.EncodeToString(s[:])
+	}
+
+	for _, test := range []struct {
+		name     string
+		actual   string
+		expected string
+	}{
+		{name: "cluster id", actual: bundle.Cluster.ID, expected: "FN60Y0LRwZhtfDjXCA53yS8kIAcZwYwHCfbkyskxLuc="},
+		{name: "bootstrap token", actual: bundle.Secrets.BootstrapToken, expected: "3t64nu.cpiybp3gcbdlgps6"},
+		{name: "trustd token", actual: bundle.TrustdInfo.Token, expected: "pd1964.25j41vr0h8vdeuj0"},
+		{name: "talos api ca", actual: sum(bundle.Certs.OS.Crt), expected: "a1c668944c8266489308db6ad84a3aa418968c81f6da837c0da4c2a8c49ccf35"},
+		{name: "kubernetes ca", actual: sum(bundle.Certs.K8s.Crt), expected: "3fcde01e59ec6e0298eaa8adefc6862711c40f5048ddcc88c3f5d2c706635f57"},
+	} {
+		if test.actual != test.expected {
+			t.Errorf("%s: expected %q, got %q", test.name, test.expected, test.actual)
+		}
+	}
+}
+
+func TestNewSeededSecretsBundleShortSeed(t *testing.T) {
+	t.Parallel()
+
+	if _, err := newSeededSecretsBundle([]byte("too short"), testSeededSecretsNotBefore, config.TalosVersionCurrent); err == nil {
+		t.Fatal("expected an error for a short seed")
+	}
+}
@@ -0,0 +1,914 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package talos
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/cosi-project/runtime/pkg/safe"
+	"github.com/hashicorp/terraform-plugin-framework-timeouts/resource/timeouts"
+	"github.com/hashicorp/terraform-plugin-framework-validators/listvalidator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/mapplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/objectplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-framework/types/basetypes"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/retry"
+	"github.com/siderolabs/crypto/x509"
+	"github.com/siderolabs/talos/pkg/cluster"
+	machineapi "github.com/siderolabs/talos/pkg/machinery/api/machine"
+	"github.com/siderolabs/talos/pkg/machinery/client"
+	clientconfig "github.com/siderolabs/talos/pkg/machinery/client/config"
+	"github.com/siderolabs/talos/pkg/machinery/config"
+	"github.com/siderolabs/talos/pkg/machinery/config/encoder"
+	"github.com/siderolabs/talos/pkg/machinery/config/generate/secrets"
+	"github.com/siderolabs/talos/pkg/machinery/config/types/v1alpha1"
+	"github.com/siderolabs/talos/pkg/machinery/constants"
+	configresource "github.com/siderolabs/talos/pkg/machinery/resources/config"
+	secretsresource "github.com/siderolabs/talos/pkg/machinery/resources/secrets"
+	v1alpha1resource "github.com/siderolabs/talos/pkg/machinery/resources/v1alpha1"
+	"github.com/siderolabs/talos/pkg/machinery/role"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	caRotationOS         = "os"
+	caRotationKubernetes = "kubernetes"
+)
+
+// CA rotation phases, recorded in state after each one completes.
+const (
+	// caRotationPhasePending: the new CA was generated and the previous CA recorded, the nodes are being patched to accept the new CA.
+	caRotationPhasePending = "pending"
+	// caRotationPhaseAccepted: the new CA is accepted by all nodes, the old CA still issues certificates.
+	caRotationPhaseAccepted = "accepted"
+	// caRotationPhaseIssuing: the new CA issues certificates, the old CA is still accepted.
+	caRotationPhaseIssuing = "issuing"
+	// caRotationPhaseCompleted: the old CA was removed from the accepted CAs.
+	caRotationPhaseCompleted = "completed"
+)
+
+type talosCARotationResource struct{}
+
+var (
+	_ resource.Resource                   = &talosCARotationResource{}
+	_ resource.ResourceWithModifyPlan     = &talosCARotationResource{}
+	_ resource.ResourceWithValidateConfig = &talosCARotationResource{}
+)
+
+type talosCARotationResourceModel struct {
+	ID                     types.String          `tfsdk:"id"`
+	CA                     types.String          `tfsdk:"ca"`
+	ControlPlaneNodes      types.List            `tfsdk:"control_plane_nodes"`
+	WorkerNodes            types.List            `tfsdk:"worker_nodes"`
+	Endpoint               types.String          `tfsdk:"endpoint"`
+	KubernetesEndpoint     types.String          `tfsdk:"kubernetes_endpoint"`
+	ClientConfiguration    basetypes.ObjectValue `tfsdk:"client_configuration"`
+	ClientConfigurationWO  basetypes.ObjectValue `tfsdk:"client_configuration_wo"`
+	Triggers               types.Map             `tfsdk:"triggers"`
+	Phase                  types.String          `tfsdk:"phase"`
+	NewCA                  types.Object          `tfsdk:"new_ca"`
+	PreviousCACertificate  types.String          `tfsdk:"previous_ca_certificate"`
+	NewClientConfiguration types.Object          `tfsdk:"new_client_configuration"`
+	Timeouts               timeouts.Value        `tfsdk:"timeouts"`
+}
+
+var (
+	caRotationCertKeyPairAttrTypes = map[string]attr.Type{
+		"cert": types.StringType,
+		"key":  types.StringType,
+	}
+
+	caRotationClientConfigurationAttrTypes = map[string]attr.Type{
+		"ca_certificate":     types.StringType,
+		"client_certificate": types.StringType,
+		"client_key":         types.StringType,
+	}
+)
+
+// NewTalosCARotationResource implements the resource.Resource interface.
+func NewTalosCARotationResource() resource.Resource {
+	return &talosCARotationResource{}
+}
+
+func (r *talosCARotationResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_ca_rotation"
+}
+
+func (r *talosCARotationResource) Schema(ctx context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Rotates the Talos API or Kubernetes CA of a cluster, the same way as `talosctl rotate-ca`.",
+		MarkdownDescription: "Rotates the Talos API (`os`) or Kubernetes CA of a cluster when created, the same way as `talosctl rotate-ca`: " +
+			"a new CA is generated and added to the accepted CAs of every node, then made the issuing CA while the old one stays accepted, " +
+			"and finally the old CA is removed. Connectivity is verified with the matching credentials before and after every phase.\n\n" +
+			"The completed phase is recorded in `phase`, starting with `pending` before the first node is patched. If the rotation fails, the resource is tainted; " +
+			"run `terraform untaint` and apply again to resume from the recorded phase, as replacing the resource starts over with yet another CA.\n\n" +
+			"The machine configurations are patched in place. Once the rotation completed, feed `new_ca` into the machine secrets " +
+			"used to generate the machine configurations, otherwise the next configuration apply reverts to the old CA. " +
+			"Change `triggers` to rotate again.",
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed: true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"ca": schema.StringAttribute{
+				Required:    true,
+				Description: "The CA to rotate, `os` for the Talos API CA or `kubernetes` for the Kubernetes CA.",
+				Validators: []validator.String{
+					stringvalidator.OneOf(caRotationOS, caRotationKubernetes),
+				},
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"control_plane_nodes": schema.ListAttribute{
+				Required:    true,
+				ElementType: types.StringType,
+				Description: "The control plane nodes of the cluster. Nodes are patched in order, control plane nodes first.",
+				Validators: []validator.List{
+					listvalidator.SizeAtLeast(1),
+				},
+			},
+			"worker_nodes": schema.ListAttribute{
+				Optional:    true,
+				ElementType: types.StringType,
+				Description: "The worker nodes of the cluster.",
+			},
+			"endpoint": schema.StringAttribute{
+				Optional:    true,
+				Computed:    true,
+				Description: "The endpoint to use when connecting to the nodes. Defaults to the first control plane node.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"kubernetes_endpoint": schema.StringAttribute{
+				Optional:    true,
+				Description: "Overrides the Kubernetes API endpoint used to verify connectivity during a `kubernetes` rotation.",
+			},
+			"client_configuration": schema.SingleNestedAttribute{
+				Optional:    true,
+				Description: "The Talos client configuration of the cluster before the rotation. Use client_configuration_wo when using ephemeral resources.",
+				Attributes: map[string]schema.Attribute{
+					"ca_certificate": schema.StringAttribute{
+						Required:    true,
+						Description: "The client CA certificate.",
+					},
+					"client_certificate": schema.StringAttribute{
+						Required:    true,
+						Description: "The client certificate.",
+					},
+					"client_key": schema.StringAttribute{
+						Required:    true,
+						Sensitive:   true,
+						Description: "The client key.",
+					},
+				},
+			},
+			"client_configuration_wo": schema.SingleNestedAttribute{
+				Optional:    true,
+				WriteOnly:   true,
+				Description: "Write-only variant of client_configuration for use with ephemeral resources. Requires Terraform 1.11+.",
+				Attributes: map[string]schema.Attribute{
+					"ca_certificate": schema.StringAttribute{
+						Required:    true,
+						WriteOnly:   true,
+						Description: "The client CA certificate.",
+					},
+					"client_certificate": schema.StringAttribute{
+						Required:    true,
+						WriteOnly:   true,
+						Description: "The client certificate.",
+					},
+					"client_key": schema.StringAttribute{
+						Required:    true,
+						Sensitive:   true,
+						WriteOnly:   true,
+						Description: "The client key.",
+					},
+				},
+			},
+			"triggers": schema.MapAttribute{
+				Optional:    true,
+				ElementType: types.StringType,
+				Description: "Arbitrary values that, when changed, rotate the CA again.",
+				PlanModifiers: []planmodifier.Map{
+					mapplanmodifier.RequiresReplace(),
+				},
+			},
+			"phase": schema.StringAttribute{
+				Computed:    true,
+				Description: "The last completed phase of the rotation: `pending`, `accepted`, `issuing` or `completed`.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"new_ca": schema.SingleNestedAttribute{
+				Computed:    true,
+				Description: "The new CA, in the same format as the certificates in `talos_machine_secrets`.",
+				Attributes: map[string]schema.Attribute{
+					"cert": schema.StringAttribute{
+						Computed:    true,
+						Description: "The CA certificate.",
+					},
+					"key": schema.StringAttribute{
+						Computed:    true,
+						Sensitive:   true,
+						Description: "The CA key.",
+					},
+				},
+				PlanModifiers: []planmodifier.Object{
+					objectplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"previous_ca_certificate": schema.StringAttribute{
+				Computed:    true,
+				Description: "The CA certificate that was issuing before the rotation.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"new_client_configuration": schema.SingleNestedAttribute{
+				Computed:    true,
+				Description: "An admin Talos client configuration signed by the new CA. Null for `kubernetes` rotations.",
+				Attributes: map[string]schema.Attribute{
+					"ca_certificate": schema.StringAttribute{
+						Computed:    true,
+						Description: "The client CA certificate.",
+					},
+					"client_certificate": schema.StringAttribute{
+						Computed:    true,
+						Description: "The client certificate.",
+					},
+					"client_key": schema.StringAttribute{
+						Computed:    true,
+						Sensitive:   true,
+						Description: "The client key.",
+					},
+				},
+				PlanModifiers: []planmodifier.Object{
+					objectplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"timeouts": timeouts.Attributes(ctx, timeouts.Opts{
+				Create: true,
+				Update: true,
+			}),
+		},
+	}
+}
+
+func (r *talosCARotationResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var cfg talosCARotationResourceModel
+
+	resp.Diagnostics.Append(req.Config.Get(ctx, &cfg)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	clientSet := !cfg.ClientConfiguration.IsNull()
+	clientWOSet := !cfg.ClientConfigurationWO.IsNull()
+
+	if !clientSet && !clientWOSet {
+		resp.Diagnostics.AddError(
+			"Missing client configuration",
+			"Exactly one of client_configuration or client_configuration_wo must be set.",
+		)
+	}
+
+	if clientSet && clientWOSet {
+		resp.Diagnostics.AddError(
+			"Conflicting client configuration",
+			"Only one of client_configuration or client_configuration_wo can be set, not both.",
+		)
+	}
+}
+
+func (r *talosCARotationResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	// nothing to resume on create and delete
+	if req.State.Raw.IsNull() || req.Plan.Raw.IsNull() {
+		return
+	}
+
+	var phase types.String
+
+	resp.Diagnostics.Append(req.State.GetAttribute(ctx, path.Root("phase"), &phase)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if phase.ValueString() != caRotationPhaseCompleted {
+		tflog.Info(ctx, "CA rotation is incomplete, resuming", map[string]any{"phase": phase.ValueString()})
+
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("phase"), types.StringUnknown())...)
+	}
+}
+
+func (r *talosCARotationResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var plan talosCARotationResourceModel
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var cfgModel talosCARotationResourceModel
+
+	resp.Diagnostics.Append(req.Config.Get(ctx, &cfgModel)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	timeout, diags := plan.Timeouts.Create(ctx, 30*time.Minute)
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	newCA, err := caRotationGenerateCA(plan.CA.ValueString())
+	if err != nil {
+		resp.Diagnostics.AddError("failed to generate new CA", err.Error())
+
+		return
+	}
+
+	plan.NewCA, diags = types.ObjectValueFrom(ctx, caRotationCertKeyPairAttrTypes, machineSecretsCertKeyPair{
+		Cert: types.StringValue(bytesToBase64(newCA.Crt)),
+		Key:  types.StringValue(bytesToBase64(newCA.Key)),
+	})
+	resp.Diagnostics.Append(diags...)
+
+	plan.NewClientConfiguration = types.ObjectNull(caRotationClientConfigurationAttrTypes)
+
+	if plan.CA.ValueString() == caRotationOS {
+		cc, ccErr := generateClientConfigurationWithTTL(&secrets.Bundle{Certs: &secrets.Certs{OS: newCA}}, role.MakeSet(role.Admin), constants.TalosAPIDefaultCertificateValidityDuration)
+		if ccErr != nil {
+			resp.Diagnostics.AddError("failed to generate client configuration", ccErr.Error())
+
+			return
+		}
+
+		plan.NewClientConfiguration, diags = types.ObjectValueFrom(ctx, caRotationClientConfigurationAttrTypes, cc)
+		resp.Diagnostics.Append(diags...)
+	}
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	plan.ID = types.StringValue(time.Now().UTC().Format(time.RFC3339))
+	plan.Phase = types.StringValue("")
+	plan.PreviousCACertificate = types.StringValue("")
+
+	r.rotate(ctx, &plan, cfgModel.ClientConfigurationWO, timeout, &resp.Diagnostics)
+
+	// the rotation failed before reaching the pending phase, so no node was patched and there is nothing to resume
+	if plan.Phase.ValueString() == "" {
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+func (r *talosCARotationResource) Read(_ context.Context, _ resource.ReadRequest, _ *resource.ReadResponse) {
+}
+
+func (r *talosCARotationResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var plan, state talosCARotationResourceModel
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	plan.Phase = state.Phase
+
+	if state.Phase.ValueString() != caRotationPhaseCompleted {
+		var cfgModel talosCARotationResourceModel
+
+		resp.Diagnostics.Append(req.Config.Get(ctx, &cfgModel)...)
+
+		if resp.Diagnostics.HasError() {
+			return
+		}
+
+		timeout, diags := plan.Timeouts.Update(ctx, 30*time.Minute)
+		resp.Diagnostics.Append(diags...)
+
+		if resp.Diagnostics.HasError() {
+			return
+		}
+
+		r.rotate(ctx, &plan, cfgModel.ClientConfigurationWO, timeout, &resp.Diagnostics)
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+func (r *talosCARotationResource) Delete(_ context.Context, _ resource.DeleteRequest, _ *resource.DeleteResponse) {
+}
+
+// rotate runs the rotation from the phase recorded in plan, updating plan.Phase and plan.PreviousCACertificate as it goes.
+func (r *talosCARotationResource) rotate(ctx context.Context, plan *talosCARotationResourceModel, clientConfigurationWO basetypes.ObjectValue, timeout time.Duration, diags *diag.Diagnostics) {
+	rotation, err := newCARotation(ctx, plan, clientConfigurationWO)
+	if err != nil {
+		diags.AddError("failed to prepare CA rotation", err.Error())
+
+		return
+	}
+
+	ctxDeadline, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	err = rotation.run(ctxDeadline, plan.Phase.ValueString(), func(phase string) {
+		tflog.Info(ctx, "CA rotation phase completed", map[string]any{"ca": rotation.ca, "phase": phase})
+
+		plan.Phase = types.StringValue(phase)
+		plan.PreviousCACertificate = types.StringValue(bytesToBase64(rotation.previousCA))
+	})
+	if err != nil {
+		detail := err.Error()
+
+		if plan.Phase.ValueString() != "" {
+			detail += fmt.Sprintf("\n\nThe rotation stopped after the %q phase. Run `terraform untaint` on this resource and apply again to resume.", plan.Phase.ValueString())
+		}
+
+		diags.AddError("CA rotation failed", detail)
+	}
+}
+
+// caRotation holds everything needed to run or resume a CA rotation.
+type caRotation struct {
+	ca                 string
+	endpoint           string
+	kubernetesEndpoint string
+	controlPlaneNodes  []string
+	workerNodes        []string
+
+	newCA      *x509.PEMEncodedCertificateAndKey
+	previousCA []byte
+
+	// currentConfig is the client configuration the rotation started with.
+	currentConfig *clientconfig.Config
+	// intermediateConfig trusts both the previous and the new CA, and authenticates with a client certificate signed by the new CA.
+	intermediateConfig *clientconfig.Config
+	// newConfig trusts the new CA and authenticates with a client certificate signed by it.
+	newConfig *clientconfig.Config
+}
+
+func newCARotation(ctx context.Context, plan *talosCARotationResourceModel, clientConfigurationWO basetypes.ObjectValue) (*caRotation, error) {
+	currentConfig, err := resolveClientConfiguration(ctx, clientConfigurationWO, plan.ClientConfiguration)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build talos config: %w", err)
+	}
+
+	rotation := &caRotation{
+		ca:                 plan.CA.ValueString(),
+		kubernetesEndpoint: plan.KubernetesEndpoint.ValueString(),
+		currentConfig:      currentConfig,
+	}
+
+	if diags := plan.ControlPlaneNodes.ElementsAs(ctx, &rotation.controlPlaneNodes, false); diags.HasError() {
+		return nil, fmt.Errorf("failed to read control_plane_nodes: %v", diags)
+	}
+
+	if diags := plan.WorkerNodes.ElementsAs(ctx, &rotation.workerNodes, true); diags.HasError() {
+		return nil, fmt.Errorf("failed to read worker_nodes: %v", diags)
+	}
+
+	if plan.Endpoint.IsUnknown() || plan.Endpoint.IsNull() {
+		plan.Endpoint = types.StringValue(rotation.controlPlaneNodes[0])
+	}
+
+	rotation.endpoint = plan.Endpoint.ValueString()
+
+	var newCA machineSecretsCertKeyPair
+
+	if diags := plan.NewCA.As(ctx, &newCA, basetypes.ObjectAsOptions{}); diags.HasError() {
+		return nil, fmt.Errorf("failed to read new_ca: %v", diags)
+	}
+
+	rotation.newCA = &x509.PEMEncodedCertificateAndKey{}
+
+	if rotation.newCA.Crt, err = base64ToBytes(newCA.Cert.ValueString()); err != nil {
+		return nil, fmt.Errorf("failed to decode new CA certificate: %w", err)
+	}
+
+	if rotation.newCA.Key, err = base64ToBytes(newCA.Key.ValueString()); err != nil {
+		return nil, fmt.Errorf("failed to decode new CA key: %w", err)
+	}
+
+	if rotation.previousCA, err = base64ToBytes(plan.PreviousCACertificate.ValueString()); err != nil {
+		return nil, fmt.Errorf("failed to decode previous CA certificate: %w", err)
+	}
+
+	if rotation.ca == caRotationOS {
+		var cc clientConfiguration
+
+		if diags := plan.NewClientConfiguration.As(ctx, &cc, basetypes.ObjectAsOptions{}); diags.HasError() {
+			return nil, fmt.Errorf("failed to read new_client_configuration: %v", diags)
+		}
+
+		if rotation.newConfig, err = talosClientTFConfigToTalosClientConfig("dynamic", cc.CA.ValueString(), cc.Cert.ValueString(), cc.Key.ValueString()); err != nil {
+			return nil, err
+		}
+	}
+
+	return rotation, nil
+}
+
+// run executes the phases following completedPhase, calling onPhase after each one.
+//
+//nolint:gocyclo,cyclop
+func (r *caRotation) run(ctx context.Context, completedPhase string, onPhase func(phase string)) error {
+	if completedPhase == "" {
+		if err := r.fetchPreviousCA(ctx); err != nil {
+			return err
+		}
+
+		if err := r.verify(ctx, r.currentConfig, "existing PKI"); err != nil {
+			return err
+		}
+
+		// record the new CA before patching any node, so a partially applied phase can be resumed with the same CA
+		completedPhase = caRotationPhasePending
+		onPhase(completedPhase)
+	}
+
+	if completedPhase == caRotationPhasePending {
+		if err := r.patchAllNodes(ctx, r.currentConfig, r.acceptNewCA); err != nil {
+			return fmt.Errorf("error adding the new CA as accepted: %w", err)
+		}
+
+		completedPhase = caRotationPhaseAccepted
+		onPhase(completedPhase)
+	}
+
+	if err := r.buildIntermediateConfig(); err != nil {
+		return err
+	}
+
+	if completedPhase == caRotationPhaseAccepted {
+		if err := r.verify(ctx, r.transitionConfig(), "new client certificate and previous CA"); err != nil {
+			return err
+		}
+
+		if err := r.patchAllNodes(ctx, r.transitionConfig(), r.swapCAs); err != nil {
+			return fmt.Errorf("error making the new CA the issuing CA: %w", err)
+		}
+
+		completedPhase = caRotationPhaseIssuing
+		onPhase(completedPhase)
+	}
+
+	if completedPhase == caRotationPhaseIssuing {
+		if err := r.verify(ctx, r.finalConfig(), "new PKI"); err != nil {
+			return err
+		}
+
+		if err := r.patchAllNodes(ctx, r.finalConfig(), r.dropPreviousCA); err != nil {
+			return fmt.Errorf("error removing the previous CA: %w", err)
+		}
+
+		if err := r.verify(ctx, r.finalConfig(), "new PKI"); err != nil {
+			return err
+		}
+
+		onPhase(caRotationPhaseCompleted)
+	}
+
+	return nil
+}
+
+// fetchPreviousCA reads the issuing CA from the first control plane node.
+func (r *caRotation) fetchPreviousCA(ctx context.Context) error {
+	return r.withClient(ctx, r.currentConfig, func(c *client.Client) error {
+		nodeCtx := client.WithNode(ctx, r.controlPlaneNodes[0])
+
+		if r.ca == caRotationOS {
+			osRoot, err := safe.StateGetByID[*secretsresource.OSRoot](nodeCtx, c.COSI, secretsresource.OSRootID)
+			if err != nil {
+				return fmt.Errorf("error fetching the current Talos CA: %w", err)
+			}
+
+			r.previousCA = osRoot.TypedSpec().IssuingCA.Crt
+
+			return nil
+		}
+
+		k8sRoot, err := safe.StateGetByID[*secretsresource.KubernetesRoot](nodeCtx, c.COSI, secretsresource.KubernetesRootID)
+		if err != nil {
+			return fmt.Errorf("error fetching the current Kubernetes CA: %w", err)
+		}
+
+		r.previousCA = k8sRoot.TypedSpec().IssuingCA.Crt
+
+		return nil
+	})
+}
+
+// buildIntermediateConfig builds the client configuration used while the new CA becomes the issuing CA.
+// Nodes already swapped serve Talos API certificates issued by the new CA while the others still use the previous CA,
+// so it trusts both, which allows resuming a partially applied swap.
+func (r *caRotation) buildIntermediateConfig() error {
+	if r.ca != caRotationOS {
+		return nil
+	}
+
+	newContext := r.newConfig.Contexts[r.newConfig.Context]
+	caBundle := slices.Concat(r.previousCA, r.newCA.Crt)
+
+	intermediate, err := talosClientTFConfigToTalosClientConfig("dynamic", bytesToBase64(caBundle), newContext.Crt, newContext.Key)
+	if err != nil {
+		return err
+	}
+
+	r.intermediateConfig = intermediate
+
+	return nil
+}
+
+// transitionConfig returns the client configuration to use while the new CA is accepted, but not yet issuing.
+func (r *caRotation) transitionConfig() *clientconfig.Config {
+	if r.ca == caRotationOS {
+		return r.intermediateConfig
+	}
+
+	return r.currentConfig
+}
+
+// finalConfig returns the client configuration to use once the new CA issues certificates.
+func (r *caRotation) finalConfig() *clientconfig.Config {
+	if r.ca == caRotationOS {
+		return r.newConfig
+	}
+
+	return r.currentConfig
+}
+
+func (r *caRotation) withClient(ctx context.Context, talosConfig *clientconfig.Config, fn func(c *client.Client) error) error {
+	c, err := client.New(ctx, client.WithConfig(talosConfig), client.WithEndpoints(r.endpoint))
+	if err != nil {
+		return err
+	}
+
+	defer c.Close() //nolint:errcheck
+
+	return fn(c)
+}
+
+// verify checks that every node answers with the given client configuration,
+// and for Kubernetes rotations that all Kubernetes nodes are ready.
+func (r *caRotation) verify(ctx context.Context, talosConfig *clientconfig.Config, label string) error {
+	tflog.Info(ctx, "verifying connectivity", map[string]any{"ca": r.ca, "with": label})
+
+	if r.ca == caRotationOS {
+		return r.withClient(ctx, talosConfig, func(c *client.Client) error {
+			for _, node := range slices.Concat(r.controlPlaneNodes, r.workerNodes) {
+				if err := retry.RetryContext(ctx, time.Minute, func() *retry.RetryError {
+					if _, err := c.Version(client.WithNode(ctx, node)); err != nil {
+						return retry.RetryableError(err)
+					}
+
+					return nil
+				}); err != nil {
+					return fmt.Errorf("error verifying connectivity with %s on node %s: %w", label, node, err)
+				}
+			}
+
+			return nil
+		})
+	}
+
+	return r.withClient(ctx, talosConfig, func(c *client.Client) error {
+		clientProvider := &cluster.ConfigClientProvider{DefaultClient: c}
+		defer clientProvider.Close() //nolint:errcheck
+
+		// a fresh client, so the kubeconfig is issued by the currently issuing CA
+		k8sProvider := &cluster.KubernetesClient{
+			ClientProvider: clientProvider,
+			ForceEndpoint:  r.kubernetesEndpoint,
+		}
+		defer k8sProvider.K8sClose() //nolint:errcheck
+
+		clientset, err := k8sProvider.K8sClient(client.WithNode(ctx, r.controlPlaneNodes[0]))
+		if err != nil {
+			return fmt.Errorf("error building Kubernetes client: %w", err)
+		}
+
+		return retry.RetryContext(ctx, 3*time.Minute, func() *retry.RetryError {
+			nodes, err := clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+			if err != nil {
+				return retry.RetryableError(err)
+			}
+
+			var notReady []string
+
+			for _, node := range nodes.Items {
+				for _, cond := range node.Status.Conditions {
+					if cond.Type == corev1.NodeReady && cond.Status != corev1.ConditionTrue {
+						notReady = append(notReady, node.Name)
+					}
+				}
+			}
+
+			if len(notReady) > 0 {
+				return retry.RetryableError(fmt.Errorf("error verifying connectivity with %s: nodes not ready: %q", label, notReady))
+			}
+
+			return nil
+		})
+	})
+}
+
+// acceptNewCA adds the new CA to the accepted CAs.
+func (r *caRotation) acceptNewCA(_ bool, cfg *v1alpha1.Config) {
+	acceptedCAs := r.acceptedCAs(cfg)
+
+	if !caRotationContainsCA(*acceptedCAs, r.newCA.Crt) {
+		*acceptedCAs = append(*acceptedCAs, &x509.PEMEncodedCertificate{Crt: r.newCA.Crt})
+	}
+}
+
+// swapCAs makes the new CA the issuing CA and moves the previous CA to the accepted CAs.
+func (r *caRotation) swapCAs(controlPlane bool, cfg *v1alpha1.Config) {
+	acceptedCAs := r.acceptedCAs(cfg)
+
+	*acceptedCAs = slices.DeleteFunc(*acceptedCAs, func(ca *x509.PEMEncodedCertificate) bool {
+		return bytes.Equal(ca.Crt, r.newCA.Crt)
+	})
+
+	if !caRotationContainsCA(*acceptedCAs, r.previousCA) {
+		*acceptedCAs = append(*acceptedCAs, &x509.PEMEncodedCertificate{Crt: r.previousCA})
+	}
+
+	issuingCA := &x509.PEMEncodedCertificateAndKey{Crt: r.newCA.Crt}
+
+	// only control plane nodes hold the CA key
+	if controlPlane {
+		issuingCA.Key = r.newCA.Key
+	}
+
+	if r.ca == caRotationOS {
+		cfg.MachineConfig.MachineCA = issuingCA
+	} else {
+		cfg.ClusterConfig.ClusterCA = issuingCA
+	}
+}
+
+// dropPreviousCA removes the previous CA from the accepted CAs.
+func (r *caRotation) dropPreviousCA(_ bool, cfg *v1alpha1.Config) {
+	acceptedCAs := r.acceptedCAs(cfg)
+
+	*acceptedCAs = slices.DeleteFunc(*acceptedCAs, func(ca *x509.PEMEncodedCertificate) bool {
+		return bytes.Equal(ca.Crt, r.previousCA)
+	})
+}
+
+func (r *caRotation) acceptedCAs(cfg *v1alpha1.Config) *[]*x509.PEMEncodedCertificate {
+	if r.ca == caRotationOS {
+		return &cfg.MachineConfig.MachineAcceptedCAs
+	}
+
+	return &cfg.ClusterConfig.ClusterAcceptedCAs
+}
+
+func caRotationContainsCA(cas []*x509.PEMEncodedCertificate, crt []byte) bool {
+	return slices.ContainsFunc(cas, func(ca *x509.PEMEncodedCertificate) bool {
+		return bytes.Equal(ca.Crt, crt)
+	})
+}
+
+// patchAllNodes patches the machine configuration of every node, control plane nodes first.
+// Nodes whose configuration doesn't change are skipped, so a resumed phase doesn't patch nodes twice.
+func (r *caRotation) patchAllNodes(ctx context.Context, talosConfig *clientconfig.Config, patch func(controlPlane bool, cfg *v1alpha1.Config)) error {
+	return r.withClient(ctx, talosConfig, func(c *client.Client) error {
+		for _, node := range slices.Concat(r.controlPlaneNodes, r.workerNodes) {
+			controlPlane := slices.Contains(r.controlPlaneNodes, node)
+
+			if err := r.patchNode(client.WithNode(ctx, node), c, func(cfg *v1alpha1.Config) error {
+				patch(controlPlane, cfg)
+
+				return nil
+			}); err != nil {
+				return fmt.Errorf("error patching node %s: %w", node, err)
+			}
+
+			tflog.Info(ctx, "patched machine configuration", map[string]any{"ca": r.ca, "node": node})
+		}
+
+		return nil
+	})
+}
+
+func (r *caRotation) patchNode(nodeCtx context.Context, c *client.Client, patch func(cfg *v1alpha1.Config) error) error {
+	mc, err := safe.StateGetByID[*configresource.MachineConfig](nodeCtx, c.COSI, configresource.ActiveID)
+	if err != nil {
+		return fmt.Errorf("error fetching machine configuration: %w", err)
+	}
+
+	encoderOpt := encoder.WithComments(encoder.CommentsDisabled)
+
+	currentBytes, err := mc.Provider().EncodeBytes(encoderOpt)
+	if err != nil {
+		return fmt.Errorf("error serializing machine configuration: %w", err)
+	}
+
+	patched, err := mc.Provider().PatchV1Alpha1(patch)
+	if err != nil {
+		return fmt.Errorf("error patching machine configuration: %w", err)
+	}
+
+	patchedBytes, err := patched.EncodeBytes(encoderOpt)
+	if err != nil {
+		return fmt.Errorf("error serializing machine configuration: %w", err)
+	}
+
+	if bytes.Equal(currentBytes, patchedBytes) {
+		return nil
+	}
+
+	var kubeletCreated time.Time
+
+	// kubelet restarts to pick up a new Kubernetes CA, wait for it to come back before moving on
+	if r.ca == caRotationKubernetes {
+		kubelet, kubeletErr := safe.StateGetByID[*v1alpha1resource.Service](nodeCtx, c.COSI, "kubelet")
+		if kubeletErr != nil {
+			return fmt.Errorf("error fetching kubelet service: %w", kubeletErr)
+		}
+
+		kubeletCreated = kubelet.Metadata().Created()
+	}
+
+	if _, err = c.ApplyConfiguration(nodeCtx, &machineapi.ApplyConfigurationRequest{
+		Data: patchedBytes,
+		Mode: machineapi.ApplyConfigurationRequest_NO_REBOOT,
+	}); err != nil {
+		return fmt.Errorf("error applying machine configuration: %w", err)
+	}
+
+	if r.ca != caRotationKubernetes {
+		return nil
+	}
+
+	return retry.RetryContext(nodeCtx, 5*time.Minute, func() *retry.RetryError {
+		kubelet, kubeletErr := safe.StateGetByID[*v1alpha1resource.Service](nodeCtx, c.COSI, "kubelet")
+		if kubeletErr != nil {
+			return retry.RetryableError(kubeletErr)
+		}
+
+		if !kubelet.Metadata().Created().After(kubeletCreated) || !kubelet.TypedSpec().Running || !kubelet.TypedSpec().Healthy {
+			return retry.RetryableError(fmt.Errorf("waiting for kubelet to restart"))
+		}
+
+		return nil
+	})
+}
+
+// caRotationGenerateCA generates a new CA of the given kind.
+func caRotationGenerateCA(ca string) (*x509.PEMEncodedCertificateAndKey, error) {
+	var (
+		newCA *x509.CertificateAuthority
+		err   error
+	)
+
+	switch ca {
+	case caRotationOS:
+		newCA, err = secrets.NewTalosCA(time.Now())
+	case caRotationKubernetes:
+		newCA, err = secrets.NewKubernetesCA(time.Now(), config.TalosVersionCurrent)
+	default:
+		return nil, fmt.Errorf("unsupported CA %q", ca)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return x509.NewCertificateAndKeyFromCertificateAuthority(newCA), nil
+}
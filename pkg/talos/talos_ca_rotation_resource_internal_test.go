@@ -0,0 +1,161 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package talos //nolint:testpackage // needs access to unexported caRotation
+
+import (
+	"bytes"
+	stdx509 "crypto/x509"
+	"encoding/pem"
+	"net"
+	"testing"
+
+	"github.com/siderolabs/crypto/x509"
+	"github.com/siderolabs/talos/pkg/machinery/config/generate/secrets"
+	"github.com/siderolabs/talos/pkg/machinery/config/types/v1alpha1"
+	"github.com/siderolabs/talos/pkg/machinery/constants"
+	"github.com/siderolabs/talos/pkg/machinery/role"
+)
+
+func TestCARotationPatches(t *testing.T) {
+	t.Parallel()
+
+	for _, ca := range []string{caRotationOS, caRotationKubernetes} {
+		t.Run(ca, func(t *testing.T) {
+			t.Parallel()
+
+			newCA, err := caRotationGenerateCA(ca)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			previousCA, err := caRotationGenerateCA(ca)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			r := &caRotation{ca: ca, newCA: newCA, previousCA: previousCA.Crt}
+
+			cfg := &v1alpha1.Config{
+				MachineConfig: &v1alpha1.MachineConfig{MachineCA: previousCA},
+				ClusterConfig: &v1alpha1.ClusterConfig{ClusterCA: previousCA},
+			}
+
+			issuingCA := func() *x509.PEMEncodedCertificateAndKey {
+				if ca == caRotationOS {
+					return cfg.MachineConfig.MachineCA
+				}
+
+				return cfg.ClusterConfig.ClusterCA
+			}
+
+			// every patch is applied twice, as a resumed phase would
+			r.acceptNewCA(true, cfg)
+			r.acceptNewCA(true, cfg)
+
+			if accepted := *r.acceptedCAs(cfg); len(accepted) != 1 || !bytes.Equal(accepted[0].Crt, newCA.Crt) {
+				t.Fatalf("expected only the new CA to be accepted, got %d CAs", len(accepted))
+			}
+
+			r.swapCAs(false, cfg)
+			r.swapCAs(false, cfg)
+
+			if accepted := *r.acceptedCAs(cfg); len(accepted) != 1 || !bytes.Equal(accepted[0].Crt, previousCA.Crt) {
+				t.Fatalf("expected only the previous CA to be accepted, got %d CAs", len(accepted))
+			}
+
+			if !bytes.Equal(issuingCA().Crt, newCA.Crt) || issuingCA().Key != nil {
+				t.Fatal("expected the new CA certificate without key to be issuing on workers")
+			}
+
+			r.swapCAs(true, cfg)
+
+			if !bytes.Equal(issuingCA().Key, newCA.Key) {
+				t.Fatal("expected the new CA key on control plane nodes")
+			}
+
+			r.dropPreviousCA(true, cfg)
+			r.dropPreviousCA(true, cfg)
+
+			if accepted := *r.acceptedCAs(cfg); len(accepted) != 0 {
+				t.Fatalf("expected no accepted CAs, got %d", len(accepted))
+			}
+		})
+	}
+}
+
+// TestCARotationResumeAfterPartialSwap covers resuming from the accepted phase after the issuing phase failed partway:
+// the swapped endpoint node already serves a certificate issued by the new CA, the other nodes by the previous one.
+func TestCARotationResumeAfterPartialSwap(t *testing.T) {
+	t.Parallel()
+
+	newCA, err := caRotationGenerateCA(caRotationOS)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	previousCA, err := caRotationGenerateCA(caRotationOS)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cc, err := generateClientConfigurationWithTTL(&secrets.Bundle{Certs: &secrets.Certs{OS: newCA}}, role.MakeSet(role.Admin), constants.TalosAPIDefaultCertificateValidityDuration)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newConfig, err := talosClientTFConfigToTalosClientConfig("dynamic", cc.CA.ValueString(), cc.Cert.ValueString(), cc.Key.ValueString())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the state of a resumed rotation
+	r := &caRotation{ca: caRotationOS, newCA: newCA, previousCA: previousCA.Crt, newConfig: newConfig}
+
+	if err = r.buildIntermediateConfig(); err != nil {
+		t.Fatal(err)
+	}
+
+	transitionContext := r.transitionConfig().Contexts[r.transitionConfig().Context]
+
+	if transitionContext.Crt != newConfig.Contexts[newConfig.Context].Crt {
+		t.Error("expected the transition config to authenticate with the client certificate signed by the new CA")
+	}
+
+	caBundle, err := base64ToBytes(transitionContext.CA)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	roots := stdx509.NewCertPool()
+	if !roots.AppendCertsFromPEM(caBundle) {
+		t.Fatal("failed to parse the transition config CA")
+	}
+
+	for name, issuingCA := range map[string]*x509.PEMEncodedCertificateAndKey{
+		"swapped node":     newCA,
+		"not swapped node": previousCA,
+	} {
+		ca, caErr := x509.NewCertificateAuthorityFromCertificateAndKey(issuingCA)
+		if caErr != nil {
+			t.Fatal(caErr)
+		}
+
+		serverCert, caErr := x509.NewKeyPair(ca, x509.IPAddresses([]net.IP{net.ParseIP("10.5.0.2")}))
+		if caErr != nil {
+			t.Fatal(caErr)
+		}
+
+		block, _ := pem.Decode(serverCert.CrtPEM)
+
+		leaf, caErr := stdx509.ParseCertificate(block.Bytes)
+		if caErr != nil {
+			t.Fatal(caErr)
+		}
+
+		if _, caErr = leaf.Verify(stdx509.VerifyOptions{Roots: roots}); caErr != nil {
+			t.Errorf("%s: expected the transition config to trust the server certificate: %v", name, caErr)
+		}
+	}
+}
@@ -0,0 +1,460 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package talos
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"net"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework-timeouts/resource/timeouts"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-framework/types/basetypes"
+	"github.com/siderolabs/talos/pkg/machinery/constants"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// certificateExpiryLiveServices are the TLS endpoints whose serving certificates are checked on live nodes.
+var certificateExpiryLiveServices = []struct {
+	name string
+	port int
+}{
+	{name: "apid", port: constants.ApidPort},
+	{name: "etcd", port: constants.EtcdClientPort},
+	{name: "kube-apiserver", port: constants.DefaultControlPlanePort},
+}
+
+type talosCertificateExpiryDataSource struct{}
+
+var _ datasource.DataSource = &talosCertificateExpiryDataSource{}
+
+type talosCertificateExpiryDataSourceModelV0 struct { //nolint:govet
+	ID                  types.String           `tfsdk:"id"`
+	MachineSecrets      *machineSecrets        `tfsdk:"machine_secrets"`
+	ClientConfiguration *clientConfiguration   `tfsdk:"client_configuration"`
+	KubeConfigRaw       types.String           `tfsdk:"kubeconfig_raw"`
+	Nodes               []types.String         `tfsdk:"nodes"`
+	SkipUnreachable     types.Bool             `tfsdk:"skip_unreachable"`
+	Certificates        []certificateInfoModel `tfsdk:"certificates"`
+	MinDaysRemaining    types.Int64            `tfsdk:"min_days_remaining"`
+	EarliestNotAfter    types.String           `tfsdk:"earliest_not_after"`
+	Timeouts            timeouts.Value         `tfsdk:"timeouts"`
+}
+
+type certificateInfoModel struct {
+	Source        types.String `tfsdk:"source"`
+	Subject       types.String `tfsdk:"subject"`
+	Issuer        types.String `tfsdk:"issuer"`
+	SerialNumber  types.String `tfsdk:"serial_number"`
+	IsCA          types.Bool   `tfsdk:"is_ca"`
+	NotBefore     types.String `tfsdk:"not_before"`
+	NotAfter      types.String `tfsdk:"not_after"`
+	DaysRemaining types.Int64  `tfsdk:"days_remaining"`
+}
+
+// NewTalosCertificateExpiryDataSource implements the datasource.DataSource interface.
+func NewTalosCertificateExpiryDataSource() datasource.DataSource {
+	return &talosCertificateExpiryDataSource{}
+}
+
+func (d *talosCertificateExpiryDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_certificate_expiry"
+}
+
+func (d *talosCertificateExpiryDataSource) Schema(ctx context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	machineSecretsAttribute := machineSecretsSchemaAttribute()
+	machineSecretsAttribute.Required = false
+	machineSecretsAttribute.Optional = true
+
+	resp.Schema = schema.Schema{
+		Description: "Reports the validity of the certificates in machine secrets, client configurations, kubeconfigs and on live nodes",
+		MarkdownDescription: "Reports the validity of the certificates in machine secrets, client configurations, kubeconfigs and on live nodes. " +
+			"`days_remaining` is computed on every read, so a `check` block on `min_days_remaining` warns on every plan once a certificate gets close to expiry.",
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed: true,
+			},
+			"machine_secrets": machineSecretsAttribute,
+			"client_configuration": schema.SingleNestedAttribute{
+				Attributes: map[string]schema.Attribute{
+					"ca_certificate": schema.StringAttribute{
+						Required:    true,
+						Description: "The client CA certificate",
+					},
+					"client_certificate": schema.StringAttribute{
+						Required:    true,
+						Description: "The client certificate",
+					},
+					"client_key": schema.StringAttribute{
+						Required:    true,
+						Sensitive:   true,
+						Description: "The client key",
+					},
+				},
+				Optional:    true,
+				Description: "The client configuration data",
+			},
+			"kubeconfig_raw": schema.StringAttribute{
+				Optional:    true,
+				Sensitive:   true,
+				Description: "A kubeconfig, e.g. `talos_cluster_kubeconfig.this.kubeconfig_raw`. Embedded CA and client certificates are checked",
+			},
+			"nodes": schema.ListAttribute{
+				ElementType: types.StringType,
+				Optional:    true,
+				Description: "Nodes to fetch the serving certificates of apid, etcd and kube-apiserver from. " +
+					"A service that can't be reached fails the read unless `skip_unreachable` is set, so only list control plane nodes",
+			},
+			"skip_unreachable": schema.BoolAttribute{
+				Optional:    true,
+				Description: "Skip services of `nodes` that can't be reached with a warning instead of failing the read. Default is false",
+			},
+			"certificates": schema.ListNestedAttribute{
+				Computed:    true,
+				Description: "The certificates found",
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"source": schema.StringAttribute{
+							Computed:    true,
+							Description: "Where the certificate was found, e.g. `machine_secrets.certs.os` or `10.5.0.2:apid`",
+						},
+						"subject": schema.StringAttribute{
+							Computed:    true,
+							Description: "The certificate subject",
+						},
+						"issuer": schema.StringAttribute{
+							Computed:    true,
+							Description: "The certificate issuer",
+						},
+						"serial_number": schema.StringAttribute{
+							Computed:    true,
+							Description: "The certificate serial number",
+						},
+						"is_ca": schema.BoolAttribute{
+							Computed:    true,
+							Description: "Whether the certificate is a CA",
+						},
+						"not_before": schema.StringAttribute{
+							Computed:    true,
+							Description: "The start of the validity period in RFC3339 format",
+						},
+						"not_after": schema.StringAttribute{
+							Computed:    true,
+							Description: "The end of the validity period in RFC3339 format",
+						},
+						"days_remaining": schema.Int64Attribute{
+							Computed:    true,
+							Description: "Full days until the certificate expires. Negative once it has expired",
+						},
+					},
+				},
+			},
+			"min_days_remaining": schema.Int64Attribute{
+				Computed:    true,
+				Description: "The lowest `days_remaining` of all certificates. Null if no certificate was found",
+			},
+			"earliest_not_after": schema.StringAttribute{
+				Computed:    true,
+				Description: "The earliest `not_after` of all certificates. Null if no certificate was found",
+			},
+			"timeouts": timeouts.Attributes(ctx, timeouts.Opts{
+				Read: true,
+			}),
+		},
+	}
+}
+
+func (d *talosCertificateExpiryDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) { //nolint:gocyclo,cyclop
+	var obj types.Object
+
+	diags := req.Config.Get(ctx, &obj)
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var state talosCertificateExpiryDataSourceModelV0
+
+	diags = obj.As(ctx, &state, basetypes.ObjectAsOptions{
+		UnhandledNullAsEmpty:    true,
+		UnhandledUnknownAsEmpty: true,
+	})
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	now := time.Now()
+
+	var inventory certificateInventory
+
+	if state.MachineSecrets != nil {
+		certs := state.MachineSecrets.Certs
+
+		for _, pair := range []struct {
+			source string
+			cert   types.String
+		}{
+			{"machine_secrets.certs.etcd", certs.Etcd.Cert},
+			{"machine_secrets.certs.k8s", certs.K8s.Cert},
+			{"machine_secrets.certs.k8s_aggregator", certs.K8sAggregator.Cert},
+			{"machine_secrets.certs.os", certs.OS.Cert},
+		} {
+			if err := inventory.addBase64PEM(pair.source, pair.cert.ValueString()); err != nil {
+				resp.Diagnostics.AddError("failed to parse certificate", err.Error())
+
+				return
+			}
+		}
+	}
+
+	if state.ClientConfiguration != nil {
+		for _, pair := range []struct {
+			source string
+			cert   types.String
+		}{
+			{"client_configuration.ca_certificate", state.ClientConfiguration.CA},
+			{"client_configuration.client_certificate", state.ClientConfiguration.Cert},
+		} {
+			if err := inventory.addBase64PEM(pair.source, pair.cert.ValueString()); err != nil {
+				resp.Diagnostics.AddError("failed to parse certificate", err.Error())
+
+				return
+			}
+		}
+	}
+
+	if raw := state.KubeConfigRaw.ValueString(); raw != "" {
+		if err := inventory.addKubeconfig(raw); err != nil {
+			resp.Diagnostics.AddError("failed to parse kubeconfig", err.Error())
+
+			return
+		}
+	}
+
+	if len(state.Nodes) > 0 {
+		readTimeout, diags := state.Timeouts.Read(ctx, 1*time.Minute)
+		resp.Diagnostics.Append(diags...)
+
+		if resp.Diagnostics.HasError() {
+			return
+		}
+
+		ctxDeadline, cancel := context.WithTimeout(ctx, readTimeout)
+		defer cancel()
+
+		for _, node := range state.Nodes {
+			for _, svc := range certificateExpiryLiveServices {
+				address := net.JoinHostPort(node.ValueString(), strconv.Itoa(svc.port))
+
+				certs, err := fetchServingCertificates(ctxDeadline, address)
+				if err != nil {
+					if !state.SkipUnreachable.ValueBool() {
+						resp.Diagnostics.AddAttributeError(path.Root("nodes"), "failed to fetch serving certificate",
+							fmt.Sprintf("%s on %s: %s. Set skip_unreachable to skip services that can't be reached", svc.name, address, err))
+
+						continue
+					}
+
+					resp.Diagnostics.AddAttributeWarning(path.Root("nodes"), "skipped serving certificate",
+						fmt.Sprintf("%s on %s can't be reached, its certificate is not checked: %s", svc.name, address, err))
+
+					continue
+				}
+
+				inventory.add(node.ValueString()+":"+svc.name, certs...)
+			}
+		}
+	}
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	state.Certificates = make([]certificateInfoModel, 0, len(inventory))
+	state.MinDaysRemaining = types.Int64Null()
+	state.EarliestNotAfter = types.StringNull()
+
+	var earliest time.Time
+
+	for _, entry := range inventory {
+		state.Certificates = append(state.Certificates, certificateToTFTypes(entry.source, entry.cert, now))
+
+		if earliest.IsZero() || entry.cert.NotAfter.Before(earliest) {
+			earliest = entry.cert.NotAfter
+		}
+	}
+
+	if !earliest.IsZero() {
+		state.MinDaysRemaining = types.Int64Value(daysRemaining(earliest, now))
+		state.EarliestNotAfter = types.StringValue(earliest.UTC().Format(time.RFC3339))
+	}
+
+	state.ID = types.StringValue("certificate_expiry")
+
+	diags = resp.State.Set(ctx, &state)
+	resp.Diagnostics.Append(diags...)
+}
+
+type certificateInventoryEntry struct {
+	cert   *x509.Certificate
+	source string
+}
+
+type certificateInventory []certificateInventoryEntry
+
+func (inv *certificateInventory) add(source string, certs ...*x509.Certificate) {
+	for _, cert := range certs {
+		*inv = append(*inv, certificateInventoryEntry{source: source, cert: cert})
+	}
+}
+
+// addBase64PEM adds the certificates of a base64 encoded PEM bundle, the encoding used for certificates in the provider schema.
+func (inv *certificateInventory) addBase64PEM(source, data string) error {
+	if data == "" {
+		return nil
+	}
+
+	decoded, err := base64ToBytes(data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", source, err)
+	}
+
+	certs, err := parsePEMCertificates(decoded)
+	if err != nil {
+		return fmt.Errorf("%s: %w", source, err)
+	}
+
+	inv.add(source, certs...)
+
+	return nil
+}
+
+func (inv *certificateInventory) addKubeconfig(raw string) error {
+	kubeConfig, err := clientcmd.Load([]byte(raw))
+	if err != nil {
+		return err
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(kubeConfig.Clusters)) {
+		certs, err := parsePEMCertificates(kubeConfig.Clusters[name].CertificateAuthorityData)
+		if err != nil {
+			return fmt.Errorf("cluster %q: %w", name, err)
+		}
+
+		inv.add("kubeconfig.clusters."+name, certs...)
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(kubeConfig.AuthInfos)) {
+		certs, err := parsePEMCertificates(kubeConfig.AuthInfos[name].ClientCertificateData)
+		if err != nil {
+			return fmt.Errorf("user %q: %w", name, err)
+		}
+
+		inv.add("kubeconfig.users."+name, certs...)
+	}
+
+	return nil
+}
+
+func parsePEMCertificates(data []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+
+	for {
+		var block *pem.Block
+
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+
+		certs = append(certs, cert)
+	}
+
+	return certs, nil
+}
+
+// fetchServingCertificates returns the certificate chain presented by a TLS server. The chain is captured during the handshake,
+// so servers which require a client certificate (apid, etcd) still reveal theirs even though the handshake itself fails.
+func fetchServingCertificates(ctx context.Context, address string) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+
+	dialer := &tls.Dialer{
+		NetDialer: &net.Dialer{Timeout: 10 * time.Second},
+		Config: &tls.Config{
+			InsecureSkipVerify: true, //nolint:gosec // certificates are only inspected, nothing is sent
+			VerifyPeerCertificate: func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+				for _, raw := range rawCerts {
+					cert, err := x509.ParseCertificate(raw)
+					if err != nil {
+						return err
+					}
+
+					certs = append(certs, cert)
+				}
+
+				return nil
+			},
+		},
+	}
+
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err == nil {
+		conn.Close() //nolint:errcheck
+	}
+
+	if len(certs) > 0 {
+		// only the leaf is of interest, the CAs are covered by machine_secrets
+		return certs[:1], nil
+	}
+
+	if err == nil {
+		err = errors.New("no certificate presented")
+	}
+
+	return nil, err
+}
+
+func certificateToTFTypes(source string, cert *x509.Certificate, now time.Time) certificateInfoModel {
+	return certificateInfoModel{
+		Source:        types.StringValue(source),
+		Subject:       types.StringValue(cert.Subject.String()),
+		Issuer:        types.StringValue(cert.Issuer.String()),
+		SerialNumber:  types.StringValue(cert.SerialNumber.String()),
+		IsCA:          types.BoolValue(cert.IsCA),
+		NotBefore:     types.StringValue(cert.NotBefore.UTC().Format(time.RFC3339)),
+		NotAfter:      types.StringValue(cert.NotAfter.UTC().Format(time.RFC3339)),
+		DaysRemaining: types.Int64Value(daysRemaining(cert.NotAfter, now)),
+	}
+}
+
+// daysRemaining returns the number of full days until notAfter, rounding towards negative infinity
+// so a certificate that expired an hour ago reports -1 rather than 0.
+func daysRemaining(notAfter, now time.Time) int64 {
+	return int64(math.Floor(notAfter.Sub(now).Hours() / 24))
+}
@@ -0,0 +1,121 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package talos //nolint:testpackage // needs access to unexported certificate inventory helpers
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/siderolabs/crypto/x509"
+)
+
+func TestCertificateInventory(t *testing.T) {
+	t.Parallel()
+
+	ca, err := x509.NewSelfSignedCertificateAuthority(x509.Organization("test"), x509.NotAfter(time.Now().Add(48*time.Hour)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var inv certificateInventory
+
+	if err = inv.addBase64PEM("ca", bytesToBase64(ca.CrtPEM)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = inv.addBase64PEM("empty", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = inv.addBase64PEM("invalid", "not base64!"); err == nil {
+		t.Error("expected an error for invalid base64")
+	}
+
+	kubeconfig := fmt.Sprintf(`apiVersion: v1
+kind: Config
+clusters:
+- name: test
+  cluster:
+    server: https://10.5.0.2:6443
+    certificate-authority-data: %s
+users:
+- name: admin@test
+  user:
+    client-certificate-data: %s
+contexts:
+- name: admin@test
+  context:
+    cluster: test
+    user: admin@test
+current-context: admin@test
+`, bytesToBase64(ca.CrtPEM), bytesToBase64(ca.CrtPEM))
+
+	if err = inv.addKubeconfig(kubeconfig); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(inv) != 3 {
+		t.Fatalf("expected 3 certificates, got %d", len(inv))
+	}
+
+	for i, want := range []string{"ca", "kubeconfig.clusters.test", "kubeconfig.users.admin@test"} {
+		if inv[i].source != want {
+			t.Errorf("certificate %d source = %q, want %q", i, inv[i].source, want)
+		}
+	}
+
+	model := certificateToTFTypes(inv[0].source, inv[0].cert, time.Now())
+
+	if !model.IsCA.ValueBool() {
+		t.Error("expected the CA to be reported as CA")
+	}
+
+	if days := model.DaysRemaining.ValueInt64(); days != 1 {
+		t.Errorf("days_remaining = %d, want 1", days)
+	}
+}
+
+func TestDaysRemaining(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for _, tc := range []struct {
+		notAfter time.Time
+		want     int64
+	}{
+		{notAfter: now.Add(365 * 24 * time.Hour), want: 365},
+		{notAfter: now.Add(23 * time.Hour), want: 0},
+		{notAfter: now.Add(-time.Hour), want: -1},
+	} {
+		if got := daysRemaining(tc.notAfter, now); got != tc.want {
+			t.Errorf("daysRemaining(%s) = %d, want %d", tc.notAfter, got, tc.want)
+		}
+	}
+}
+
+func TestFetchServingCertificates(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewUnstartedServer(http.NotFoundHandler())
+	srv.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert}
+	srv.StartTLS()
+
+	defer srv.Close()
+
+	certs, err := fetchServingCertificates(context.Background(), srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(certs) != 1 || !certs[0].Equal(srv.Certificate()) {
+		t.Errorf("expected the server leaf certificate, got %d certificates", len(certs))
+	}
+}
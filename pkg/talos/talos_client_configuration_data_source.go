@@ -7,9 +7,12 @@ package talos
 import (
 	"context"
 
+	"github.com/hashicorp/terraform-plugin-framework-validators/listvalidator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/objectvalidator"
 	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
 	"github.com/hashicorp/terraform-plugin-framework/datasource"
 	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
 	"github.com/hashicorp/terraform-plugin-framework/types"
 	"github.com/hashicorp/terraform-plugin-framework/types/basetypes"
@@ -21,6 +24,10 @@ type talosClientConfigurationDataSourceModelV0 struct {
 	ID                  types.String        `tfsdk:"id"`
 	ClusterName         types.String        `tfsdk:"cluster_name"`
 	ClientConfiguration clientConfiguration `tfsdk:"client_configuration"`
+	MachineSecrets      *machineSecrets     `tfsdk:"machine_secrets"`
+	Roles               []types.String      `tfsdk:"roles"`
+	NotBefore           types.String        `tfsdk:"not_before"`
+	CrtTTL              types.String        `tfsdk:"crt_ttl"`
 	Endpoints           types.List          `tfsdk:"endpoints"`
 	Nodes               types.List          `tfsdk:"nodes"`
 	TalosConfig         types.String        `tfsdk:"talos_config"`
@@ -38,6 +45,12 @@ func (d *talosClientConfigurationDataSource) Metadata(_ context.Context, req dat
 }
 
 func (d *talosClientConfigurationDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	machineSecretsAttribute := machineSecretsSchemaAttribute()
+	machineSecretsAttribute.Required = false
+	machineSecretsAttribute.Optional = true
+	machineSecretsAttribute.Description = "The machine secrets to generate the client certificate from, e.g. `talos_machine_secrets.this.machine_secrets`. " +
+		"Use together with `roles` to generate a least-privilege client configuration"
+
 	resp.Schema = schema.Schema{
 		Description: "Generate client configuration for a Talos cluster",
 		Attributes: map[string]schema.Attribute{
@@ -68,8 +81,38 @@ func (d *talosClientConfigurationDataSource) Schema(_ context.Context, _ datasou
 						Description: "The client key",
 					},
 				},
-				Required:    true,
-				Description: "The client configuration data",
+				Optional:    true,
+				Computed:    true,
+				Description: "The client configuration data. Computed when `machine_secrets` is set",
+				Validators: []validator.Object{
+					objectvalidator.ExactlyOneOf(path.MatchRoot("machine_secrets")),
+				},
+			},
+			"machine_secrets": machineSecretsAttribute,
+			"roles": schema.ListAttribute{
+				ElementType: types.StringType,
+				Optional:    true,
+				Description: "The Talos API roles of the generated client certificate, e.g. `os:reader`, `os:operator` or `os:etcd:backup`. " +
+					"Requires `machine_secrets`. Defaults to `[\"os:admin\"]`",
+				Validators: append(clientRolesValidators(), listvalidator.AlsoRequires(path.MatchRoot("machine_secrets"))),
+			},
+			"not_before": schema.StringAttribute{
+				Optional: true,
+				Description: "RFC3339 timestamp to use as the NotBefore field of the generated client certificate. " +
+					"Requires `machine_secrets`. If not set, the validity of the OS CA certificate is used",
+				Validators: []validator.String{
+					rfc3339Valid(),
+					stringvalidator.AlsoRequires(path.MatchRoot("machine_secrets")),
+				},
+			},
+			"crt_ttl": schema.StringAttribute{
+				Optional: true,
+				Description: "The lifetime of the generated client certificate as a Go duration string (e.g. \"24h\"). " +
+					"Defaults to \"87600h\" (10 years). Only used when `not_before` is set",
+				Validators: []validator.String{
+					goDurationValid(),
+					stringvalidator.AlsoRequires(path.MatchRoot("not_before")),
+				},
 			},
 			"endpoints": schema.ListAttribute{
 				ElementType: types.StringType,
@@ -91,9 +134,21 @@ func (d *talosClientConfigurationDataSource) Schema(_ context.Context, _ datasou
 }
 
 func (d *talosClientConfigurationDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var obj types.Object
+
+	diags := req.Config.Get(ctx, &obj)
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
 	var state talosClientConfigurationDataSourceModelV0
 
-	diags := req.Config.Get(ctx, &state)
+	diags = obj.As(ctx, &state, basetypes.ObjectAsOptions{
+		UnhandledNullAsEmpty:    true,
+		UnhandledUnknownAsEmpty: true,
+	})
 	resp.Diagnostics.Append(diags...)
 
 	if resp.Diagnostics.HasError() {
@@ -116,6 +171,38 @@ func (d *talosClientConfigurationDataSource) Read(ctx context.Context, req datas
 		return
 	}
 
+	if state.MachineSecrets != nil {
+		secretsBundle, err := machineSecretsToSecretsBundle(talosMachineSecretsResourceModelV1{
+			MachineSecrets: *state.MachineSecrets,
+		})
+		if err != nil {
+			resp.Diagnostics.AddError("failed to convert machine secrets to secrets bundle", err.Error())
+
+			return
+		}
+
+		notBefore, notAfter, tsErr := resolveClientConfigTimestamps(state.NotBefore.ValueString(), state.CrtTTL.ValueString(), secretsBundle.Certs.OS.Crt)
+		if tsErr != nil {
+			resp.Diagnostics.AddError(tsErr.summary, tsErr.detail)
+
+			return
+		}
+
+		roles, rolesErr := clientRolesToRoleSet(state.Roles)
+		if rolesErr != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("roles"), "invalid client roles", rolesErr.Error())
+
+			return
+		}
+
+		state.ClientConfiguration, err = generateClientConfiguration(secretsBundle, state.ClusterName.ValueString(), roles, notBefore, notAfter)
+		if err != nil {
+			resp.Diagnostics.AddError("failed to generate client configuration", err.Error())
+
+			return
+		}
+	}
+
 	talosConfig, err := talosClientTFConfigToTalosClientConfig(
 		state.ClusterName.ValueString(),
 		state.ClientConfiguration.CA.ValueString(),
@@ -10,6 +10,7 @@ import (
 	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
 	"github.com/hashicorp/terraform-plugin-framework/ephemeral"
 	"github.com/hashicorp/terraform-plugin-framework/ephemeral/schema"
+	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
 	"github.com/hashicorp/terraform-plugin-framework/types"
 	"github.com/hashicorp/terraform-plugin-framework/types/basetypes"
@@ -24,6 +25,7 @@ type talosClientConfigurationEphemeralResourceModel struct {
 	MachineSecrets      machineSecrets      `tfsdk:"machine_secrets"`
 	NotBefore           types.String        `tfsdk:"not_before"`
 	CrtTTL              types.String        `tfsdk:"crt_ttl"`
+	Roles               []types.String      `tfsdk:"roles"`
 	Endpoints           types.List          `tfsdk:"endpoints"`
 	Nodes               types.List          `tfsdk:"nodes"`
 	TalosConfig         types.String        `tfsdk:"talos_config"`
@@ -43,7 +45,7 @@ func (r *talosClientConfigurationEphemeralResource) Schema(_ context.Context, _
 	resp.Schema = schema.Schema{
 		Description: "Generate client configuration for a Talos cluster from machine secrets. " +
 			"This is an ephemeral resource that does not persist secrets in Terraform state. " +
-			"The admin client certificate is generated with pinned timestamps so talos_config " +
+			"The client certificate is generated with pinned timestamps so talos_config " +
 			"is byte-identical on every open as long as machine_secrets and not_before are unchanged.",
 		Attributes: map[string]schema.Attribute{
 			"cluster_name": schema.StringAttribute{
@@ -56,7 +58,7 @@ func (r *talosClientConfigurationEphemeralResource) Schema(_ context.Context, _
 			"machine_secrets": machineSecretsSchemaAttribute(),
 			"not_before": schema.StringAttribute{
 				Optional: true,
-				Description: "RFC3339 timestamp to use as the NotBefore field of the generated admin client certificate. " +
+				Description: "RFC3339 timestamp to use as the NotBefore field of the generated client certificate. " +
 					"When set, the certificate validity starts at this time and ends at not_before + crt_ttl. " +
 					"Persist this value in a terraform_data resource so it is stable across plans and the " +
 					"generated talos_config is byte-identical on every open. " +
@@ -67,13 +69,19 @@ func (r *talosClientConfigurationEphemeralResource) Schema(_ context.Context, _
 			},
 			"crt_ttl": schema.StringAttribute{
 				Optional: true,
-				Description: "The lifetime of the generated admin client certificate as a Go duration string " +
+				Description: "The lifetime of the generated client certificate as a Go duration string " +
 					"(e.g. \"8760h\" for 1 year, \"87600h\" for 10 years). Defaults to \"87600h\" (10 years). " +
 					"Only used when not_before is set; when not_before is omitted the cert uses the OS CA's NotAfter directly.",
 				Validators: []validator.String{
 					goDurationValid(),
 				},
 			},
+			"roles": schema.ListAttribute{
+				ElementType: types.StringType,
+				Optional:    true,
+				Description: "The Talos API roles of the generated client certificate, e.g. `os:reader`, `os:operator` or `os:etcd:backup`. Defaults to `[\"os:admin\"]`.",
+				Validators:  clientRolesValidators(),
+			},
 			"endpoints": schema.ListAttribute{
 				ElementType: types.StringType,
 				Optional:    true,
@@ -167,7 +175,14 @@ func (r *talosClientConfigurationEphemeralResource) Open(ctx context.Context, re
 		return
 	}
 
-	cc, err := generateClientConfiguration(secretsBundle, config.ClusterName.ValueString(), notBefore, notAfter)
+	roles, err := clientRolesToRoleSet(config.Roles)
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("roles"), "invalid client roles", err.Error())
+
+		return
+	}
+
+	cc, err := generateClientConfiguration(secretsBundle, config.ClusterName.ValueString(), roles, notBefore, notAfter)
 	if err != nil {
 		resp.Diagnostics.AddError("failed to generate client configuration", err.Error())
 
@@ -206,6 +221,7 @@ func (r *talosClientConfigurationEphemeralResource) Open(ctx context.Context, re
 		MachineSecrets:      config.MachineSecrets,
 		NotBefore:           config.NotBefore,
 		CrtTTL:              config.CrtTTL,
+		Roles:               config.Roles,
 		Endpoints:           config.Endpoints,
 		Nodes:               config.Nodes,
 		TalosConfig:         basetypes.NewStringValue(string(talosConfigStringBytes)),
@@ -29,6 +29,8 @@ type talosClusterKubeConfigDataSourceModelV0 struct {
 	Node                          types.String                  `tfsdk:"node"`
 	Endpoint                      types.String                  `tfsdk:"endpoint"`
 	KubeConfigRaw                 types.String                  `tfsdk:"kubeconfig_raw"`
+	ExpiresAt                     types.String                  `tfsdk:"expires_at"`
+	RenewAfter                    types.String                  `tfsdk:"renew_after"`
 	Timeouts                      timeouts.Value                `tfsdk:"timeouts"`
 	Wait                          types.Bool                    `tfsdk:"wait"`
 }
@@ -113,6 +115,14 @@ func (d *talosClusterKubeConfigDataSource) Schema(ctx context.Context, _ datasou
 				Computed:    true,
 				Description: "The kubernetes client configuration",
 			},
+			"expires_at": schema.StringAttribute{
+				Computed:    true,
+				Description: "The time the kubernetes client certificate expires at in RFC3339 format",
+			},
+			"renew_after": schema.StringAttribute{
+				Computed:    true,
+				Description: "The time in RFC3339 format after which the kubernetes client certificate should be renewed, 720h before `expires_at`",
+			},
 			"timeouts": timeouts.Attributes(ctx, timeouts.Opts{
 				Read: true,
 			}),
@@ -213,6 +223,15 @@ func (d *talosClusterKubeConfigDataSource) Read(ctx context.Context, req datasou
 
 	state.ID = basetypes.NewStringValue(clusterName)
 
+	x509Cert, err := parseKubernetesClientCertificate(kubeConfig.AuthInfos[authName].ClientCertificateData)
+	if err != nil {
+		resp.Diagnostics.AddError("failed to parse kubernetes client certificate", err.Error())
+
+		return
+	}
+
+	state.ExpiresAt, state.RenewAfter = kubernetesClientCertificateExpiry(x509Cert, kubeconfigDefaultRenewalDuration)
+
 	diags = resp.State.Set(ctx, &state)
 	resp.Diagnostics.Append(diags...)
 
@@ -11,14 +11,18 @@ import (
 	"crypto/sha256"
 	stdlibx509 "crypto/x509"
 	"crypto/x509/pkix"
+	"encoding/json"
 	"encoding/pem"
 	"fmt"
 	"math/big"
+	"slices"
 	"time"
 
+	"github.com/hashicorp/terraform-plugin-framework-validators/listvalidator"
 	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
 	"github.com/hashicorp/terraform-plugin-framework/ephemeral"
 	"github.com/hashicorp/terraform-plugin-framework/ephemeral/schema"
+	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
 	"github.com/hashicorp/terraform-plugin-framework/types"
 	"github.com/hashicorp/terraform-plugin-framework/types/basetypes"
@@ -38,8 +42,13 @@ type talosClusterKubeConfigEphemeralResourceModel struct {
 	Endpoint                      types.String                  `tfsdk:"endpoint"`
 	NotBefore                     types.String                  `tfsdk:"not_before"`
 	CrtTTL                        types.String                  `tfsdk:"crt_ttl"`
+	Username                      types.String                  `tfsdk:"username"`
+	Groups                        []types.String                `tfsdk:"groups"`
+	CertificateRenewalDuration    types.String                  `tfsdk:"certificate_renewal_duration"`
 	KubeConfigRaw                 types.String                  `tfsdk:"kubeconfig_raw"`
 	KubernetesClientConfiguration kubernetesClientConfiguration `tfsdk:"kubernetes_client_configuration"`
+	ExpiresAt                     types.String                  `tfsdk:"expires_at"`
+	RenewAfter                    types.String                  `tfsdk:"renew_after"`
 }
 
 // NewTalosClusterKubeConfigEphemeralResource implements the ephemeral.EphemeralResource interface.
@@ -55,8 +64,10 @@ func (r *talosClusterKubeConfigEphemeralResource) Schema(_ context.Context, _ ep
 	resp.Schema = schema.Schema{
 		Description: "Generate a kubeconfig for a Talos cluster from machine secrets. " +
 			"This is an ephemeral resource that does not persist secrets in Terraform state. " +
-			"The admin client certificate is generated with pinned timestamps so kubeconfig_raw " +
-			"is byte-identical on every open as long as machine_secrets and not_before are unchanged.",
+			"The client certificate is generated with pinned timestamps so kubeconfig_raw " +
+			"is byte-identical on every open as long as machine_secrets and not_before are unchanged. " +
+			"By default the kubeconfig authenticates as the cluster admin; set username and groups " +
+			"to generate a kubeconfig for a user bound to more limited RBAC roles.",
 		Attributes: map[string]schema.Attribute{
 			"machine_secrets": machineSecretsSchemaAttribute(),
 			"cluster_name": schema.StringAttribute{
@@ -72,7 +83,7 @@ func (r *talosClusterKubeConfigEphemeralResource) Schema(_ context.Context, _ ep
 			},
 			"not_before": schema.StringAttribute{
 				Optional: true,
-				Description: "RFC3339 timestamp to use as the NotBefore field of the generated admin client certificate. " +
+				Description: "RFC3339 timestamp to use as the NotBefore field of the generated client certificate. " +
 					"When set, the certificate validity starts at this time and ends at not_before + crt_ttl. " +
 					"Persist this value in a terraform_data resource so it is stable across plans and the " +
 					"generated kubeconfig_raw is byte-identical on every open. " +
@@ -83,13 +94,38 @@ func (r *talosClusterKubeConfigEphemeralResource) Schema(_ context.Context, _ ep
 			},
 			"crt_ttl": schema.StringAttribute{
 				Optional: true,
-				Description: "The lifetime of the generated admin client certificate as a Go duration string " +
+				Description: "The lifetime of the generated client certificate as a Go duration string " +
 					"(e.g. \"8760h\" for 1 year, \"87600h\" for 10 years). Defaults to \"87600h\" (10 years). " +
 					"Only used when not_before is set; when not_before is omitted the cert uses the K8s CA's NotAfter directly.",
 				Validators: []validator.String{
 					goDurationValid(),
 				},
 			},
+			"username": schema.StringAttribute{
+				Optional: true,
+				Description: "The Kubernetes user name, used as the Common Name of the generated client certificate. " +
+					"Defaults to \"admin\"",
+				Validators: []validator.String{
+					stringvalidator.LengthAtLeast(1),
+				},
+			},
+			"groups": schema.ListAttribute{
+				ElementType: types.StringType,
+				Optional:    true,
+				Description: "The Kubernetes groups of the user, used as the Organization of the generated client certificate. " +
+					"Defaults to [\"system:masters\"] when username is not set, and to no groups otherwise",
+				Validators: []validator.List{
+					listvalidator.ValueStringsAre(stringvalidator.LengthAtLeast(1)),
+					listvalidator.AlsoRequires(path.MatchRoot("username")),
+				},
+			},
+			"certificate_renewal_duration": schema.StringAttribute{
+				Optional:    true,
+				Description: "The duration before the client certificate expires from which `renew_after` is computed, defaults to 720h. Must be a valid duration string",
+				Validators: []validator.String{
+					goDurationValid(),
+				},
+			},
 			"kubeconfig_raw": schema.StringAttribute{
 				Computed:    true,
 				Description: "The raw kubeconfig",
@@ -118,6 +154,15 @@ func (r *talosClusterKubeConfigEphemeralResource) Schema(_ context.Context, _ ep
 				Computed:    true,
 				Description: "The kubernetes client configuration",
 			},
+			"expires_at": schema.StringAttribute{
+				Computed:    true,
+				Description: "The time the client certificate expires at in RFC3339 format",
+			},
+			"renew_after": schema.StringAttribute{
+				Computed: true,
+				Description: "The time in RFC3339 format after which the client certificate should be renewed, `certificate_renewal_duration` before `expires_at`. " +
+					"With `not_before` persisted in a `terraform_data` resource, replace it once this time has passed to issue a new certificate",
+			},
 		},
 	}
 }
@@ -160,45 +205,92 @@ func (r *talosClusterKubeConfigEphemeralResource) Open(ctx context.Context, req
 		return
 	}
 
-	kc, err := GenerateKubeconfig(secretsBundle, config.ClusterName.ValueString(), config.Endpoint.ValueString(), notBefore, notAfter)
+	username, groups := kubeconfigAdminUser, []string{kubeconfigAdminGroup}
+
+	if !config.Username.IsNull() {
+		username, groups = config.Username.ValueString(), make([]string, 0, len(config.Groups))
+
+		for _, group := range config.Groups {
+			groups = append(groups, group.ValueString())
+		}
+	}
+
+	kc, err := GenerateKubeconfigForUser(secretsBundle, config.ClusterName.ValueString(), config.Endpoint.ValueString(), username, groups, notBefore, notAfter)
 	if err != nil {
 		resp.Diagnostics.AddError("failed to generate kubeconfig", err.Error())
 
 		return
 	}
 
+	renewalDuration := kubeconfigDefaultRenewalDuration
+
+	if config.CertificateRenewalDuration.ValueString() != "" {
+		if renewalDuration, err = time.ParseDuration(config.CertificateRenewalDuration.ValueString()); err != nil {
+			resp.Diagnostics.AddError("failed to parse certificate renewal duration", err.Error())
+
+			return
+		}
+	}
+
+	x509Cert, err := parseKubernetesClientCertificate(kc.ClientCertPEM)
+	if err != nil {
+		resp.Diagnostics.AddError("failed to parse kubernetes client certificate", err.Error())
+
+		return
+	}
+
+	expiresAt, renewAfter := kubernetesClientCertificateExpiry(x509Cert, renewalDuration)
+
 	result := talosClusterKubeConfigEphemeralResourceModel{
-		MachineSecrets: config.MachineSecrets,
-		ClusterName:    config.ClusterName,
-		Endpoint:       config.Endpoint,
-		NotBefore:      config.NotBefore,
-		CrtTTL:         config.CrtTTL,
-		KubeConfigRaw:  basetypes.NewStringValue(kc.Raw),
+		MachineSecrets:             config.MachineSecrets,
+		ClusterName:                config.ClusterName,
+		Endpoint:                   config.Endpoint,
+		NotBefore:                  config.NotBefore,
+		CrtTTL:                     config.CrtTTL,
+		Username:                   config.Username,
+		Groups:                     config.Groups,
+		CertificateRenewalDuration: config.CertificateRenewalDuration,
+		KubeConfigRaw:              basetypes.NewStringValue(kc.Raw),
 		KubernetesClientConfiguration: kubernetesClientConfiguration{
 			Host:              basetypes.NewStringValue(config.Endpoint.ValueString()),
 			CACertificate:     basetypes.NewStringValue(bytesToBase64(secretsBundle.Certs.K8s.Crt)),
 			ClientCertificate: basetypes.NewStringValue(bytesToBase64(kc.ClientCertPEM)),
 			ClientKey:         basetypes.NewStringValue(bytesToBase64(kc.ClientKeyPEM)),
 		},
+		ExpiresAt:  expiresAt,
+		RenewAfter: renewAfter,
 	}
 
 	diags = resp.Result.Set(ctx, &result)
 	resp.Diagnostics.Append(diags...)
 }
 
+const (
+	kubeconfigAdminUser  = "admin"
+	kubeconfigAdminGroup = "system:masters"
+)
+
 // KubeconfigResult holds the generated kubeconfig and its components.
 type KubeconfigResult struct {
 	Raw           string // Full kubeconfig YAML
-	ClientCertPEM []byte // PEM-encoded admin client certificate
-	ClientKeyPEM  []byte // PEM-encoded admin client private key
+	ClientCertPEM []byte // PEM-encoded client certificate
+	ClientKeyPEM  []byte // PEM-encoded client private key
 }
 
-// GenerateKubeconfig generates a kubeconfig from the provided secrets bundle.
+// GenerateKubeconfig generates a cluster admin kubeconfig from the provided secrets bundle.
 //
 // The admin client certificate and key are derived deterministically using HKDF
 // (RFC 5869) seeded from the K8s CA private key and all cert-relevant inputs.
 // Same inputs always produce byte-identical output — no crypto/rand is used.
 func GenerateKubeconfig(bundle *secrets.Bundle, clusterName, endpoint string, notBefore, notAfter time.Time) (*KubeconfigResult, error) {
+	return GenerateKubeconfigForUser(bundle, clusterName, endpoint, kubeconfigAdminUser, []string{kubeconfigAdminGroup}, notBefore, notAfter)
+}
+
+// GenerateKubeconfigForUser generates a kubeconfig for the given Kubernetes user and groups from the provided secrets bundle.
+//
+// The certificate is derived the same way as in GenerateKubeconfig, with the user and groups
+// bound into the HKDF info so that every user gets its own key.
+func GenerateKubeconfigForUser(bundle *secrets.Bundle, clusterName, endpoint, username string, groups []string, notBefore, notAfter time.Time) (*KubeconfigResult, error) {
 	// Parse CA certificate and private key from PEM.
 	caCertBlock, _ := pem.Decode(bundle.Certs.K8s.Crt)
 	if caCertBlock == nil {
@@ -224,21 +316,39 @@ func GenerateKubeconfig(bundle *secrets.Bundle, clusterName, endpoint string, no
 	// CA private key (stable in machine_secrets); the info string binds all
 	// inputs that affect the cert so different parameters produce different keys.
 	info := fmt.Sprintf("talos-kubeconfig:v1:%s:%s:%d:%d", clusterName, endpoint, notBefore.Unix(), notAfter.Unix())
+
+	// the admin kubeconfig keeps the original derivation, so kubeconfigs generated before users were supported stay byte-identical
+	// the user is JSON encoded, so that separators in the username or groups can't make two users derive the same key
+	if username != kubeconfigAdminUser || !slices.Equal(groups, []string{kubeconfigAdminGroup}) {
+		user, err := json.Marshal(struct {
+			Username string   `json:"username"`
+			Groups   []string `json:"groups"`
+		}{
+			Username: username,
+			Groups:   groups,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error encoding kubeconfig user: %w", err)
+		}
+
+		info += ":" + string(user)
+	}
+
 	deterministicReader := hkdf.New(sha256.New, bundle.Certs.K8s.Key, []byte("talos-kubeconfig-v1"), []byte(info))
 
-	// Derive admin client ECDSA private key deterministically from HKDF output.
+	// Derive client ECDSA private key deterministically from HKDF output.
 	// ecdsa.ParseRawPrivateKey constructs a key from raw bytes without using
 	// crypto/rand (unlike ecdsa.GenerateKey which ignores the reader in Go 1.26+).
-	adminKeyBytes := make([]byte, 32) // P-256 key size
-	if _, err = deterministicReader.Read(adminKeyBytes); err != nil {
-		return nil, fmt.Errorf("error deriving admin key bytes: %w", err)
+	clientKeyBytes := make([]byte, 32) // P-256 key size
+	if _, err = deterministicReader.Read(clientKeyBytes); err != nil {
+		return nil, fmt.Errorf("error deriving client key bytes: %w", err)
 	}
 
-	adminKey, err := ecdsa.ParseRawPrivateKey(elliptic.P256(), adminKeyBytes)
+	clientKey, err := ecdsa.ParseRawPrivateKey(elliptic.P256(), clientKeyBytes)
 	if err != nil {
 		// The HKDF output might produce an invalid scalar (0 or >= n).
 		// In practice this is astronomically unlikely for P-256 but handle it.
-		return nil, fmt.Errorf("error parsing derived admin key: %w", err)
+		return nil, fmt.Errorf("error parsing derived client key: %w", err)
 	}
 
 	// Deterministic serial number from the same HKDF stream.
@@ -249,12 +359,12 @@ func GenerateKubeconfig(bundle *secrets.Bundle, clusterName, endpoint string, no
 
 	serialNumber := new(big.Int).SetBytes(serialBytes)
 
-	// Build and sign the admin client certificate.
+	// Build and sign the client certificate.
 	template := &stdlibx509.Certificate{
 		SerialNumber: serialNumber,
 		Subject: pkix.Name{
-			CommonName:   "admin",
-			Organization: []string{"system:masters"},
+			CommonName:   username,
+			Organization: groups,
 		},
 		NotBefore: notBefore,
 		NotAfter:  notAfter,
@@ -271,20 +381,22 @@ func GenerateKubeconfig(bundle *secrets.Bundle, clusterName, endpoint string, no
 
 	// The rand reader is unused: serial number is set explicitly and signing
 	// goes through our deterministic crypto.Signer.
-	certDER, err := stdlibx509.CreateCertificate(nil, template, caCert, &adminKey.PublicKey, caSigner)
+	certDER, err := stdlibx509.CreateCertificate(nil, template, caCert, &clientKey.PublicKey, caSigner)
 	if err != nil {
-		return nil, fmt.Errorf("error signing admin certificate: %w", err)
+		return nil, fmt.Errorf("error signing client certificate: %w", err)
 	}
 
 	// PEM-encode the client cert and key.
 	clientCertPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER})
 
-	adminKeyDER, err := stdlibx509.MarshalECPrivateKey(adminKey)
+	clientKeyDER, err := stdlibx509.MarshalECPrivateKey(clientKey)
 	if err != nil {
-		return nil, fmt.Errorf("error marshaling admin private key: %w", err)
+		return nil, fmt.Errorf("error marshaling client private key: %w", err)
 	}
 
-	clientKeyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: adminKeyDER})
+	clientKeyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: clientKeyDER})
+
+	contextName := username + "@" + clusterName
 
 	// Assemble kubeconfig.
 	cfg := clientcmdapi.Config{
@@ -297,19 +409,19 @@ func GenerateKubeconfig(bundle *secrets.Bundle, clusterName, endpoint string, no
 			},
 		},
 		AuthInfos: map[string]*clientcmdapi.AuthInfo{
-			"admin@" + clusterName: {
+			contextName: {
 				ClientCertificateData: clientCertPEM,
 				ClientKeyData:         clientKeyPEM,
 			},
 		},
 		Contexts: map[string]*clientcmdapi.Context{
-			"admin@" + clusterName: {
+			contextName: {
 				Cluster:   clusterName,
 				Namespace: "default",
-				AuthInfo:  "admin@" + clusterName,
+				AuthInfo:  contextName,
 			},
 		},
-		CurrentContext: "admin@" + clusterName,
+		CurrentContext: contextName,
 	}
 
 	marshaled, err := clientcmd.Write(cfg)
@@ -219,11 +219,15 @@ ephemeral "talos_cluster_kubeconfig" "this" {
 	endpoint        = "https://10.0.0.1:6443"
 	not_before      = "2024-01-01T00:00:00Z"
 	crt_ttl         = "8760h"
+
+	certificate_renewal_duration = "240h"
 }
 
 provider "echo" {
 	data = {
 		kubeconfig_raw = ephemeral.talos_cluster_kubeconfig.this.kubeconfig_raw
+		expires_at     = ephemeral.talos_cluster_kubeconfig.this.expires_at
+		renew_after    = ephemeral.talos_cluster_kubeconfig.this.renew_after
 	}
 }
 
@@ -231,6 +235,8 @@ resource "echo" "test" {}
 `,
 				ConfigStateChecks: []statecheck.StateCheck{
 					statecheck.ExpectKnownValue("echo.test", tfjsonpath.New("data").AtMapKey("kubeconfig_raw"), knownvalue.NotNull()),
+					statecheck.ExpectKnownValue("echo.test", tfjsonpath.New("data").AtMapKey("expires_at"), knownvalue.StringExact("2024-12-31T00:00:00Z")),
+					statecheck.ExpectKnownValue("echo.test", tfjsonpath.New("data").AtMapKey("renew_after"), knownvalue.StringExact("2024-12-21T00:00:00Z")),
 				},
 			},
 		},
@@ -8,10 +8,16 @@ import (
 	"context"
 	"crypto/x509"
 	"encoding/pem"
+	"errors"
 	"fmt"
+	"maps"
+	"slices"
 	"time"
 
 	"github.com/hashicorp/terraform-plugin-framework-timeouts/resource/timeouts"
+	"github.com/hashicorp/terraform-plugin-framework-validators/listvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
 	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
@@ -19,6 +25,7 @@ import (
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringdefault"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
 	"github.com/hashicorp/terraform-plugin-framework/types"
 	"github.com/hashicorp/terraform-plugin-framework/types/basetypes"
 	"github.com/hashicorp/terraform-plugin-log/tflog"
@@ -27,6 +34,7 @@ import (
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 )
 
 type talosClusterKubeConfigResource struct{}
@@ -55,9 +63,29 @@ type talosClusterKubeConfigResourceModelV1 struct {
 	KubeConfigRaw                 types.String                  `tfsdk:"kubeconfig_raw"`
 	KubernetesClientConfiguration kubernetesClientConfiguration `tfsdk:"kubernetes_client_configuration"`
 	CertificateRenewalDuration    types.String                  `tfsdk:"certificate_renewal_duration"`
+	ExpiresAt                     types.String                  `tfsdk:"expires_at"`
+	RenewAfter                    types.String                  `tfsdk:"renew_after"`
+	Exec                          types.Object                  `tfsdk:"exec"`
+	Contexts                      types.List                    `tfsdk:"contexts"`
+	RenderedKubeConfigRaw         types.String                  `tfsdk:"rendered_kubeconfig_raw"`
 	Timeouts                      timeouts.Value                `tfsdk:"timeouts"`
 }
 
+type kubeconfigExec struct {
+	Command            types.String            `tfsdk:"command"`
+	Args               []types.String          `tfsdk:"args"`
+	Env                map[string]types.String `tfsdk:"env"`
+	APIVersion         types.String            `tfsdk:"api_version"`
+	ProvideClusterInfo types.Bool              `tfsdk:"provide_cluster_info"`
+}
+
+type kubeconfigContext struct {
+	Name          types.String `tfsdk:"name"`
+	KubeConfigRaw types.String `tfsdk:"kubeconfig_raw"`
+	Namespace     types.String `tfsdk:"namespace"`
+	Server        types.String `tfsdk:"server"`
+}
+
 type kubernetesClientConfiguration struct {
 	Host              types.String `tfsdk:"host"`
 	CACertificate     types.String `tfsdk:"ca_certificate"`
@@ -153,6 +181,77 @@ func (r *talosClusterKubeConfigResource) Schema(ctx context.Context, _ resource.
 				Description: "The duration in hours before the certificate is renewed, defaults to 720h. Must be a valid duration string",
 				Default:     stringdefault.StaticString("720h"),
 			},
+			"expires_at": schema.StringAttribute{
+				Computed:    true,
+				Description: "The time the kubernetes client certificate expires at in RFC3339 format",
+			},
+			"renew_after": schema.StringAttribute{
+				Computed:    true,
+				Description: "The time in RFC3339 format after which the kubernetes client certificate is renewed by the next apply, `certificate_renewal_duration` before `expires_at`",
+			},
+			"exec": schema.SingleNestedAttribute{
+				Attributes: map[string]schema.Attribute{
+					"command": schema.StringAttribute{
+						Required:    true,
+						Description: "The command executed by kubectl to obtain credentials",
+					},
+					"args": schema.ListAttribute{
+						ElementType: types.StringType,
+						Optional:    true,
+						Description: "The arguments passed to the command",
+					},
+					"env": schema.MapAttribute{
+						ElementType: types.StringType,
+						Optional:    true,
+						Description: "The environment variables set for the command",
+					},
+					"api_version": schema.StringAttribute{
+						Optional:    true,
+						Description: "The ExecCredential API version requested from the command, defaults to `" + kubeconfigExecDefaultAPIVersion + "`",
+					},
+					"provide_cluster_info": schema.BoolAttribute{
+						Optional:    true,
+						Description: "Pass the cluster information to the command in the `KUBERNETES_EXEC_INFO` environment variable",
+					},
+				},
+				Optional: true,
+				Description: "Replaces the client certificate of the users in `rendered_kubeconfig_raw` with an exec credential plugin, " +
+					"so that kubectl obtains short-lived credentials from the given command",
+			},
+			"contexts": schema.ListNestedAttribute{
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"name": schema.StringAttribute{
+							Required:    true,
+							Description: "The name of the context, also used for its cluster and user",
+						},
+						"kubeconfig_raw": schema.StringAttribute{
+							Optional:    true,
+							Sensitive:   true,
+							Description: "The kubeconfig of the cluster, e.g. the `kubeconfig_raw` of another `talos_cluster_kubeconfig`. Defaults to the kubeconfig of this resource",
+						},
+						"namespace": schema.StringAttribute{
+							Optional:    true,
+							Description: "The default namespace of the context",
+						},
+						"server": schema.StringAttribute{
+							Optional:    true,
+							Description: "Overrides the kubernetes API server URL of the cluster, e.g. to use a single node instead of the VIP",
+						},
+					},
+				},
+				Optional: true,
+				Description: "Merges the given kubeconfigs into `rendered_kubeconfig_raw` with one context each, the first one being the current context. " +
+					"If not set, `rendered_kubeconfig_raw` only contains the kubeconfig of this resource",
+				Validators: []validator.List{
+					listvalidator.SizeAtLeast(1),
+				},
+			},
+			"rendered_kubeconfig_raw": schema.StringAttribute{
+				Computed:    true,
+				Description: "The kubeconfig rendered with `exec` and `contexts`, null if neither is set",
+				Sensitive:   true,
+			},
 			"timeouts": timeouts.Attributes(ctx, timeouts.Opts{
 				Create: true,
 				Update: true,
@@ -279,6 +378,29 @@ func (r *talosClusterKubeConfigResource) Create(ctx context.Context, req resourc
 		state.CertificateRenewalDuration = planState.CertificateRenewalDuration
 	}
 
+	renewalDuration, err := time.ParseDuration(state.CertificateRenewalDuration.ValueString())
+	if err != nil {
+		resp.Diagnostics.AddError("failed to parse certificate renewal duration", err.Error())
+
+		return
+	}
+
+	x509Cert, err := parseKubernetesClientCertificate(kubeConfig.AuthInfos[authName].ClientCertificateData)
+	if err != nil {
+		resp.Diagnostics.AddError("failed to parse kubernetes client certificate", err.Error())
+
+		return
+	}
+
+	state.ExpiresAt, state.RenewAfter = kubernetesClientCertificateExpiry(x509Cert, renewalDuration)
+
+	state.RenderedKubeConfigRaw, diags = renderedKubeconfig(ctx, state.KubeConfigRaw, state.Exec, state.Contexts)
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
 	diags = resp.State.Set(ctx, &state)
 	resp.Diagnostics.Append(diags...)
 
@@ -438,10 +560,21 @@ func (r *talosClusterKubeConfigResource) ModifyPlan(ctx context.Context, req res
 		return
 	}
 
+	expiresAt, renewAfter := kubernetesClientCertificateExpiry(x509Cert, renewalDuration)
+	kubeConfigRaw := existingState.KubeConfigRaw
+
 	// check if NotAfter expires in the given duration
-	if x509Cert.NotAfter.Before(OverridableTimeFunc().Add(renewalDuration)) {
+	if now := OverridableTimeFunc(); x509Cert.NotAfter.Before(now.Add(renewalDuration)) {
 		tflog.Info(ctx, fmt.Sprintf("kubernetes client certificate expires in %s, needs regeneration", existingState.CertificateRenewalDuration.ValueString()))
 
+		resp.Diagnostics.AddWarning(
+			"kubernetes client certificate renewal",
+			kubernetesClientCertificateRenewalMessage(x509Cert.NotAfter, now)+", it is renewed by this apply",
+		)
+
+		expiresAt, renewAfter = types.StringUnknown(), types.StringUnknown()
+		kubeConfigRaw = types.StringUnknown()
+
 		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("kubernetes_client_configuration").AtName("host"), types.StringUnknown())...)
 		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("kubernetes_client_configuration").AtName("client_certificate"), types.StringUnknown())...)
 		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("kubernetes_client_configuration").AtName("client_key"), types.StringUnknown())...)
@@ -452,6 +585,18 @@ func (r *talosClusterKubeConfigResource) ModifyPlan(ctx context.Context, req res
 			return
 		}
 	}
+
+	resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("expires_at"), expiresAt)...)
+	resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("renew_after"), renewAfter)...)
+
+	renderedKubeConfigRaw, diags := renderedKubeconfig(ctx, kubeConfigRaw, config.Exec, config.Contexts)
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("rendered_kubeconfig_raw"), renderedKubeConfigRaw)...)
 }
 
 func (r *talosClusterKubeConfigResource) UpgradeState(ctx context.Context) map[int64]resource.StateUpgrader {
@@ -729,5 +874,221 @@ func (r *talosClusterKubeConfigResource) Update(ctx context.Context, req resourc
 		if resp.Diagnostics.HasError() {
 			return
 		}
+
+		x509Cert, err = parseKubernetesClientCertificate(kubeConfig.AuthInfos[authName].ClientCertificateData)
+		if err != nil {
+			resp.Diagnostics.AddError("failed to parse kubernetes client certificate", err.Error())
+
+			return
+		}
+	}
+
+	state.ExpiresAt, state.RenewAfter = kubernetesClientCertificateExpiry(x509Cert, renewalDuration)
+
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("expires_at"), state.ExpiresAt)...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("renew_after"), state.RenewAfter)...)
+
+	renderedKubeConfigRaw, diags := renderedKubeconfig(ctx, state.KubeConfigRaw, state.Exec, state.Contexts)
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("rendered_kubeconfig_raw"), renderedKubeConfigRaw)...)
+}
+
+// kubeconfigDefaultRenewalDuration is the default certificate_renewal_duration,
+// also used for the renew_after of the kubeconfig variants which don't renew the certificate themselves.
+const kubeconfigDefaultRenewalDuration = 720 * time.Hour
+
+// parseKubernetesClientCertificate parses the PEM encoded client certificate of a kubeconfig.
+func parseKubernetesClientCertificate(certPEM []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(certPEM)
+	if block == nil {
+		return nil, errors.New("failed to decode PEM block")
 	}
+
+	return x509.ParseCertificate(block.Bytes)
+}
+
+// kubernetesClientCertificateExpiry returns the expires_at and renew_after values of a kubernetes client certificate,
+// renew_after being renewalDuration before the certificate expires.
+func kubernetesClientCertificateExpiry(cert *x509.Certificate, renewalDuration time.Duration) (expiresAt, renewAfter types.String) {
+	return types.StringValue(cert.NotAfter.UTC().Format(time.RFC3339)),
+		types.StringValue(cert.NotAfter.Add(-renewalDuration).UTC().Format(time.RFC3339))
+}
+
+// kubernetesClientCertificateRenewalMessage tells how many days are left until a kubernetes client certificate expires.
+func kubernetesClientCertificateRenewalMessage(notAfter, now time.Time) string {
+	days := daysRemaining(notAfter, now)
+	if days < 0 {
+		return fmt.Sprintf("The kubernetes client certificate expired %d day(s) ago, on %s", -days, notAfter.UTC().Format(time.RFC3339))
+	}
+
+	return fmt.Sprintf("The kubernetes client certificate expires in %d day(s), on %s", days, notAfter.UTC().Format(time.RFC3339))
+}
+
+// kubeconfigExecDefaultAPIVersion is the default ExecCredential API version of the exec credential plugin.
+const kubeconfigExecDefaultAPIVersion = "client.authentication.k8s.io/v1"
+
+// renderedKubeconfig returns the rendered_kubeconfig_raw value, unknown until the kubeconfig and the exec and contexts attributes are known.
+func renderedKubeconfig(ctx context.Context, kubeconfigRaw types.String, execObj types.Object, contextsList types.List) (types.String, diag.Diagnostics) {
+	var diags diag.Diagnostics
+
+	if execObj.IsNull() && contextsList.IsNull() {
+		return types.StringNull(), diags
+	}
+
+	for _, value := range []attr.Value{kubeconfigRaw, execObj, contextsList} {
+		tfValue, err := value.ToTerraformValue(ctx)
+		if err != nil {
+			diags.AddError("failed to render kubeconfig", err.Error())
+
+			return types.StringUnknown(), diags
+		}
+
+		if !tfValue.IsFullyKnown() {
+			return types.StringUnknown(), diags
+		}
+	}
+
+	var exec *kubeconfigExec
+
+	if !execObj.IsNull() {
+		exec = &kubeconfigExec{}
+
+		diags.Append(execObj.As(ctx, exec, basetypes.ObjectAsOptions{})...)
+	}
+
+	var contexts []kubeconfigContext
+
+	if !contextsList.IsNull() {
+		diags.Append(contextsList.ElementsAs(ctx, &contexts, false)...)
+	}
+
+	if diags.HasError() {
+		return types.StringUnknown(), diags
+	}
+
+	rendered, err := renderKubeconfig(kubeconfigRaw.ValueString(), exec, contexts)
+	if err != nil {
+		diags.AddError("failed to render kubeconfig", err.Error())
+
+		return types.StringUnknown(), diags
+	}
+
+	return types.StringValue(rendered), diags
+}
+
+// renderKubeconfig merges the current context of each kubeconfig into a single kubeconfig, replacing the users with the exec credential plugin if set.
+// Without contexts, the current context of kubeconfigRaw is kept as is.
+func renderKubeconfig(kubeconfigRaw string, exec *kubeconfigExec, contexts []kubeconfigContext) (string, error) {
+	if len(contexts) == 0 {
+		contexts = []kubeconfigContext{{}}
+	}
+
+	rendered := clientcmdapi.NewConfig()
+
+	for _, entry := range contexts {
+		source := kubeconfigRaw
+		if !entry.KubeConfigRaw.IsNull() {
+			source = entry.KubeConfigRaw.ValueString()
+		}
+
+		sourceConfig, err := clientcmd.Load([]byte(source))
+		if err != nil {
+			return "", fmt.Errorf("failed to parse kubeconfig of context %q: %w", entry.Name.ValueString(), err)
+		}
+
+		currentContext, ok := sourceConfig.Contexts[sourceConfig.CurrentContext]
+		if !ok {
+			return "", fmt.Errorf("kubeconfig of context %q has no current context", entry.Name.ValueString())
+		}
+
+		cluster, ok := sourceConfig.Clusters[currentContext.Cluster]
+		if !ok {
+			return "", fmt.Errorf("kubeconfig of context %q has no cluster %q", entry.Name.ValueString(), currentContext.Cluster)
+		}
+
+		authInfo, ok := sourceConfig.AuthInfos[currentContext.AuthInfo]
+		if !ok {
+			return "", fmt.Errorf("kubeconfig of context %q has no user %q", entry.Name.ValueString(), currentContext.AuthInfo)
+		}
+
+		contextName, clusterName, authName := sourceConfig.CurrentContext, currentContext.Cluster, currentContext.AuthInfo
+		if !entry.Name.IsNull() {
+			contextName, clusterName, authName = entry.Name.ValueString(), entry.Name.ValueString(), entry.Name.ValueString()
+		}
+
+		if _, exists := rendered.Contexts[contextName]; exists {
+			return "", fmt.Errorf("duplicate context %q", contextName)
+		}
+
+		cluster = cluster.DeepCopy()
+		cluster.LocationOfOrigin = ""
+
+		if !entry.Server.IsNull() {
+			cluster.Server = entry.Server.ValueString()
+		}
+
+		if exec != nil {
+			authInfo = &clientcmdapi.AuthInfo{
+				Exec: exec.execConfig(),
+			}
+		} else {
+			authInfo = authInfo.DeepCopy()
+			authInfo.LocationOfOrigin = ""
+		}
+
+		namespace := currentContext.Namespace
+		if !entry.Namespace.IsNull() {
+			namespace = entry.Namespace.ValueString()
+		}
+
+		rendered.Clusters[clusterName] = cluster
+		rendered.AuthInfos[authName] = authInfo
+		rendered.Contexts[contextName] = &clientcmdapi.Context{
+			Cluster:   clusterName,
+			AuthInfo:  authName,
+			Namespace: namespace,
+		}
+
+		if rendered.CurrentContext == "" {
+			rendered.CurrentContext = contextName
+		}
+	}
+
+	out, err := clientcmd.Write(*rendered)
+	if err != nil {
+		return "", err
+	}
+
+	return string(out), nil
+}
+
+func (e *kubeconfigExec) execConfig() *clientcmdapi.ExecConfig {
+	execConfig := &clientcmdapi.ExecConfig{
+		Command:            e.Command.ValueString(),
+		APIVersion:         kubeconfigExecDefaultAPIVersion,
+		ProvideClusterInfo: e.ProvideClusterInfo.ValueBool(),
+		InteractiveMode:    clientcmdapi.IfAvailableExecInteractiveMode,
+	}
+
+	if !e.APIVersion.IsNull() {
+		execConfig.APIVersion = e.APIVersion.ValueString()
+	}
+
+	for _, arg := range e.Args {
+		execConfig.Args = append(execConfig.Args, arg.ValueString())
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(e.Env)) {
+		execConfig.Env = append(execConfig.Env, clientcmdapi.ExecEnvVar{
+			Name:  name,
+			Value: e.Env[name].ValueString(),
+		})
+	}
+
+	return execConfig
 }
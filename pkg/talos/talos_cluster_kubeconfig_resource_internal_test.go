@@ -0,0 +1,239 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package talos //nolint:testpackage // needs access to unexported kubeconfig expiry helpers
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/siderolabs/crypto/x509"
+	"github.com/siderolabs/talos/pkg/machinery/config"
+	"github.com/siderolabs/talos/pkg/machinery/config/generate/secrets"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+func TestKubernetesClientCertificateExpiry(t *testing.T) {
+	t.Parallel()
+
+	notAfter := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	ca, err := x509.NewSelfSignedCertificateAuthority(
+		x509.Organization("test"),
+		x509.NotBefore(notAfter.Add(-365*24*time.Hour)),
+		x509.NotAfter(notAfter),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := parseKubernetesClientCertificate(ca.CrtPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expiresAt, renewAfter := kubernetesClientCertificateExpiry(cert, kubeconfigDefaultRenewalDuration)
+
+	if expiresAt.ValueString() != "2026-03-01T12:00:00Z" {
+		t.Errorf("unexpected expires_at %q", expiresAt.ValueString())
+	}
+
+	if renewAfter.ValueString() != "2026-01-30T12:00:00Z" {
+		t.Errorf("unexpected renew_after %q", renewAfter.ValueString())
+	}
+
+	if _, err = parseKubernetesClientCertificate([]byte("not a certificate")); err == nil {
+		t.Error("expected an error for an invalid certificate")
+	}
+
+	for _, test := range []struct {
+		now      time.Time
+		expected string
+	}{
+		{
+			now:      notAfter.Add(-10 * 24 * time.Hour),
+			expected: "The kubernetes client certificate expires in 10 day(s), on 2026-03-01T12:00:00Z",
+		},
+		{
+			now:      notAfter.Add(3 * 24 * time.Hour),
+			expected: "The kubernetes client certificate expired 3 day(s) ago, on 2026-03-01T12:00:00Z",
+		},
+	} {
+		if actual := kubernetesClientCertificateRenewalMessage(notAfter, test.now); actual != test.expected {
+			t.Errorf("expected %q, got %q", test.expected, actual)
+		}
+	}
+}
+
+func newTestKubeconfig(t *testing.T, clusterName, endpoint string) string {
+	t.Helper()
+
+	secretsBundle, err := secrets.NewBundle(secrets.NewFixedClock(time.Now()), config.TalosVersionCurrent)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	kc, err := GenerateKubeconfig(secretsBundle, clusterName, endpoint, time.Now(), time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return kc.Raw
+}
+
+func TestGenerateKubeconfigForUserSeparators(t *testing.T) {
+	t.Parallel()
+
+	secretsBundle, err := secrets.NewBundle(secrets.NewFixedClock(time.Now()), config.TalosVersionCurrent)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	notBefore := time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+	keys := map[string]string{}
+
+	for _, user := range []struct {
+		username string
+		groups   []string
+	}{
+		{username: "ops:admins", groups: []string{"view"}},
+		{username: "ops", groups: []string{"admins:view"}},
+		{username: "ops", groups: []string{"admins,view"}},
+		{username: "ops", groups: []string{"admins", "view"}},
+	} {
+		kc, err := GenerateKubeconfigForUser(secretsBundle, "prod", "https://10.5.0.1:6443", user.username, user.groups, notBefore, notBefore.Add(time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		name := user.username + " " + strings.Join(user.groups, " ")
+
+		if other, ok := keys[string(kc.ClientKeyPEM)]; ok {
+			t.Errorf("%q and %q derived the same client key", other, name)
+		}
+
+		keys[string(kc.ClientKeyPEM)] = name
+	}
+}
+
+func TestRenderKubeconfig(t *testing.T) {
+	t.Parallel()
+
+	own := newTestKubeconfig(t, "prod", "https://10.5.0.1:6443")
+	other := newTestKubeconfig(t, "staging", "https://10.6.0.1:6443")
+
+	exec := &kubeconfigExec{
+		Command: types.StringValue("kubectl-oidc"),
+		Args:    []types.String{types.StringValue("get-token")},
+		Env: map[string]types.String{
+			"B": types.StringValue("2"),
+			"A": types.StringValue("1"),
+		},
+		ProvideClusterInfo: types.BoolValue(true),
+	}
+
+	t.Run("exec", func(t *testing.T) {
+		t.Parallel()
+
+		rendered, err := renderKubeconfig(own, exec, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		kubeConfig, err := clientcmd.Load([]byte(rendered))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if kubeConfig.CurrentContext != "admin@prod" {
+			t.Errorf("expected the current context to be kept, got %q", kubeConfig.CurrentContext)
+		}
+
+		authInfo := kubeConfig.AuthInfos[kubeConfig.Contexts["admin@prod"].AuthInfo]
+		if len(authInfo.ClientCertificateData) != 0 || len(authInfo.ClientKeyData) != 0 {
+			t.Error("expected the client certificate to be replaced")
+		}
+
+		if authInfo.Exec == nil || authInfo.Exec.Command != "kubectl-oidc" || authInfo.Exec.APIVersion != kubeconfigExecDefaultAPIVersion || !authInfo.Exec.ProvideClusterInfo {
+			t.Fatalf("unexpected exec config %+v", authInfo.Exec)
+		}
+
+		if len(authInfo.Exec.Env) != 2 || authInfo.Exec.Env[0].Name != "A" || authInfo.Exec.Env[1].Name != "B" {
+			t.Errorf("expected the environment variables to be sorted, got %+v", authInfo.Exec.Env)
+		}
+
+		again, err := renderKubeconfig(own, exec, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if again != rendered {
+			t.Error("expected rendering to be deterministic")
+		}
+	})
+
+	t.Run("contexts", func(t *testing.T) {
+		t.Parallel()
+
+		rendered, err := renderKubeconfig(own, nil, []kubeconfigContext{
+			{Name: types.StringValue("prod"), Namespace: types.StringValue("apps")},
+			{Name: types.StringValue("prod-node"), Server: types.StringValue("https://10.5.0.2:6443")},
+			{Name: types.StringValue("staging"), KubeConfigRaw: types.StringValue(other)},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		kubeConfig, err := clientcmd.Load([]byte(rendered))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if kubeConfig.CurrentContext != "prod" || len(kubeConfig.Contexts) != 3 {
+			t.Fatalf("unexpected contexts %v, current %q", kubeConfig.Contexts, kubeConfig.CurrentContext)
+		}
+
+		for _, test := range []struct {
+			context   string
+			server    string
+			namespace string
+		}{
+			{context: "prod", server: "https://10.5.0.1:6443", namespace: "apps"},
+			{context: "prod-node", server: "https://10.5.0.2:6443", namespace: "default"},
+			{context: "staging", server: "https://10.6.0.1:6443", namespace: "default"},
+		} {
+			kubeContext := kubeConfig.Contexts[test.context]
+
+			if kubeContext.Namespace != test.namespace {
+				t.Errorf("%s: expected namespace %q, got %q", test.context, test.namespace, kubeContext.Namespace)
+			}
+
+			if server := kubeConfig.Clusters[kubeContext.Cluster].Server; server != test.server {
+				t.Errorf("%s: expected server %q, got %q", test.context, test.server, server)
+			}
+
+			if len(kubeConfig.AuthInfos[kubeContext.AuthInfo].ClientCertificateData) == 0 {
+				t.Errorf("%s: expected the client certificate to be kept", test.context)
+			}
+		}
+
+		if string(kubeConfig.Clusters["prod"].CertificateAuthorityData) == string(kubeConfig.Clusters["staging"].CertificateAuthorityData) {
+			t.Error("expected each cluster to keep its CA")
+		}
+	})
+
+	t.Run("duplicate context", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := renderKubeconfig(own, nil, []kubeconfigContext{
+			{Name: types.StringValue("prod")},
+			{Name: types.StringValue("prod"), KubeConfigRaw: types.StringValue(other)},
+		})
+		if err == nil || !strings.Contains(err.Error(), "duplicate context") {
+			t.Errorf("expected a duplicate context error, got %v", err)
+		}
+	})
+}
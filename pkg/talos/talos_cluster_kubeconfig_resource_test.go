@@ -42,6 +42,9 @@ func TestAccTalosClusterKubeconfigResource(t *testing.T) {
 					resource.TestCheckResourceAttrSet("talos_cluster_kubeconfig.this", "kubernetes_client_configuration.ca_certificate"),
 					resource.TestCheckResourceAttrSet("talos_cluster_kubeconfig.this", "kubernetes_client_configuration.client_certificate"),
 					resource.TestCheckResourceAttrSet("talos_cluster_kubeconfig.this", "kubernetes_client_configuration.client_key"),
+					resource.TestCheckResourceAttrSet("talos_cluster_kubeconfig.this", "expires_at"),
+					resource.TestCheckResourceAttrSet("talos_cluster_kubeconfig.this", "renew_after"),
+					resource.TestCheckNoResourceAttr("talos_cluster_kubeconfig.this", "rendered_kubeconfig_raw"),
 				),
 			},
 			// test kubeconfig regeneration
@@ -85,6 +88,9 @@ func TestAccTalosClusterKubeconfigResource(t *testing.T) {
 					resource.TestCheckResourceAttrSet("talos_cluster_kubeconfig.this", "kubernetes_client_configuration.ca_certificate"),
 					resource.TestCheckResourceAttrSet("talos_cluster_kubeconfig.this", "kubernetes_client_configuration.client_certificate"),
 					resource.TestCheckResourceAttrSet("talos_cluster_kubeconfig.this", "kubernetes_client_configuration.client_key"),
+					resource.TestCheckResourceAttrSet("talos_cluster_kubeconfig.this", "expires_at"),
+					resource.TestCheckResourceAttrSet("talos_cluster_kubeconfig.this", "renew_after"),
+					resource.TestCheckNoResourceAttr("talos_cluster_kubeconfig.this", "rendered_kubeconfig_raw"),
 				),
 			},
 			// make sure there are no changes
@@ -0,0 +1,322 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package talos
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework-timeouts/resource/timeouts"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-framework/types/basetypes"
+	goupgrade "github.com/siderolabs/go-kubernetes/kubernetes/upgrade"
+)
+
+type talosClusterKubernetesUpgradePlanDataSource struct{}
+
+var _ datasource.DataSource = &talosClusterKubernetesUpgradePlanDataSource{}
+
+type talosClusterKubernetesUpgradePlanDataSourceModelV0 struct { //nolint:govet
+	ID                  types.String        `tfsdk:"id"`
+	Endpoint            types.String        `tfsdk:"endpoint"`
+	ClientConfiguration clientConfiguration `tfsdk:"client_configuration"`
+	KubernetesVersion   types.String        `tfsdk:"kubernetes_version"`
+	ImageRegistryMirror types.String        `tfsdk:"image_registry_mirror"`
+	Images              *talosClusterImages `tfsdk:"images"`
+	FromVersion         types.String        `tfsdk:"from_version"`
+	ToVersion           types.String        `tfsdk:"to_version"`
+	Path                types.String        `tfsdk:"path"`
+	Supported           types.Bool          `tfsdk:"supported"`
+	ImagesToPull        []types.String      `tfsdk:"images_to_pull"`
+	ManifestChanges     []types.String      `tfsdk:"manifest_changes"`
+	RemovedItems        []removedItem       `tfsdk:"removed_items"`
+	Log                 types.String        `tfsdk:"log"`
+	Timeouts            timeouts.Value      `tfsdk:"timeouts"`
+}
+
+type removedItem struct {
+	Kind      types.String `tfsdk:"kind"`
+	Node      types.String `tfsdk:"node"`
+	Component types.String `tfsdk:"component"`
+	Value     types.String `tfsdk:"value"`
+	Count     types.Int64  `tfsdk:"count"`
+}
+
+// NewTalosClusterKubernetesUpgradePlanDataSource implements the datasource.DataSource interface.
+func NewTalosClusterKubernetesUpgradePlanDataSource() datasource.DataSource {
+	return &talosClusterKubernetesUpgradePlanDataSource{}
+}
+
+func (d *talosClusterKubernetesUpgradePlanDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_cluster_kubernetes_upgrade_plan"
+}
+
+func (d *talosClusterKubernetesUpgradePlanDataSource) Schema(ctx context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Runs a Kubernetes upgrade in dry-run mode and reports what it would change",
+		MarkdownDescription: "Runs Talos's `upgrade-k8s` procedure in dry-run mode against a cluster and reports the upgrade path, " +
+			"the images that would be pulled, the bootstrap manifest changes and any removed flags, feature gates, admission plugins or API resources still in use. " +
+			"Nothing in the cluster is changed; to pull the images ahead of the upgrade, set `pre_pull_kubernetes_version` on `talos_cluster`.",
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed: true,
+			},
+			"endpoint": schema.StringAttribute{
+				Required:    true,
+				Description: "The control plane endpoint to use for the talosclient.",
+			},
+			"client_configuration": schema.SingleNestedAttribute{
+				Attributes: map[string]schema.Attribute{
+					"ca_certificate": schema.StringAttribute{
+						Required:    true,
+						Description: "The client CA certificate",
+					},
+					"client_certificate": schema.StringAttribute{
+						Required:    true,
+						Description: "The client certificate",
+					},
+					"client_key": schema.StringAttribute{
+						Required:    true,
+						Sensitive:   true,
+						Description: "The client key",
+					},
+				},
+				Required:    true,
+				Description: "The client configuration data",
+			},
+			"kubernetes_version": schema.StringAttribute{
+				Required:    true,
+				Description: "The Kubernetes version to plan the upgrade to (e.g. `v1.32.0`).",
+			},
+			"image_registry_mirror": schema.StringAttribute{
+				Optional:    true,
+				Description: "Registry prefix replacing the upstream registry of all Kubernetes component images. Should match `image_registry_mirror` of `talos_cluster`.",
+			},
+			"images": schema.SingleNestedAttribute{
+				Optional:    true,
+				Description: "Per-component image repositories (without tag), taking precedence over `image_registry_mirror`. Should match `images` of `talos_cluster`.",
+				Attributes: map[string]schema.Attribute{
+					"kubelet": schema.StringAttribute{
+						Optional:    true,
+						Description: "The kubelet image repository.",
+					},
+					"kube_apiserver": schema.StringAttribute{
+						Optional:    true,
+						Description: "The kube-apiserver image repository.",
+					},
+					"kube_controller_manager": schema.StringAttribute{
+						Optional:    true,
+						Description: "The kube-controller-manager image repository.",
+					},
+					"kube_scheduler": schema.StringAttribute{
+						Optional:    true,
+						Description: "The kube-scheduler image repository.",
+					},
+					"kube_proxy": schema.StringAttribute{
+						Optional:    true,
+						Description: "The kube-proxy image repository.",
+					},
+				},
+			},
+			"from_version": schema.StringAttribute{
+				Computed:    true,
+				Description: "The lowest Kubernetes version currently running in the cluster.",
+			},
+			"to_version": schema.StringAttribute{
+				Computed:    true,
+				Description: "The Kubernetes version the upgrade targets.",
+			},
+			"path": schema.StringAttribute{
+				Computed:    true,
+				Description: "The upgrade path in minor versions, e.g. `1.31->1.32`.",
+			},
+			"supported": schema.BoolAttribute{
+				Computed:    true,
+				Description: "Whether the upgrade path is supported. Unsupported paths are not dry-run.",
+			},
+			"images_to_pull": schema.ListAttribute{
+				Computed:    true,
+				ElementType: types.StringType,
+				Description: "The component images the upgrade pulls.",
+			},
+			"manifest_changes": schema.ListAttribute{
+				Computed:    true,
+				ElementType: types.StringType,
+				Description: "The bootstrap manifest changes the upgrade would apply, e.g. `update DaemonSet kube-system/kube-proxy`.",
+			},
+			"removed_items": schema.ListNestedAttribute{
+				Computed:    true,
+				Description: "Items removed in the target version that are still in use. The upgrade fails while any are present.",
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"kind": schema.StringAttribute{
+							Computed:    true,
+							Description: "One of `admission_plugin`, `feature_gate`, `flag` or `api_resource`.",
+						},
+						"node": schema.StringAttribute{
+							Computed:    true,
+							Description: "The node the item is configured on. Empty for API resources.",
+						},
+						"component": schema.StringAttribute{
+							Computed:    true,
+							Description: "The component the item is configured for. Empty for API resources.",
+						},
+						"value": schema.StringAttribute{
+							Computed:    true,
+							Description: "The removed flag, feature gate, admission plugin or API resource.",
+						},
+						"count": schema.Int64Attribute{
+							Computed:    true,
+							Description: "The number of objects still stored with a removed API resource version.",
+						},
+					},
+				},
+			},
+			"log": schema.StringAttribute{
+				Computed:    true,
+				Description: "The full dry-run log.",
+			},
+			"timeouts": timeouts.Attributes(ctx, timeouts.Opts{
+				Read: true,
+			}),
+		},
+	}
+}
+
+func (d *talosClusterKubernetesUpgradePlanDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var obj types.Object
+
+	diags := req.Config.Get(ctx, &obj)
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var state talosClusterKubernetesUpgradePlanDataSourceModelV0
+
+	diags = obj.As(ctx, &state, basetypes.ObjectAsOptions{
+		UnhandledNullAsEmpty:    true,
+		UnhandledUnknownAsEmpty: true,
+	})
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	talosConfig, err := talosClientTFConfigToTalosClientConfig(
+		"dynamic",
+		state.ClientConfiguration.CA.ValueString(),
+		state.ClientConfiguration.Cert.ValueString(),
+		state.ClientConfiguration.Key.ValueString(),
+	)
+	if err != nil {
+		resp.Diagnostics.AddError("failed to generate talos config", err.Error())
+
+		return
+	}
+
+	if err = validateTalosClusterImages(state.ImageRegistryMirror, state.Images); err != nil {
+		resp.Diagnostics.AddError("invalid Kubernetes component image", err.Error())
+
+		return
+	}
+
+	readTimeout, diags := state.Timeouts.Read(ctx, 10*time.Minute)
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctxDeadline, cancel := context.WithTimeout(ctx, readTimeout)
+	defer cancel()
+
+	opts := talosClusterUpgradeOptions(state.Endpoint.ValueString(), state.ImageRegistryMirror, state.Images)
+
+	plan, err := talosClusterPlanKubernetesUpgrade(ctxDeadline, state.Endpoint.ValueString(), talosConfig, state.KubernetesVersion.ValueString(), opts)
+	if err != nil {
+		resp.Diagnostics.AddError("failed to plan Kubernetes upgrade", err.Error())
+
+		return
+	}
+
+	state.FromVersion = types.StringValue(plan.FromVersion)
+	state.ToVersion = types.StringValue(plan.ToVersion)
+	state.Path = types.StringValue(plan.Path)
+	state.Supported = types.BoolValue(plan.Supported)
+	state.ImagesToPull = stringsToTFTypes(plan.Images)
+	state.ManifestChanges = stringsToTFTypes(plan.ManifestChanges)
+	state.RemovedItems = removedItemsToTFTypes(plan.RemovedItems)
+	state.Log = types.StringValue(strings.Join(plan.Log, "\n"))
+	state.ID = types.StringValue(plan.Path)
+
+	if !plan.Supported {
+		resp.Diagnostics.AddWarning("unsupported upgrade path",
+			"upgrading Kubernetes from "+plan.FromVersion+" to "+plan.ToVersion+" is not supported, upgrade one minor version at a time")
+	}
+
+	if len(state.RemovedItems) > 0 {
+		resp.Diagnostics.AddWarning("upgrade blocked by removed items", plan.RemovedItems.Error())
+	}
+
+	diags = resp.State.Set(ctx, &state)
+	resp.Diagnostics.Append(diags...)
+}
+
+func stringsToTFTypes(values []string) []types.String {
+	result := make([]types.String, 0, len(values))
+
+	for _, v := range values {
+		result = append(result, types.StringValue(v))
+	}
+
+	return result
+}
+
+func removedItemsToTFTypes(removed goupgrade.ComponentRemovedItemsError) []removedItem {
+	var result []removedItem
+
+	for kind, items := range map[string][]goupgrade.ComponentItem{
+		"admission_plugin": removed.AdmissionFlags,
+		"feature_gate":     removed.FeatureGates,
+		"flag":             removed.CLIFlags,
+	} {
+		for _, item := range items {
+			result = append(result, removedItem{
+				Kind:      types.StringValue(kind),
+				Node:      types.StringValue(item.Node),
+				Component: types.StringValue(item.Component),
+				Value:     types.StringValue(item.Value),
+				Count:     types.Int64Null(),
+			})
+		}
+	}
+
+	for resource, count := range removed.APIResources {
+		result = append(result, removedItem{
+			Kind:      types.StringValue("api_resource"),
+			Node:      types.StringValue(""),
+			Component: types.StringValue(""),
+			Value:     types.StringValue(resource),
+			Count:     types.Int64Value(int64(count)),
+		})
+	}
+
+	// map iteration order is random, keep the output stable between reads
+	slices.SortFunc(result, func(a, b removedItem) int {
+		return strings.Compare(
+			a.Kind.ValueString()+a.Node.ValueString()+a.Component.ValueString()+a.Value.ValueString(),
+			b.Kind.ValueString()+b.Node.ValueString()+b.Component.ValueString()+b.Value.ValueString(),
+		)
+	})
+
+	return result
+}
@@ -8,30 +8,47 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"maps"
+	pathpkg "path"
 	"slices"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/cosi-project/runtime/pkg/safe"
+	cosistate "github.com/cosi-project/runtime/pkg/state"
 	"github.com/hashicorp/terraform-plugin-framework-timeouts/resource/timeouts"
+	"github.com/hashicorp/terraform-plugin-framework-validators/int64validator"
 	"github.com/hashicorp/terraform-plugin-framework/attr"
 	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/booldefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/boolplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/int64default"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/listplanmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
 	"github.com/hashicorp/terraform-plugin-framework/types"
 	"github.com/hashicorp/terraform-plugin-framework/types/basetypes"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/retry"
 	"github.com/siderolabs/go-kubernetes/kubernetes/ssa"
 	goupgrade "github.com/siderolabs/go-kubernetes/kubernetes/upgrade"
+	"github.com/siderolabs/talos/cmd/talosctl/pkg/talos/action"
 	"github.com/siderolabs/talos/pkg/cluster"
 	"github.com/siderolabs/talos/pkg/cluster/check"
 	k8s "github.com/siderolabs/talos/pkg/cluster/kubernetes"
+	machineapi "github.com/siderolabs/talos/pkg/machinery/api/machine"
 	"github.com/siderolabs/talos/pkg/machinery/client"
 	clientconfig "github.com/siderolabs/talos/pkg/machinery/client/config"
 	"github.com/siderolabs/talos/pkg/machinery/config/encoder"
 	"github.com/siderolabs/talos/pkg/machinery/constants"
+	configresource "github.com/siderolabs/talos/pkg/machinery/resources/config"
+	etcdresource "github.com/siderolabs/talos/pkg/machinery/resources/etcd"
+	"golang.org/x/sync/errgroup"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
@@ -45,14 +62,39 @@ var (
 )
 
 type talosClusterResourceModel struct {
-	ClientConfiguration   basetypes.ObjectValue `tfsdk:"client_configuration"`
-	ClientConfigurationWO basetypes.ObjectValue `tfsdk:"client_configuration_wo"`
-	ControlPlaneNodes     types.List            `tfsdk:"control_plane_nodes"`
-	Endpoint              types.String          `tfsdk:"endpoint"`
-	ID                    types.String          `tfsdk:"id"`
-	KubernetesVersion     types.String          `tfsdk:"kubernetes_version"`
-	Node                  types.String          `tfsdk:"node"`
-	Timeouts              timeouts.Value        `tfsdk:"timeouts"`
+	ClientConfiguration   basetypes.ObjectValue  `tfsdk:"client_configuration"`
+	ClientConfigurationWO basetypes.ObjectValue  `tfsdk:"client_configuration_wo"`
+	ControlPlaneNodes     types.List             `tfsdk:"control_plane_nodes"`
+	Endpoint              types.String           `tfsdk:"endpoint"`
+	ImageRegistryMirror   types.String           `tfsdk:"image_registry_mirror"`
+	Images                *talosClusterImages    `tfsdk:"images"`
+	EtcdBootstrapped      types.Bool             `tfsdk:"etcd_bootstrapped"`
+	EtcdMembers           types.List             `tfsdk:"etcd_members"`
+	ID                    types.String           `tfsdk:"id"`
+	KubernetesVersion     types.String           `tfsdk:"kubernetes_version"`
+	Node                  types.String           `tfsdk:"node"`
+	OnDestroy             *talosClusterOnDestroy `tfsdk:"on_destroy"`
+	PrePullImages         types.Bool             `tfsdk:"pre_pull_images"`
+	PrePullK8sVersion     types.String           `tfsdk:"pre_pull_kubernetes_version"`
+	RecoverFromSnapshot   *etcdRecoverOptions    `tfsdk:"recover_from_snapshot"`
+	RunningK8sVersion     types.String           `tfsdk:"running_kubernetes_version"`
+	Timeouts              timeouts.Value         `tfsdk:"timeouts"`
+}
+
+type talosClusterImages struct {
+	Kubelet           types.String `tfsdk:"kubelet"`
+	APIServer         types.String `tfsdk:"kube_apiserver"`
+	ControllerManager types.String `tfsdk:"kube_controller_manager"`
+	Scheduler         types.String `tfsdk:"kube_scheduler"`
+	Proxy             types.String `tfsdk:"kube_proxy"`
+}
+
+type talosClusterOnDestroy struct {
+	Reset       types.Bool  `tfsdk:"reset"`
+	Graceful    types.Bool  `tfsdk:"graceful"`
+	Reboot      types.Bool  `tfsdk:"reboot"`
+	WorkerNodes types.List  `tfsdk:"worker_nodes"`
+	Concurrency types.Int64 `tfsdk:"concurrency"`
 }
 
 // NewTalosClusterResource implements the resource.Resource interface.
@@ -84,7 +126,8 @@ func (r *talosClusterResource) Schema(ctx context.Context, _ resource.SchemaRequ
 				Optional:    true,
 				Computed:    true,
 				ElementType: types.StringType,
-				Description: "List of all control plane node IPs used for etcd health checks. Defaults to [node]. Required for HA clusters where all control plane IPs must be listed.",
+				Description: "List of all control plane node IPs used for etcd health checks. Defaults to [node]. Required for HA clusters where all control plane IPs must be listed. " +
+					"Adding a node waits for it to join etcd as a voting member; removing a node removes its etcd member, refusing when quorum would be lost.",
 				PlanModifiers: []planmodifier.List{
 					listplanmodifier.UseStateForUnknown(),
 				},
@@ -150,9 +193,119 @@ func (r *talosClusterResource) Schema(ctx context.Context, _ resource.SchemaRequ
 					"changes owned by `upgrade-k8s` are excluded from drift detection and `talos_cluster` " +
 					"fully owns the upgrade sequencing.",
 			},
+			"image_registry_mirror": schema.StringAttribute{
+				Optional: true,
+				Description: "Registry prefix replacing the upstream registry of all Kubernetes component images during upgrades, " +
+					"e.g. `registry.example.com/k8s` upgrades kube-apiserver from `registry.example.com/k8s/kube-apiserver`. " +
+					"Use for air-gapped clusters that mirror the upstream images.",
+			},
+			"images": schema.SingleNestedAttribute{
+				Optional: true,
+				Description: "Per-component image repositories (without tag) used during upgrades, taking precedence over `image_registry_mirror`. " +
+					"The Kubernetes version is appended as the tag. Should match the images in the machine configuration, a plan warning is shown otherwise.",
+				Attributes: map[string]schema.Attribute{
+					"kubelet": schema.StringAttribute{
+						Optional:    true,
+						Description: "The kubelet image repository. Defaults to `" + constants.KubeletImage + "`.",
+					},
+					"kube_apiserver": schema.StringAttribute{
+						Optional:    true,
+						Description: "The kube-apiserver image repository. Defaults to `" + constants.KubernetesAPIServerImage + "`.",
+					},
+					"kube_controller_manager": schema.StringAttribute{
+						Optional:    true,
+						Description: "The kube-controller-manager image repository. Defaults to `" + constants.KubernetesControllerManagerImage + "`.",
+					},
+					"kube_scheduler": schema.StringAttribute{
+						Optional:    true,
+						Description: "The kube-scheduler image repository. Defaults to `" + constants.KubernetesSchedulerImage + "`.",
+					},
+					"kube_proxy": schema.StringAttribute{
+						Optional:    true,
+						Description: "The kube-proxy image repository. Defaults to `" + constants.KubeProxyImage + "`.",
+					},
+				},
+			},
+			"pre_pull_images": schema.BoolAttribute{
+				Optional: true,
+				Computed: true,
+				Default:  booldefault.StaticBool(true),
+				Description: "Pre-pull the new Kubernetes images on all nodes as the first step of an upgrade. " +
+					"Disable when images were already pulled ahead of the maintenance window with `pre_pull_kubernetes_version`.",
+			},
+			"pre_pull_kubernetes_version": schema.StringAttribute{
+				Optional: true,
+				Description: "Pull the images of this Kubernetes version on all nodes without upgrading, e.g. ahead of a maintenance window. " +
+					"The images are pulled when the value changes and differs from `kubernetes_version`; the upgrade to this version can then run with `pre_pull_images = false`.",
+			},
+			"recover_from_snapshot": etcdRecoverSchemaAttribute(),
+			"running_kubernetes_version": schema.StringAttribute{
+				Computed: true,
+				Description: "The lowest Kubernetes version currently running in the cluster, detected from the control plane static pods on refresh. " +
+					"When it differs from `kubernetes_version` (e.g. after an out-of-band `talosctl upgrade-k8s`), the drift is reported in the plan.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"etcd_bootstrapped": schema.BoolAttribute{
+				Computed:    true,
+				Description: "Whether etcd is bootstrapped on node, as seen on the last refresh. If it is `false` (e.g. the node was rebuilt), the next apply bootstraps etcd again.",
+				PlanModifiers: []planmodifier.Bool{
+					boolplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"etcd_members": schema.ListAttribute{
+				Computed:    true,
+				ElementType: types.StringType,
+				Description: "Hostnames of the current etcd members, as reported by node on refresh.",
+				PlanModifiers: []planmodifier.List{
+					listplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"on_destroy": schema.SingleNestedAttribute{
+				Description:         "Actions to be taken on destroy, if `reset` is not set this is a no-op.",
+				MarkdownDescription: onDestroyMarkDownDescription,
+				Optional:            true,
+				Attributes: map[string]schema.Attribute{
+					"reset": schema.BoolAttribute{
+						Description: "Reset all nodes of the cluster to the initial state (STATE and EPHEMERAL will be wiped): worker_nodes first, then control_plane_nodes.",
+						Optional:    true,
+						Computed:    true,
+						Default:     booldefault.StaticBool(false),
+					},
+					"graceful": schema.BoolAttribute{
+						Description: "Graceful indicates whether worker nodes should be cordoned and drained before the reset. " +
+							"Control plane nodes are always reset non-gracefully, as etcd is torn down with them.",
+						Optional: true,
+						Computed: true,
+						Default:  booldefault.StaticBool(true),
+					},
+					"reboot": schema.BoolAttribute{
+						Description: "Reboot indicates whether nodes should reboot or halt after resetting.",
+						Optional:    true,
+						Computed:    true,
+						Default:     booldefault.StaticBool(false),
+					},
+					"worker_nodes": schema.ListAttribute{
+						Description: "Worker nodes to reset before the control plane nodes.",
+						Optional:    true,
+						ElementType: types.StringType,
+					},
+					"concurrency": schema.Int64Attribute{
+						Description: "The maximum number of nodes reset in parallel.",
+						Optional:    true,
+						Computed:    true,
+						Default:     int64default.StaticInt64(5),
+						Validators: []validator.Int64{
+							int64validator.AtLeast(1),
+						},
+					},
+				},
+			},
 			"timeouts": timeouts.Attributes(ctx, timeouts.Opts{
 				Create: true,
 				Update: true,
+				Delete: true,
 			}),
 		},
 	}
@@ -184,6 +337,29 @@ func (r *talosClusterResource) ValidateConfig(ctx context.Context, req resource.
 		)
 	}
 
+	if !cfg.ImageRegistryMirror.IsUnknown() && (cfg.Images == nil || !talosClusterImagesUnknown(cfg.Images)) {
+		if err := validateTalosClusterImages(cfg.ImageRegistryMirror, cfg.Images); err != nil {
+			resp.Diagnostics.AddError("Invalid Kubernetes component image", err.Error())
+		}
+	}
+
+	if cfg.OnDestroy != nil && !cfg.OnDestroy.WorkerNodes.IsNull() && !cfg.ControlPlaneNodes.IsNull() {
+		var workers, controlPlanes []string
+
+		resp.Diagnostics.Append(cfg.OnDestroy.WorkerNodes.ElementsAs(ctx, &workers, true)...)
+		resp.Diagnostics.Append(cfg.ControlPlaneNodes.ElementsAs(ctx, &controlPlanes, true)...)
+
+		for _, worker := range workers {
+			if worker != "" && slices.Contains(controlPlanes, worker) {
+				resp.Diagnostics.AddAttributeError(
+					path.Root("on_destroy").AtName("worker_nodes"),
+					"node in both worker_nodes and control_plane_nodes",
+					fmt.Sprintf("node %q can't be both a worker and a control plane node", worker),
+				)
+			}
+		}
+	}
+
 	if !cfg.ControlPlaneNodes.IsNull() && !cfg.Node.IsNull() && !cfg.Node.IsUnknown() {
 		var nodes []string
 
@@ -228,6 +404,63 @@ func (r *talosClusterResource) ModifyPlan(ctx context.Context, req resource.Modi
 		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("control_plane_nodes"),
 			types.ListValueMust(types.StringType, []attr.Value{plan.Node}))...)
 	}
+
+	if req.State.Raw.IsNull() {
+		return
+	}
+
+	var state talosClusterResourceModel
+
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if !plan.ControlPlaneNodes.Equal(state.ControlPlaneNodes) {
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("etcd_members"), types.ListUnknown(types.StringType))...)
+	}
+
+	// The last refresh found etcd not bootstrapped, bootstrap it again in Update.
+	if !state.EtcdBootstrapped.IsNull() && !state.EtcdBootstrapped.ValueBool() {
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("etcd_bootstrapped"), types.BoolValue(true))...)
+	}
+
+	// An upgrade changes what is running; the detected version is only known after apply.
+	if !talosClusterKubernetesVersionsEqual(plan.KubernetesVersion, state.KubernetesVersion) {
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("running_kubernetes_version"), types.StringUnknown())...)
+	} else if !talosClusterImagesChanged(&plan, &state) {
+		return
+	}
+
+	if plan.KubernetesVersion.IsUnknown() || plan.ImageRegistryMirror.IsUnknown() || (plan.Images != nil && talosClusterImagesUnknown(plan.Images)) {
+		return
+	}
+
+	if !cfgFromConfig.ClientConfigurationWO.IsNull() {
+		plan.ClientConfigurationWO = cfgFromConfig.ClientConfigurationWO
+	}
+
+	talosConfig, err := resolveTalosClusterClientConfig(ctx, &plan)
+	if err != nil {
+		return
+	}
+
+	// Best effort: the upgrade itself reports connectivity problems.
+	mismatches, err := talosClusterImageMismatches(ctx, talosClusterEffectiveEndpoint(&plan), plan.Node.ValueString(), talosConfig,
+		talosClusterUpgradeOptions("", plan.ImageRegistryMirror, plan.Images))
+	if err != nil {
+		tflog.Warn(ctx, "failed to compare component images with the machine configuration", map[string]any{"error": err.Error()})
+
+		return
+	}
+
+	if len(mismatches) > 0 {
+		resp.Diagnostics.AddWarning(
+			"Kubernetes component images differ from the machine configuration",
+			"The upgrade patches the machine configuration of every node with the new images:\n"+strings.Join(mismatches, "\n"),
+		)
+	}
 }
 
 func (r *talosClusterResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
@@ -251,6 +484,10 @@ func (r *talosClusterResource) Create(ctx context.Context, req resource.CreateRe
 		plan.ClientConfigurationWO = cfgModel.ClientConfigurationWO
 	}
 
+	if plan.RecoverFromSnapshot != nil && cfgModel.RecoverFromSnapshot != nil {
+		plan.RecoverFromSnapshot.AgeIdentityWO = cfgModel.RecoverFromSnapshot.AgeIdentityWO
+	}
+
 	talosConfig, err := resolveTalosClusterClientConfig(ctx, &plan)
 	if err != nil {
 		resp.Diagnostics.AddError("failed to build talos config", err.Error())
@@ -258,6 +495,14 @@ func (r *talosClusterResource) Create(ctx context.Context, req resource.CreateRe
 		return
 	}
 
+	if plan.RecoverFromSnapshot != nil {
+		if err = etcdCheckSnapshot(plan.RecoverFromSnapshot); err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("recover_from_snapshot"), "error reading etcd snapshot", err.Error())
+
+			return
+		}
+	}
+
 	timeout, diags := plan.Timeouts.Create(ctx, 20*time.Minute)
 	resp.Diagnostics.Append(diags...)
 
@@ -271,7 +516,7 @@ func (r *talosClusterResource) Create(ctx context.Context, req resource.CreateRe
 	endpoint := talosClusterEffectiveEndpoint(&plan)
 	plan.Endpoint = types.StringValue(endpoint)
 
-	if bootstrapErr := talosClusterBootstrap(ctxDeadline, endpoint, plan.Node.ValueString(), talosConfig); bootstrapErr != nil {
+	if bootstrapErr := talosClusterBootstrap(ctxDeadline, endpoint, plan.Node.ValueString(), talosConfig, plan.RecoverFromSnapshot); bootstrapErr != nil {
 		resp.Diagnostics.AddError("error bootstrapping etcd", bootstrapErr.Error())
 
 		return
@@ -291,7 +536,25 @@ func (r *talosClusterResource) Create(ctx context.Context, req resource.CreateRe
 		return
 	}
 
+	if !plan.PrePullK8sVersion.IsNull() && !talosClusterKubernetesVersionsEqual(plan.PrePullK8sVersion, plan.KubernetesVersion) {
+		opts := talosClusterUpgradeOptions(endpoint, plan.ImageRegistryMirror, plan.Images)
+
+		if pullErr := talosClusterPrePullKubernetesImages(ctxDeadline, endpoint, talosConfig, plan.PrePullK8sVersion.ValueString(), opts); pullErr != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("pre_pull_kubernetes_version"), "error pre-pulling Kubernetes images", pullErr.Error())
+
+			return
+		}
+	}
+
 	plan.ID = types.StringValue(plan.Node.ValueString())
+	plan.EtcdBootstrapped = types.BoolValue(true)
+	plan.EtcdMembers = types.ListNull(types.StringType)
+	plan.RunningK8sVersion = types.StringNull()
+
+	// Kubernetes may not be serving yet; the first refresh fills in whatever is missing.
+	if members, membersErr := talosClusterEtcdMembers(ctxDeadline, endpoint, plan.Node.ValueString(), talosConfig); membersErr == nil {
+		plan.EtcdMembers = members
+	}
 
 	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
 }
@@ -305,6 +568,65 @@ func (r *talosClusterResource) Read(ctx context.Context, req resource.ReadReques
 		return
 	}
 
+	// Write-only credentials are not persisted to state. Skip the live refresh
+	// rather than failing — drift detection is unavailable in this mode.
+	if state.ClientConfiguration.IsNull() {
+		return
+	}
+
+	talosConfig, err := resolveTalosClusterClientConfig(ctx, &state)
+	if err != nil {
+		resp.Diagnostics.AddError("failed to build talos config from state", err.Error())
+
+		return
+	}
+
+	endpoint := talosClusterEffectiveEndpoint(&state)
+	node := state.Node.ValueString()
+
+	bootstrapped, err := talosClusterEtcdBootstrapped(ctx, endpoint, node, talosConfig)
+	if err != nil {
+		// Cluster unreachable — keep the last known state.
+		tflog.Warn(ctx, "failed to refresh etcd bootstrap status", map[string]any{"node": node, "error": err.Error()})
+
+		resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
+
+		return
+	}
+
+	// etcd may also not be running yet, e.g. right after a reboot: only record it,
+	// the next apply bootstraps again, which is safe as Talos answers AlreadyExists if etcd has data.
+	state.EtcdBootstrapped = types.BoolValue(bootstrapped)
+
+	if !bootstrapped {
+		resp.Diagnostics.AddWarning(
+			"etcd is not bootstrapped",
+			fmt.Sprintf("etcd is not bootstrapped on node %q, the next apply bootstraps it again.", node),
+		)
+
+		resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
+
+		return
+	}
+
+	if members, membersErr := talosClusterEtcdMembers(ctx, endpoint, node, talosConfig); membersErr == nil {
+		state.EtcdMembers = members
+	} else {
+		tflog.Warn(ctx, "failed to refresh etcd members", map[string]any{"node": node, "error": membersErr.Error()})
+	}
+
+	running, err := talosClusterDetectKubernetesVersion(ctx, endpoint, talosConfig)
+	if err != nil {
+		tflog.Warn(ctx, "failed to detect running Kubernetes version", map[string]any{"error": err.Error()})
+	} else {
+		state.RunningK8sVersion = types.StringValue("v" + running)
+
+		// Surface out-of-band upgrades as drift, keeping the configured spelling when nothing changed.
+		if !talosClusterKubernetesVersionsEqual(state.KubernetesVersion, state.RunningK8sVersion) {
+			state.KubernetesVersion = state.RunningK8sVersion
+		}
+	}
+
 	resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
 }
 
@@ -330,6 +652,10 @@ func (r *talosClusterResource) Update(ctx context.Context, req resource.UpdateRe
 		plan.ClientConfigurationWO = cfgModel.ClientConfigurationWO
 	}
 
+	if plan.RecoverFromSnapshot != nil && cfgModel.RecoverFromSnapshot != nil {
+		plan.RecoverFromSnapshot.AgeIdentityWO = cfgModel.RecoverFromSnapshot.AgeIdentityWO
+	}
+
 	talosConfig, err := resolveTalosClusterClientConfig(ctx, &plan)
 	if err != nil {
 		resp.Diagnostics.AddError("failed to build talos config", err.Error())
@@ -350,25 +676,186 @@ func (r *talosClusterResource) Update(ctx context.Context, req resource.UpdateRe
 	endpoint := talosClusterEffectiveEndpoint(&plan)
 	plan.Endpoint = types.StringValue(endpoint)
 
-	if !plan.KubernetesVersion.Equal(state.KubernetesVersion) {
-		if upgradeErr := talosClusterUpgradeKubernetes(ctxDeadline, endpoint, talosConfig, plan.KubernetesVersion.ValueString()); upgradeErr != nil {
+	if !state.EtcdBootstrapped.IsNull() && !state.EtcdBootstrapped.ValueBool() {
+		if plan.RecoverFromSnapshot != nil {
+			if err = etcdCheckSnapshot(plan.RecoverFromSnapshot); err != nil {
+				resp.Diagnostics.AddAttributeError(path.Root("recover_from_snapshot"), "error reading etcd snapshot", err.Error())
+
+				return
+			}
+		}
+
+		if bootstrapErr := talosClusterBootstrap(ctxDeadline, endpoint, plan.Node.ValueString(), talosConfig, plan.RecoverFromSnapshot); bootstrapErr != nil {
+			resp.Diagnostics.AddError("error bootstrapping etcd", bootstrapErr.Error())
+
+			return
+		}
+
+		plan.EtcdBootstrapped = types.BoolValue(true)
+	}
+
+	var oldControlPlaneNodes, newControlPlaneNodes []string
+
+	resp.Diagnostics.Append(state.ControlPlaneNodes.ElementsAs(ctx, &oldControlPlaneNodes, true)...)
+	resp.Diagnostics.Append(plan.ControlPlaneNodes.ElementsAs(ctx, &newControlPlaneNodes, true)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if err = talosClusterReconcileControlPlanes(ctxDeadline, endpoint, plan.Node.ValueString(), talosConfig, oldControlPlaneNodes, newControlPlaneNodes); err != nil {
+		resp.Diagnostics.AddError("error updating etcd membership", err.Error())
+
+		return
+	}
+
+	if members, membersErr := talosClusterEtcdMembers(ctxDeadline, endpoint, plan.Node.ValueString(), talosConfig); membersErr == nil {
+		plan.EtcdMembers = members
+	} else if plan.EtcdMembers.IsUnknown() {
+		plan.EtcdMembers = state.EtcdMembers
+	}
+
+	if !plan.PrePullK8sVersion.IsNull() && !talosClusterKubernetesVersionsEqual(plan.PrePullK8sVersion, state.PrePullK8sVersion) &&
+		!talosClusterKubernetesVersionsEqual(plan.PrePullK8sVersion, state.KubernetesVersion) {
+		opts := talosClusterUpgradeOptions(endpoint, plan.ImageRegistryMirror, plan.Images)
+
+		if pullErr := talosClusterPrePullKubernetesImages(ctxDeadline, endpoint, talosConfig, plan.PrePullK8sVersion.ValueString(), opts); pullErr != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("pre_pull_kubernetes_version"), "error pre-pulling Kubernetes images", pullErr.Error())
+
+			return
+		}
+	}
+
+	// Changing only the images re-runs the upgrade at the current version to repoint the components.
+	if !talosClusterKubernetesVersionsEqual(plan.KubernetesVersion, state.KubernetesVersion) || talosClusterImagesChanged(&plan, &state) {
+		opts := talosClusterUpgradeOptions(endpoint, plan.ImageRegistryMirror, plan.Images)
+		opts.PrePullImages = plan.PrePullImages.ValueBool()
+
+		if upgradeErr := talosClusterUpgradeKubernetes(ctxDeadline, endpoint, talosConfig, plan.KubernetesVersion.ValueString(), opts); upgradeErr != nil {
 			resp.Diagnostics.AddError("error upgrading Kubernetes", upgradeErr.Error())
 
 			return
 		}
+
+		plan.RunningK8sVersion = types.StringValue("v" + strings.TrimPrefix(plan.KubernetesVersion.ValueString(), "v"))
 	}
 
 	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
 }
 
-func (r *talosClusterResource) Delete(_ context.Context, _ resource.DeleteRequest, _ *resource.DeleteResponse) {
+func (r *talosClusterResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var state talosClusterResourceModel
+
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if state.OnDestroy == nil || !state.OnDestroy.Reset.ValueBool() {
+		return
+	}
+
+	// During Delete, write-only attrs are not in state; client_configuration (non-wo) is required.
+	talosConfig, err := resolveTalosClusterClientConfig(ctx, &state)
+	if err != nil {
+		resp.Diagnostics.AddError("failed to build talos config for destroy", err.Error())
+
+		return
+	}
+
+	deleteTimeout, diags := state.Timeouts.Delete(ctx, 10*time.Minute)
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var controlPlaneNodes, workerNodes []string
+
+	resp.Diagnostics.Append(state.ControlPlaneNodes.ElementsAs(ctx, &controlPlaneNodes, true)...)
+	resp.Diagnostics.Append(state.OnDestroy.WorkerNodes.ElementsAs(ctx, &workerNodes, true)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	concurrency := int(state.OnDestroy.Concurrency.ValueInt64())
+	reboot := state.OnDestroy.Reboot.ValueBool()
+
+	// Control planes go last and all at once: etcd is destroyed with them, so there is nothing to leave gracefully.
+	failures := talosClusterResetNodes(ctx, talosConfig, workerNodes, state.OnDestroy.Graceful.ValueBool(), reboot, concurrency, deleteTimeout)
+	maps.Copy(failures, talosClusterResetNodes(ctx, talosConfig, controlPlaneNodes, false, reboot, concurrency, deleteTimeout))
+
+	if len(failures) == 0 {
+		return
+	}
+
+	report := make([]string, 0, len(failures))
+
+	for _, node := range slices.Sorted(maps.Keys(failures)) {
+		report = append(report, fmt.Sprintf("%s: %s", node, failures[node]))
+	}
+
+	resp.Diagnostics.AddError(
+		fmt.Sprintf("failed to reset %d of %d nodes", len(failures), len(workerNodes)+len(controlPlaneNodes)),
+		strings.Join(report, "\n"),
+	)
+}
+
+// talosClusterResetNodes resets nodes in parallel, at most concurrency at a time, and returns the error per failed node.
+// Each node is reached directly, as the control plane endpoint may be going away at the same time.
+func talosClusterResetNodes(ctx context.Context, talosConfig *clientconfig.Config, nodes []string, graceful, reboot bool, concurrency int, timeout time.Duration) map[string]error {
+	var (
+		mu       sync.Mutex
+		eg       errgroup.Group
+		failures = map[string]error{}
+	)
+
+	eg.SetLimit(concurrency)
+
+	for _, node := range nodes {
+		eg.Go(func() error {
+			resetRequest := &machineapi.ResetRequest{
+				Graceful: graceful,
+				Reboot:   reboot,
+				SystemPartitionsToWipe: []*machineapi.ResetPartitionSpec{
+					{Label: "STATE", Wipe: true},
+					{Label: "EPHEMERAL", Wipe: true},
+				},
+			}
+
+			actionFn := func(ctx context.Context, c *client.Client) (string, error) {
+				return resetGetActorID(ctx, c, resetRequest)
+			}
+
+			if err := action.NewTracker(
+				newTalosClientFactory(talosConfig, node, []string{node}),
+				action.StopAllServicesEventFn,
+				actionFn,
+				action.WithDebug(false),
+				action.WithTimeout(timeout),
+			).Run(ctx); err != nil {
+				mu.Lock()
+				failures[node] = err
+				mu.Unlock()
+			}
+
+			return nil
+		})
+	}
+
+	eg.Wait() //nolint:errcheck
+
+	return failures
 }
 
 // talosClusterBootstrap sends the Bootstrap RPC, retrying until etcd is bootstrapped.
 // codes.AlreadyExists is treated as success — bootstrap is idempotent.
 // The retry budget matches the caller's context deadline so the full configured
 // timeout is available rather than a hard-coded sub-window.
-func talosClusterBootstrap(ctx context.Context, endpoint, node string, talosConfig *clientconfig.Config) error {
+// When recoverFrom is set, the snapshot is uploaded before every attempt and etcd is restored from it.
+func talosClusterBootstrap(ctx context.Context, endpoint, node string, talosConfig *clientconfig.Config, recoverFrom *etcdRecoverOptions) error {
 	timeout := 10 * time.Minute // fallback when ctx has no deadline
 
 	if deadline, ok := ctx.Deadline(); ok {
@@ -383,7 +870,19 @@ func talosClusterBootstrap(ctx context.Context, endpoint, node string, talosConf
 
 		defer c.Close() //nolint:errcheck
 
-		if err := c.Bootstrap(client.WithNode(ctx, node), nil); err != nil {
+		nodeCtx := client.WithNode(ctx, node)
+
+		if recoverFrom != nil {
+			if err := etcdUploadSnapshot(nodeCtx, c, recoverFrom); err != nil {
+				if status.Code(err) == codes.InvalidArgument {
+					return retry.NonRetryableError(err)
+				}
+
+				return retry.RetryableError(err)
+			}
+		}
+
+		if err := c.Bootstrap(nodeCtx, etcdBootstrapRequest(recoverFrom)); err != nil {
 			if s := status.Code(err); s == codes.AlreadyExists {
 				return nil
 			} else if s == codes.InvalidArgument {
@@ -397,6 +896,79 @@ func talosClusterBootstrap(ctx context.Context, endpoint, node string, talosConf
 	})
 }
 
+// talosClusterReconcileControlPlanes updates etcd membership after control_plane_nodes changed: added nodes are
+// waited on until they are voting members, then removed nodes leave etcd, as long as quorum is kept.
+// node is the bootstrap node, which always stays in the cluster and is used to observe membership.
+func talosClusterReconcileControlPlanes(ctx context.Context, endpoint, node string, talosConfig *clientconfig.Config, oldNodes, newNodes []string) error {
+	added := slices.DeleteFunc(slices.Clone(newNodes), func(n string) bool { return slices.Contains(oldNodes, n) })
+	removed := slices.DeleteFunc(slices.Clone(oldNodes), func(n string) bool { return slices.Contains(newNodes, n) })
+
+	if len(added) == 0 && len(removed) == 0 {
+		return nil
+	}
+
+	c, err := client.New(ctx, client.WithConfig(talosConfig), client.WithEndpoints(endpoint))
+	if err != nil {
+		return err
+	}
+
+	defer c.Close() //nolint:errcheck
+
+	timeout := 10 * time.Minute // fallback when ctx has no deadline
+
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = time.Until(deadline)
+	}
+
+	for _, n := range added {
+		if err = etcdWaitForVoter(ctx, c, n, node, timeout); err != nil {
+			return fmt.Errorf("waiting for control plane %s to join etcd: %w", n, err)
+		}
+	}
+
+	if len(removed) == 0 {
+		return nil
+	}
+
+	members, err := etcdMemberList(ctx, c, node)
+	if err != nil {
+		return err
+	}
+
+	removing := map[string]*machineapi.EtcdMember{}
+
+	for _, n := range removed {
+		if member := etcdFindMember(members, n); member != nil {
+			removing[n] = member
+		} else {
+			tflog.Info(ctx, "removed control plane has no etcd member", map[string]any{"node": n})
+		}
+	}
+
+	ids := make([]uint64, 0, len(removing))
+
+	for _, member := range removing {
+		ids = append(ids, member.GetId())
+	}
+
+	if err = etcdCheckQuorum(ctx, c, members, ids); err != nil {
+		return err
+	}
+
+	for _, n := range removed {
+		member, ok := removing[n]
+		if !ok {
+			continue
+		}
+
+		if err = etcdRemoveMember(ctx, c, n, member, node); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // talosClusterWaitForK8s waits for the cluster to pass all default health checks.
 func talosClusterWaitForK8s(ctx context.Context, endpoint string, controlPlaneNodes []string, talosConfig *clientconfig.Config) error {
 	c, err := client.New(ctx, client.WithConfig(talosConfig), client.WithEndpoints(endpoint))
@@ -427,8 +999,14 @@ func talosClusterWaitForK8s(ctx context.Context, endpoint string, controlPlaneNo
 	return check.Wait(ctx, &clusterState, check.PreBootSequenceChecks(), newReporter())
 }
 
-// talosClusterUpgradeKubernetes runs a rolling Kubernetes upgrade via the talos cluster package.
-func talosClusterUpgradeKubernetes(ctx context.Context, endpoint string, talosConfig *clientconfig.Config, toVersion string) error {
+// talosClusterUpgradeProvider is the subset of cluster access required by the kubernetes upgrade package.
+type talosClusterUpgradeProvider struct {
+	cluster.ClientProvider
+	cluster.K8sProvider
+}
+
+// withTalosClusterUpgradeProvider connects to endpoint and runs fn with a provider suitable for k8s.Upgrade and friends.
+func withTalosClusterUpgradeProvider(ctx context.Context, endpoint string, talosConfig *clientconfig.Config, fn func(*talosClusterUpgradeProvider) error) error {
 	c, err := client.New(ctx, client.WithConfig(talosConfig), client.WithEndpoints(endpoint))
 	if err != nil {
 		return err
@@ -439,17 +1017,18 @@ func talosClusterUpgradeKubernetes(ctx context.Context, endpoint string, talosCo
 	clientProvider := &cluster.ConfigClientProvider{DefaultClient: c}
 	defer clientProvider.Close() //nolint:errcheck
 
-	clusterState := struct {
-		cluster.ClientProvider
-		cluster.K8sProvider
-	}{
+	return fn(&talosClusterUpgradeProvider{
 		ClientProvider: clientProvider,
 		K8sProvider: &cluster.KubernetesClient{
 			ClientProvider: clientProvider,
 			ForceEndpoint:  endpoint,
 		},
-	}
+	})
+}
 
+// talosClusterUpgradeOptions returns the upgrade options shared by detection, planning and upgrade.
+// Component images default to the upstream repositories, replaced by the registry mirror and then by per-component overrides.
+func talosClusterUpgradeOptions(endpoint string, mirror types.String, images *talosClusterImages) k8s.UpgradeOptions {
 	opts := k8s.UpgradeOptions{
 		ControlPlaneEndpoint:   endpoint,
 		PrePullImages:          true,
@@ -465,45 +1044,358 @@ func talosClusterUpgradeKubernetes(ctx context.Context, endpoint string, talosCo
 		ProxyImage:             constants.KubeProxyImage,
 	}
 
-	fromVersion, err := k8s.DetectLowestVersion(ctx, &clusterState, opts)
-	if err != nil {
-		return err
+	for _, component := range talosClusterImageComponents(&opts, images) {
+		if mirror.ValueString() != "" {
+			*component.image = strings.TrimSuffix(mirror.ValueString(), "/") + "/" + pathpkg.Base(*component.image)
+		}
+
+		if component.override.ValueString() != "" {
+			*component.image = component.override.ValueString()
+		}
 	}
 
-	opts.Path, err = goupgrade.NewPath(fromVersion, strings.TrimPrefix(toVersion, "v"))
-	if err != nil {
-		return err
+	return opts
+}
+
+// talosClusterImageComponent ties a component image in k8s.UpgradeOptions to its override in the talos_cluster images block.
+type talosClusterImageComponent struct {
+	name     string
+	image    *string
+	override types.String
+}
+
+func talosClusterImageComponents(opts *k8s.UpgradeOptions, images *talosClusterImages) []talosClusterImageComponent {
+	if images == nil {
+		images = &talosClusterImages{}
 	}
 
-	return k8s.Upgrade(ctx, &clusterState, opts)
+	return []talosClusterImageComponent{
+		{name: "kubelet", image: &opts.KubeletImage, override: images.Kubelet},
+		{name: "kube_apiserver", image: &opts.APIServerImage, override: images.APIServer},
+		{name: "kube_controller_manager", image: &opts.ControllerManagerImage, override: images.ControllerManager},
+		{name: "kube_scheduler", image: &opts.SchedulerImage, override: images.Scheduler},
+		{name: "kube_proxy", image: &opts.ProxyImage, override: images.Proxy},
+	}
 }
 
-// resolveTalosClusterClientConfig builds the Talos client config from the write-only or
-// regular client_configuration attribute. Write-only credentials are never persisted
-// in state; callers must not assign client_configuration from this return value.
-func resolveTalosClusterClientConfig(ctx context.Context, state *talosClusterResourceModel) (*clientconfig.Config, error) {
-	var clientObj basetypes.ObjectValue
+// validateTalosClusterImages checks the resolved component images are repositories the upgrade can append a version tag to.
+func validateTalosClusterImages(mirror types.String, images *talosClusterImages) error {
+	opts := talosClusterUpgradeOptions("", mirror, images)
 
-	switch {
-	case !state.ClientConfigurationWO.IsNull() && !state.ClientConfigurationWO.IsUnknown():
-		clientObj = state.ClientConfigurationWO
-	case !state.ClientConfiguration.IsNull():
-		clientObj = state.ClientConfiguration
-	default:
-		return nil, errors.New("no client configuration available")
+	for _, component := range talosClusterImageComponents(&opts, nil) {
+		if err := k8s.ValidateImageReference(*component.image + ":v0.0.0"); err != nil {
+			return fmt.Errorf("%s: %q must be an image repository without tag or digest: %w", component.name, *component.image, err)
+		}
 	}
 
-	ca, cert, key, errMsg, ok := getClientConfigurationValues(ctx, clientObj)
-	if !ok {
-		return nil, errors.New(errMsg)
+	return nil
+}
+
+// talosClusterImageRepository strips the tag and digest from an image reference.
+func talosClusterImageRepository(image string) string {
+	image, _, _ = strings.Cut(image, "@")
+
+	if idx := strings.LastIndex(image, ":"); idx > strings.LastIndex(image, "/") {
+		image = image[:idx]
+	}
+
+	return image
+}
+
+// talosClusterImageMismatches compares the component images in the active machine config of node with the
+// repositories the upgrade will use, returning a human-readable line per mismatch.
+func talosClusterImageMismatches(ctx context.Context, endpoint, node string, talosConfig *clientconfig.Config, opts k8s.UpgradeOptions) ([]string, error) {
+	var mismatches []string
+
+	err := talosClientOp(ctx, endpoint, node, talosConfig, func(nodeCtx context.Context, c *client.Client) error {
+		mc, err := safe.StateGet[*configresource.MachineConfig](nodeCtx, c.COSI, configresource.NewMachineConfig(nil).Metadata())
+		if err != nil {
+			return err
+		}
+
+		cfg := mc.Config()
+
+		configured := map[string]string{}
+
+		if cfg.Machine() != nil {
+			configured["kubelet"] = cfg.Machine().Kubelet().Image()
+		}
+
+		if apiServer := cfg.K8sAPIServerConfig(); apiServer != nil {
+			configured["kube_apiserver"] = apiServer.Image()
+		}
+
+		if controllerManager := cfg.K8sControllerManagerConfig(); controllerManager != nil {
+			configured["kube_controller_manager"] = controllerManager.Image()
+		}
+
+		if scheduler := cfg.K8sSchedulerConfig(); scheduler != nil {
+			configured["kube_scheduler"] = scheduler.Image()
+		}
+
+		if proxy := cfg.K8sProxyConfig(); proxy != nil {
+			configured["kube_proxy"] = proxy.Image()
+		}
+
+		for _, component := range talosClusterImageComponents(&opts, nil) {
+			current, ok := configured[component.name]
+			if !ok || current == "" {
+				continue
+			}
+
+			if repo := talosClusterImageRepository(current); repo != *component.image {
+				mismatches = append(mismatches, fmt.Sprintf("%s: machine config uses %q, upgrade will switch to %q", component.name, repo, *component.image))
+			}
+		}
+
+		return nil
+	})
+
+	return mismatches, err
+}
+
+// talosClusterUpgradeKubernetes runs a rolling Kubernetes upgrade via the talos cluster package.
+func talosClusterUpgradeKubernetes(ctx context.Context, endpoint string, talosConfig *clientconfig.Config, toVersion string, opts k8s.UpgradeOptions) error {
+	return withTalosClusterUpgradeProvider(ctx, endpoint, talosConfig, func(clusterState *talosClusterUpgradeProvider) error {
+		fromVersion, err := k8s.DetectLowestVersion(ctx, clusterState, opts)
+		if err != nil {
+			return err
+		}
+
+		opts.Path, err = goupgrade.NewPath(fromVersion, strings.TrimPrefix(toVersion, "v"))
+		if err != nil {
+			return err
+		}
+
+		return k8s.Upgrade(ctx, clusterState, opts)
+	})
+}
+
+// talosClusterPrePullKubernetesImages pulls the images of the Kubernetes upgrade to toVersion on all nodes, without upgrading.
+func talosClusterPrePullKubernetesImages(ctx context.Context, endpoint string, talosConfig *clientconfig.Config, toVersion string, opts k8s.UpgradeOptions) error {
+	opts.DryRun = true
+	opts.PrePullImages = true
+
+	return talosClusterUpgradeKubernetes(ctx, endpoint, talosConfig, toVersion, opts)
+}
+
+// talosClusterUpgradePlan is the outcome of a dry-run Kubernetes upgrade.
+type talosClusterUpgradePlan struct {
+	FromVersion     string
+	ToVersion       string
+	Path            string
+	Supported       bool
+	Images          []string
+	ManifestChanges []string
+	RemovedItems    goupgrade.ComponentRemovedItemsError
+	Log             []string
+}
+
+// talosClusterUpgradeLog collects upgrade log lines; the upgrade package emits one Write per line without a trailing newline.
+type talosClusterUpgradeLog struct {
+	lines []string
+}
+
+func (l *talosClusterUpgradeLog) Write(p []byte) (int, error) {
+	l.lines = append(l.lines, strings.TrimRight(string(p), "\n"))
+
+	return len(p), nil
+}
+
+// manifestChanges returns the manifest diff summary lines logged by a dry-run SSA sync, e.g. "create Deployment kube-system/coredns".
+func (l *talosClusterUpgradeLog) manifestChanges() []string {
+	var (
+		changes  []string
+		syncSeen bool
+	)
+
+	for _, line := range l.lines {
+		if line == "updating manifests (dry run)" {
+			syncSeen = true
+
+			continue
+		}
+
+		if change, ok := strings.CutPrefix(line, " < "); ok && syncSeen {
+			changes = append(changes, change)
+		}
 	}
 
-	talosConfig, err := talosClientTFConfigToTalosClientConfig("dynamic", ca, cert, key)
+	return changes
+}
+
+// talosClusterPlanKubernetesUpgrade runs the Kubernetes upgrade in dry-run mode and reports what it would change.
+func talosClusterPlanKubernetesUpgrade(ctx context.Context, endpoint string, talosConfig *clientconfig.Config, toVersion string, opts k8s.UpgradeOptions) (*talosClusterUpgradePlan, error) {
+	plan := &talosClusterUpgradePlan{}
+
+	err := withTalosClusterUpgradeProvider(ctx, endpoint, talosConfig, func(clusterState *talosClusterUpgradeProvider) error {
+		upgradeLog := &talosClusterUpgradeLog{}
+
+		// Pre-pulling is the only step a dry run still performs; planning must not change the cluster.
+		opts.DryRun = true
+		opts.PrePullImages = false
+		opts.LogOutput = upgradeLog
+
+		defer func() {
+			plan.Log = upgradeLog.lines
+			plan.ManifestChanges = upgradeLog.manifestChanges()
+		}()
+
+		fromVersion, err := k8s.DetectLowestVersion(ctx, clusterState, opts)
+		if err != nil {
+			return err
+		}
+
+		opts.Path, err = goupgrade.NewPath(fromVersion, strings.TrimPrefix(toVersion, "v"))
+		if err != nil {
+			return err
+		}
+
+		plan.FromVersion = "v" + opts.Path.FromVersion()
+		plan.ToVersion = "v" + opts.Path.ToVersion()
+		plan.Path = opts.Path.String()
+		plan.Supported = opts.Path.IsSupported()
+		plan.Images = talosClusterUpgradeImages(opts)
+
+		if !plan.Supported {
+			return nil
+		}
+
+		err = k8s.Upgrade(ctx, clusterState, opts)
+		if errors.As(err, &plan.RemovedItems) {
+			return nil
+		}
+
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	return talosConfig, nil
+	return plan, nil
+}
+
+// talosClusterUpgradeImages lists the component images an upgrade along opts.Path pulls.
+func talosClusterUpgradeImages(opts k8s.UpgradeOptions) []string {
+	images := []string{
+		opts.APIServerImage,
+		opts.ControllerManagerImage,
+		opts.SchedulerImage,
+		opts.ProxyImage,
+	}
+
+	if opts.UpgradeKubelet {
+		images = append(images, opts.KubeletImage)
+	}
+
+	for i, image := range images {
+		images[i] = fmt.Sprintf("%s:v%s", image, opts.Path.ToVersion())
+	}
+
+	return images
+}
+
+// talosClusterDetectKubernetesVersion returns the lowest Kubernetes version running in the cluster, without the "v" prefix.
+func talosClusterDetectKubernetesVersion(ctx context.Context, endpoint string, talosConfig *clientconfig.Config) (string, error) {
+	var version string
+
+	err := withTalosClusterUpgradeProvider(ctx, endpoint, talosConfig, func(clusterState *talosClusterUpgradeProvider) error {
+		opts := talosClusterUpgradeOptions(endpoint, types.StringNull(), nil)
+		opts.LogOutput = io.Discard
+
+		var err error
+
+		version, err = k8s.DetectLowestVersion(ctx, clusterState, opts)
+
+		return err
+	})
+
+	return version, err
+}
+
+// talosClusterEtcdBootstrapped reports whether etcd on node has joined a cluster.
+// The local etcd member resource only exists once etcd has been bootstrapped or joined.
+func talosClusterEtcdBootstrapped(ctx context.Context, endpoint, node string, talosConfig *clientconfig.Config) (bool, error) {
+	var bootstrapped bool
+
+	err := talosClientOp(ctx, endpoint, node, talosConfig, func(nodeCtx context.Context, c *client.Client) error {
+		_, err := safe.StateGet[*etcdresource.Member](nodeCtx, c.COSI, etcdresource.NewMember(etcdresource.NamespaceName, etcdresource.LocalMemberID).Metadata())
+		if err != nil {
+			if cosistate.IsNotFoundError(err) {
+				return nil
+			}
+
+			return err
+		}
+
+		bootstrapped = true
+
+		return nil
+	})
+
+	return bootstrapped, err
+}
+
+// talosClusterEtcdMembers returns the hostnames of the etcd members as seen by node.
+func talosClusterEtcdMembers(ctx context.Context, endpoint, node string, talosConfig *clientconfig.Config) (types.List, error) {
+	var hostnames []attr.Value
+
+	err := talosClientOp(ctx, endpoint, node, talosConfig, func(nodeCtx context.Context, c *client.Client) error {
+		resp, err := c.EtcdMemberList(nodeCtx, &machineapi.EtcdMemberListRequest{})
+		if err != nil {
+			return err
+		}
+
+		for _, msg := range resp.GetMessages() {
+			for _, member := range msg.GetMembers() {
+				hostnames = append(hostnames, types.StringValue(member.GetHostname()))
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return types.ListNull(types.StringType), err
+	}
+
+	return types.ListValueMust(types.StringType, hostnames), nil
+}
+
+// talosClusterImagesChanged reports whether the image settings differ between plan and state.
+func talosClusterImagesChanged(plan, state *talosClusterResourceModel) bool {
+	planOpts := talosClusterUpgradeOptions("", plan.ImageRegistryMirror, plan.Images)
+	stateOpts := talosClusterUpgradeOptions("", state.ImageRegistryMirror, state.Images)
+
+	planComponents := talosClusterImageComponents(&planOpts, nil)
+
+	for i, component := range talosClusterImageComponents(&stateOpts, nil) {
+		if *component.image != *planComponents[i].image {
+			return true
+		}
+	}
+
+	return false
+}
+
+func talosClusterImagesUnknown(images *talosClusterImages) bool {
+	return images.Kubelet.IsUnknown() || images.APIServer.IsUnknown() || images.ControllerManager.IsUnknown() ||
+		images.Scheduler.IsUnknown() || images.Proxy.IsUnknown()
+}
+
+// talosClusterKubernetesVersionsEqual compares two Kubernetes versions ignoring the optional "v" prefix.
+func talosClusterKubernetesVersionsEqual(a, b types.String) bool {
+	if a.IsUnknown() || b.IsUnknown() || a.IsNull() || b.IsNull() {
+		return a.Equal(b)
+	}
+
+	return strings.TrimPrefix(a.ValueString(), "v") == strings.TrimPrefix(b.ValueString(), "v")
+}
+
+// resolveTalosClusterClientConfig builds the Talos client config from the write-only or
+// regular client_configuration attribute. Write-only credentials are never persisted
+// in state; callers must not assign client_configuration from this return value.
+func resolveTalosClusterClientConfig(ctx context.Context, state *talosClusterResourceModel) (*clientconfig.Config, error) {
+	return resolveClientConfiguration(ctx, state.ClientConfigurationWO, state.ClientConfiguration)
 }
 
 // talosClusterEffectiveEndpoint returns the endpoint, defaulting to node.
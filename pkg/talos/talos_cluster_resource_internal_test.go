@@ -0,0 +1,156 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package talos //nolint:testpackage // needs access to unexported upgrade plan helpers
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	goupgrade "github.com/siderolabs/go-kubernetes/kubernetes/upgrade"
+	"github.com/siderolabs/talos/pkg/machinery/constants"
+)
+
+// TestUpgradeLog_ManifestChanges feeds log lines in the shape emitted by a dry-run
+// upgrade and verifies only the SSA diff summary lines are reported as manifest changes.
+func TestUpgradeLog_ManifestChanges(t *testing.T) {
+	t.Parallel()
+
+	upgradeLog := &talosClusterUpgradeLog{}
+
+	for _, line := range [][]any{
+		{"updating %q to version %q", "kube-apiserver", "1.32.0"},
+		{" > %q: starting update", "10.5.0.2"},
+		{" < %q: successfully updated", "10.5.0.2"},
+		{"%s", "updating manifests (dry run)"},
+		{" < %s %s", "create", "ConfigMap kube-system/example"},
+		{"%s", "--- diff ---"},
+		{" < %s %s", "update", "DaemonSet kube-system/kube-proxy"},
+	} {
+		fmt.Fprintf(upgradeLog, line[0].(string), line[1:]...) //nolint:errcheck,forcetypeassert
+	}
+
+	got := upgradeLog.manifestChanges()
+	want := []string{"create ConfigMap kube-system/example", "update DaemonSet kube-system/kube-proxy"}
+
+	if !slices.Equal(got, want) {
+		t.Fatalf("manifestChanges() = %q, want %q", got, want)
+	}
+
+	if len(upgradeLog.lines) != 7 {
+		t.Fatalf("expected 7 log lines, got %d", len(upgradeLog.lines))
+	}
+}
+
+// TestRemovedItemsToTFTypes verifies every kind of removed item is flattened and the
+// output order is stable regardless of map iteration order.
+func TestRemovedItemsToTFTypes(t *testing.T) {
+	t.Parallel()
+
+	removed := goupgrade.ComponentRemovedItemsError{
+		CLIFlags:     []goupgrade.ComponentItem{{Node: "10.5.0.2", Component: "kube-apiserver", Value: "insecure-port"}},
+		FeatureGates: []goupgrade.ComponentItem{{Node: "10.5.0.2", Component: "kubelet", Value: "SomeGate"}},
+		APIResources: map[string]int{
+			"flowschemas.v1beta3.flowcontrol.apiserver.k8s.io": 3,
+			"cronjobs.v1beta1.batch":                           1,
+		},
+	}
+
+	first := removedItemsToTFTypes(removed)
+
+	for range 10 {
+		if !slices.Equal(first, removedItemsToTFTypes(removed)) {
+			t.Fatal("removedItemsToTFTypes output is not stable")
+		}
+	}
+
+	kinds := make([]string, 0, len(first))
+
+	for _, item := range first {
+		kinds = append(kinds, item.Kind.ValueString())
+	}
+
+	if want := []string{"api_resource", "api_resource", "feature_gate", "flag"}; !slices.Equal(kinds, want) {
+		t.Fatalf("kinds = %q, want %q", kinds, want)
+	}
+
+	if first[0].Count.ValueInt64() != 1 || !first[3].Count.IsNull() {
+		t.Fatalf("unexpected counts: %v, %v", first[0].Count, first[3].Count)
+	}
+}
+
+// TestUpgradeOptions_Images verifies the registry mirror replaces the upstream registries
+// and per-component overrides take precedence over it.
+func TestUpgradeOptions_Images(t *testing.T) {
+	t.Parallel()
+
+	opts := talosClusterUpgradeOptions("10.5.0.2", types.StringValue("registry.example.com/k8s/"), &talosClusterImages{
+		Scheduler: types.StringValue("registry.example.com/custom/scheduler"),
+	})
+
+	for name, got := range map[string]string{
+		"kubelet":                 opts.KubeletImage,
+		"kube-apiserver":          opts.APIServerImage,
+		"kube-controller-manager": opts.ControllerManagerImage,
+		"kube-scheduler":          opts.SchedulerImage,
+		"kube-proxy":              opts.ProxyImage,
+	} {
+		want := "registry.example.com/k8s/" + name
+		if name == "kube-scheduler" {
+			want = "registry.example.com/custom/scheduler"
+		}
+
+		if got != want {
+			t.Errorf("%s image = %q, want %q", name, got, want)
+		}
+	}
+
+	defaults := talosClusterUpgradeOptions("10.5.0.2", types.StringNull(), nil)
+	if defaults.APIServerImage != constants.KubernetesAPIServerImage || defaults.KubeletImage != constants.KubeletImage {
+		t.Errorf("unexpected default images: %q, %q", defaults.APIServerImage, defaults.KubeletImage)
+	}
+}
+
+func TestValidateTalosClusterImages(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name    string
+		mirror  types.String
+		images  *talosClusterImages
+		wantErr bool
+	}{
+		{name: "defaults", mirror: types.StringNull()},
+		{name: "mirror", mirror: types.StringValue("registry.example.com:5000/k8s")},
+		{name: "override", mirror: types.StringNull(), images: &talosClusterImages{Proxy: types.StringValue("registry.example.com/kube-proxy")}},
+		{name: "override with tag", mirror: types.StringNull(), images: &talosClusterImages{Proxy: types.StringValue("registry.example.com/kube-proxy:v1.32.0")}, wantErr: true},
+		{name: "invalid mirror", mirror: types.StringValue("Registry Example"), wantErr: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := validateTalosClusterImages(tc.mirror, tc.images)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("validateTalosClusterImages() error = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestTalosClusterImageRepository(t *testing.T) {
+	t.Parallel()
+
+	for image, want := range map[string]string{
+		"registry.k8s.io/kube-apiserver:v1.32.0":                     "registry.k8s.io/kube-apiserver",
+		"registry.example.com:5000/kube-apiserver":                   "registry.example.com:5000/kube-apiserver",
+		"registry.example.com:5000/kube-apiserver:v1.32.0":           "registry.example.com:5000/kube-apiserver",
+		"ghcr.io/siderolabs/kubelet:v1.32.0@sha256:0123456789abcdef": "ghcr.io/siderolabs/kubelet",
+	} {
+		if got := talosClusterImageRepository(image); got != want {
+			t.Errorf("talosClusterImageRepository(%q) = %q, want %q", image, got, want)
+		}
+	}
+}
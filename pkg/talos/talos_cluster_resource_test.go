@@ -41,6 +41,8 @@ func TestAccTalosCluster_bootstrapAndUpgrade(t *testing.T) {
 				Check: resource.ComposeAggregateTestCheckFunc(
 					resource.TestCheckResourceAttrSet("talos_cluster.this", "id"),
 					resource.TestCheckResourceAttr("talos_cluster.this", "kubernetes_version", baseK8sVersion),
+					resource.TestCheckResourceAttr("talos_cluster.this", "etcd_bootstrapped", "true"),
+					resource.TestCheckResourceAttr("talos_cluster.this", "etcd_members.#", "1"),
 				),
 			},
 			// Step 2: idempotency at base version
@@ -53,6 +55,7 @@ func TestAccTalosCluster_bootstrapAndUpgrade(t *testing.T) {
 				Config: testAccTalosClusterConfig(rName, gendata.VersionTag, upgradeK8sVersion),
 				Check: resource.ComposeAggregateTestCheckFunc(
 					resource.TestCheckResourceAttr("talos_cluster.this", "kubernetes_version", upgradeK8sVersion),
+					resource.TestCheckResourceAttr("talos_cluster.this", "running_kubernetes_version", upgradeK8sVersion),
 				),
 			},
 			// Step 4: idempotency after upgrade
@@ -0,0 +1,454 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package talos
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework-timeouts/resource/timeouts"
+	"github.com/hashicorp/terraform-plugin-framework-validators/listvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/booldefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/boolplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/listplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/mapplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-framework/types/basetypes"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/siderolabs/talos/pkg/machinery/client"
+	etcdresource "github.com/siderolabs/talos/pkg/machinery/resources/etcd"
+)
+
+type talosEtcdMaintenanceResource struct{}
+
+var (
+	_ resource.Resource                   = &talosEtcdMaintenanceResource{}
+	_ resource.ResourceWithValidateConfig = &talosEtcdMaintenanceResource{}
+)
+
+type talosEtcdMaintenanceResourceModel struct {
+	ID                    types.String          `tfsdk:"id"`
+	Nodes                 types.List            `tfsdk:"nodes"`
+	Endpoint              types.String          `tfsdk:"endpoint"`
+	ClientConfiguration   basetypes.ObjectValue `tfsdk:"client_configuration"`
+	ClientConfigurationWO basetypes.ObjectValue `tfsdk:"client_configuration_wo"`
+	Defragment            types.Bool            `tfsdk:"defragment"`
+	DisarmAlarms          types.Bool            `tfsdk:"disarm_alarms"`
+	Triggers              types.Map             `tfsdk:"triggers"`
+	Members               types.List            `tfsdk:"members"`
+	Alarms                types.List            `tfsdk:"alarms"`
+	Timeouts              timeouts.Value        `tfsdk:"timeouts"`
+}
+
+type etcdDefragResult struct {
+	ID           types.String `tfsdk:"id"`
+	Hostname     types.String `tfsdk:"hostname"`
+	DBSizeBefore types.Int64  `tfsdk:"db_size_before"`
+	DBSizeAfter  types.Int64  `tfsdk:"db_size_after"`
+	FreedBytes   types.Int64  `tfsdk:"freed_bytes"`
+}
+
+type etcdAlarm struct {
+	ID       types.String `tfsdk:"id"`
+	Hostname types.String `tfsdk:"hostname"`
+	Alarm    types.String `tfsdk:"alarm"`
+}
+
+var (
+	etcdDefragResultAttrTypes = map[string]attr.Type{
+		"id":             types.StringType,
+		"hostname":       types.StringType,
+		"db_size_before": types.Int64Type,
+		"db_size_after":  types.Int64Type,
+		"freed_bytes":    types.Int64Type,
+	}
+
+	etcdAlarmAttrTypes = map[string]attr.Type{
+		"id":       types.StringType,
+		"hostname": types.StringType,
+		"alarm":    types.StringType,
+	}
+)
+
+// NewTalosEtcdMaintenanceResource implements the resource.Resource interface.
+func NewTalosEtcdMaintenanceResource() resource.Resource {
+	return &talosEtcdMaintenanceResource{}
+}
+
+func (r *talosEtcdMaintenanceResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_etcd_maintenance"
+}
+
+func (r *talosEtcdMaintenanceResource) Schema(ctx context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Runs etcd maintenance on a Talos cluster: defragments members one at a time and lists or disarms alarms.",
+		MarkdownDescription: "Runs etcd maintenance on a Talos cluster when created: defragments every member one at a time, the leader last, " +
+			"waiting for all members to respond again before moving on, and lists or disarms alarms such as `NOSPACE`. " +
+			"Change `triggers` to run the maintenance again.",
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed: true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"nodes": schema.ListAttribute{
+				Required:    true,
+				ElementType: types.StringType,
+				Description: "Control plane nodes to read the etcd member list from, tried in order. All members are maintained, not only the listed nodes.",
+				Validators: []validator.List{
+					listvalidator.SizeAtLeast(1),
+				},
+				PlanModifiers: []planmodifier.List{
+					listplanmodifier.RequiresReplace(),
+				},
+			},
+			"endpoint": schema.StringAttribute{
+				Optional:    true,
+				Computed:    true,
+				Description: "The endpoint to use when connecting to the nodes. Defaults to the first node.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"client_configuration": schema.SingleNestedAttribute{
+				Optional:    true,
+				Description: "The Talos client configuration. Use client_configuration_wo when using ephemeral resources.",
+				Attributes: map[string]schema.Attribute{
+					"ca_certificate": schema.StringAttribute{
+						Required:    true,
+						Description: "The client CA certificate.",
+					},
+					"client_certificate": schema.StringAttribute{
+						Required:    true,
+						Description: "The client certificate.",
+					},
+					"client_key": schema.StringAttribute{
+						Required:    true,
+						Sensitive:   true,
+						Description: "The client key.",
+					},
+				},
+			},
+			"client_configuration_wo": schema.SingleNestedAttribute{
+				Optional:    true,
+				WriteOnly:   true,
+				Description: "Write-only variant of client_configuration for use with ephemeral resources. Requires Terraform 1.11+.",
+				Attributes: map[string]schema.Attribute{
+					"ca_certificate": schema.StringAttribute{
+						Required:    true,
+						WriteOnly:   true,
+						Description: "The client CA certificate.",
+					},
+					"client_certificate": schema.StringAttribute{
+						Required:    true,
+						WriteOnly:   true,
+						Description: "The client certificate.",
+					},
+					"client_key": schema.StringAttribute{
+						Required:    true,
+						Sensitive:   true,
+						WriteOnly:   true,
+						Description: "The client key.",
+					},
+				},
+			},
+			"defragment": schema.BoolAttribute{
+				Optional:    true,
+				Computed:    true,
+				Default:     booldefault.StaticBool(true),
+				Description: "Defragment all etcd members. Refuses to start unless every member responds and follows a leader.",
+				PlanModifiers: []planmodifier.Bool{
+					boolplanmodifier.RequiresReplace(),
+				},
+			},
+			"disarm_alarms": schema.BoolAttribute{
+				Optional:    true,
+				Computed:    true,
+				Default:     booldefault.StaticBool(false),
+				Description: "Disarm the active etcd alarms after defragmentation. A `NOSPACE` alarm only clears for good once enough space was freed.",
+				PlanModifiers: []planmodifier.Bool{
+					boolplanmodifier.RequiresReplace(),
+				},
+			},
+			"triggers": schema.MapAttribute{
+				Optional:    true,
+				ElementType: types.StringType,
+				Description: "Arbitrary values that, when changed, run the maintenance again, e.g. a timestamp rotated on a schedule.",
+				PlanModifiers: []planmodifier.Map{
+					mapplanmodifier.RequiresReplace(),
+				},
+			},
+			"members": schema.ListNestedAttribute{
+				Computed:    true,
+				Description: "The defragmented members, in the order they were defragmented.",
+				PlanModifiers: []planmodifier.List{
+					listplanmodifier.UseStateForUnknown(),
+				},
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"id": schema.StringAttribute{
+							Computed:    true,
+							Description: "The member ID in hex.",
+						},
+						"hostname": schema.StringAttribute{
+							Computed:    true,
+							Description: "The member hostname.",
+						},
+						"db_size_before": schema.Int64Attribute{
+							Computed:    true,
+							Description: "The size of the backend database in bytes before defragmentation.",
+						},
+						"db_size_after": schema.Int64Attribute{
+							Computed:    true,
+							Description: "The size of the backend database in bytes after defragmentation.",
+						},
+						"freed_bytes": schema.Int64Attribute{
+							Computed:    true,
+							Description: "The space freed by defragmentation in bytes.",
+						},
+					},
+				},
+			},
+			"alarms": schema.ListNestedAttribute{
+				Computed:    true,
+				Description: "The alarms that were active before the maintenance ran.",
+				PlanModifiers: []planmodifier.List{
+					listplanmodifier.UseStateForUnknown(),
+				},
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"id": schema.StringAttribute{
+							Computed:    true,
+							Description: "The ID of the member that raised the alarm, in hex.",
+						},
+						"hostname": schema.StringAttribute{
+							Computed:    true,
+							Description: "The hostname of the member that raised the alarm.",
+						},
+						"alarm": schema.StringAttribute{
+							Computed:    true,
+							Description: "The alarm type, e.g. `NOSPACE` or `CORRUPT`.",
+						},
+					},
+				},
+			},
+			"timeouts": timeouts.Attributes(ctx, timeouts.Opts{
+				Create: true,
+			}),
+		},
+	}
+}
+
+func (r *talosEtcdMaintenanceResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var cfg talosEtcdMaintenanceResourceModel
+
+	resp.Diagnostics.Append(req.Config.Get(ctx, &cfg)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	clientSet := !cfg.ClientConfiguration.IsNull()
+	clientWOSet := !cfg.ClientConfigurationWO.IsNull()
+
+	if !clientSet && !clientWOSet {
+		resp.Diagnostics.AddError(
+			"Missing client configuration",
+			"Exactly one of client_configuration or client_configuration_wo must be set.",
+		)
+	}
+
+	if clientSet && clientWOSet {
+		resp.Diagnostics.AddError(
+			"Conflicting client configuration",
+			"Only one of client_configuration or client_configuration_wo can be set, not both.",
+		)
+	}
+}
+
+func (r *talosEtcdMaintenanceResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var plan talosEtcdMaintenanceResourceModel
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var cfgModel talosEtcdMaintenanceResourceModel
+
+	resp.Diagnostics.Append(req.Config.Get(ctx, &cfgModel)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	talosConfig, err := resolveClientConfiguration(ctx, cfgModel.ClientConfigurationWO, plan.ClientConfiguration)
+	if err != nil {
+		resp.Diagnostics.AddError("failed to build talos config", err.Error())
+
+		return
+	}
+
+	var nodes []string
+
+	resp.Diagnostics.Append(plan.Nodes.ElementsAs(ctx, &nodes, false)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	timeout, diags := plan.Timeouts.Create(ctx, 30*time.Minute)
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctxDeadline, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	if plan.Endpoint.IsUnknown() || plan.Endpoint.IsNull() {
+		plan.Endpoint = types.StringValue(nodes[0])
+	}
+
+	var (
+		results []etcdDefragResult
+		alarms  []etcdAlarm
+	)
+
+	if err = talosClientOp(ctxDeadline, plan.Endpoint.ValueString(), nodes[0], talosConfig, func(nodeCtx context.Context, c *client.Client) error {
+		members, stateErr := etcdClusterState(nodeCtx, c, nodes)
+		if stateErr != nil {
+			return stateErr
+		}
+
+		alarms = etcdAlarmsToTFTypes(members)
+
+		if plan.Defragment.ValueBool() {
+			if results, stateErr = etcdDefragment(nodeCtx, c, nodes, members); stateErr != nil {
+				return stateErr
+			}
+		}
+
+		if plan.DisarmAlarms.ValueBool() && len(alarms) > 0 {
+			if _, disarmErr := c.EtcdAlarmDisarm(client.WithNode(nodeCtx, nodes[0])); disarmErr != nil {
+				return fmt.Errorf("error disarming etcd alarms: %w", disarmErr)
+			}
+		}
+
+		return nil
+	}); err != nil {
+		resp.Diagnostics.AddError("etcd maintenance failed", err.Error())
+
+		return
+	}
+
+	if !plan.DisarmAlarms.ValueBool() {
+		for _, alarm := range alarms {
+			resp.Diagnostics.AddWarning("etcd alarm is active",
+				fmt.Sprintf("member %s raised %s, set disarm_alarms to clear it", alarm.Hostname.ValueString(), alarm.Alarm.ValueString()))
+		}
+	}
+
+	plan.Members, diags = types.ListValueFrom(ctx, types.ObjectType{AttrTypes: etcdDefragResultAttrTypes}, results)
+	resp.Diagnostics.Append(diags...)
+
+	plan.Alarms, diags = types.ListValueFrom(ctx, types.ObjectType{AttrTypes: etcdAlarmAttrTypes}, alarms)
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	plan.ID = types.StringValue(time.Now().UTC().Format(time.RFC3339))
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+func (r *talosEtcdMaintenanceResource) Read(_ context.Context, _ resource.ReadRequest, _ *resource.ReadResponse) {
+}
+
+func (r *talosEtcdMaintenanceResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var plan talosEtcdMaintenanceResourceModel
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+func (r *talosEtcdMaintenanceResource) Delete(_ context.Context, _ resource.DeleteRequest, _ *resource.DeleteResponse) {
+}
+
+// etcdDefragment defragments the members one at a time in etcdDefragOrder, waiting for every member to
+// respond again before moving on, and reports the database size before and after.
+func etcdDefragment(ctx context.Context, c *client.Client, nodes []string, members []etcdMemberState) ([]etcdDefragResult, error) {
+	for _, m := range members {
+		if !m.responsive() {
+			return nil, fmt.Errorf("refusing to defragment: etcd member %s is not healthy", m.member.GetHostname())
+		}
+	}
+
+	results := make([]etcdDefragResult, 0, len(members))
+
+	for _, m := range etcdDefragOrder(members) {
+		host := etcdMemberHost(m.member)
+		before := m.status.GetDbSize()
+
+		tflog.Info(ctx, "defragmenting etcd member", map[string]any{"member": m.member.GetHostname(), "db_size": before})
+
+		if _, err := c.EtcdDefragment(client.WithNode(ctx, host)); err != nil {
+			return nil, fmt.Errorf("error defragmenting etcd member %s: %w", m.member.GetHostname(), err)
+		}
+
+		after := before
+
+		if err := etcdWaitForClusterResponsive(ctx, c, nodes, 5*time.Minute); err != nil {
+			return nil, fmt.Errorf("etcd did not recover after defragmenting member %s: %w", m.member.GetHostname(), err)
+		}
+
+		if resp, err := c.EtcdStatus(client.WithNode(ctx, host)); err == nil && len(resp.GetMessages()) > 0 {
+			after = resp.GetMessages()[0].GetMemberStatus().GetDbSize()
+		}
+
+		results = append(results, etcdDefragResult{
+			ID:           types.StringValue(etcdresource.FormatMemberID(m.member.GetId())),
+			Hostname:     types.StringValue(m.member.GetHostname()),
+			DBSizeBefore: types.Int64Value(before),
+			DBSizeAfter:  types.Int64Value(after),
+			FreedBytes:   types.Int64Value(before - after),
+		})
+	}
+
+	return results, nil
+}
+
+func etcdAlarmsToTFTypes(members []etcdMemberState) []etcdAlarm {
+	var alarms []etcdAlarm
+
+	for _, m := range members {
+		for _, alarm := range m.alarms {
+			alarms = append(alarms, etcdAlarm{
+				ID:       types.StringValue(etcdresource.FormatMemberID(m.member.GetId())),
+				Hostname: types.StringValue(m.member.GetHostname()),
+				Alarm:    types.StringValue(alarm),
+			})
+		}
+	}
+
+	return alarms
+}
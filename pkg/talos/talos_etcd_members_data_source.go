@@ -0,0 +1,360 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package talos
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework-timeouts/resource/timeouts"
+	"github.com/hashicorp/terraform-plugin-framework-validators/listvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-framework/types/basetypes"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/retry"
+	"github.com/siderolabs/talos/pkg/machinery/client"
+	etcdresource "github.com/siderolabs/talos/pkg/machinery/resources/etcd"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type talosEtcdMembersDataSource struct{}
+
+var _ datasource.DataSource = &talosEtcdMembersDataSource{}
+
+type talosEtcdMembersDataSourceModelV0 struct { //nolint:govet
+	ID                   types.String        `tfsdk:"id"`
+	Endpoint             types.String        `tfsdk:"endpoint"`
+	Nodes                []types.String      `tfsdk:"nodes"`
+	ClientConfiguration  clientConfiguration `tfsdk:"client_configuration"`
+	Members              []etcdMemberModel   `tfsdk:"members"`
+	Leader               types.String        `tfsdk:"leader"`
+	VotingMembers        types.Int64         `tfsdk:"voting_members"`
+	HealthyVotingMembers types.Int64         `tfsdk:"healthy_voting_members"`
+	Quorum               types.Int64         `tfsdk:"quorum"`
+	HasQuorum            types.Bool          `tfsdk:"has_quorum"`
+	Timeouts             timeouts.Value      `tfsdk:"timeouts"`
+}
+
+type etcdMemberModel struct {
+	ID              types.String   `tfsdk:"id"`
+	Hostname        types.String   `tfsdk:"hostname"`
+	PeerURLs        []types.String `tfsdk:"peer_urls"`
+	ClientURLs      []types.String `tfsdk:"client_urls"`
+	IsLearner       types.Bool     `tfsdk:"is_learner"`
+	IsLeader        types.Bool     `tfsdk:"is_leader"`
+	Healthy         types.Bool     `tfsdk:"healthy"`
+	ProtocolVersion types.String   `tfsdk:"protocol_version"`
+	DBSize          types.Int64    `tfsdk:"db_size"`
+	DBSizeInUse     types.Int64    `tfsdk:"db_size_in_use"`
+	RaftIndex       types.Int64    `tfsdk:"raft_index"`
+	RaftTerm        types.Int64    `tfsdk:"raft_term"`
+	Alarms          []types.String `tfsdk:"alarms"`
+	Errors          []types.String `tfsdk:"errors"`
+}
+
+// NewTalosEtcdMembersDataSource implements the datasource.DataSource interface.
+func NewTalosEtcdMembersDataSource() datasource.DataSource {
+	return &talosEtcdMembersDataSource{}
+}
+
+func (d *talosEtcdMembersDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_etcd_members"
+}
+
+func (d *talosEtcdMembersDataSource) Schema(ctx context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Lists the etcd members of a Talos cluster together with the status each member reports",
+		MarkdownDescription: "Lists the etcd members of a Talos cluster together with the status each member reports. " +
+			"The member list is read from the first of `nodes` that answers, then every member is asked for its own status, " +
+			"so a member that is down shows up with `healthy = false` instead of failing the read.",
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed: true,
+			},
+			"endpoint": schema.StringAttribute{
+				Optional:    true,
+				Computed:    true,
+				Description: "endpoint to use for the talosclient. If not set, the first node will be used",
+			},
+			"nodes": schema.ListAttribute{
+				Required:    true,
+				ElementType: types.StringType,
+				Description: "Control plane nodes to read the member list from, tried in order",
+				Validators: []validator.List{
+					listvalidator.SizeAtLeast(1),
+				},
+			},
+			"client_configuration": schema.SingleNestedAttribute{
+				Attributes: map[string]schema.Attribute{
+					"ca_certificate": schema.StringAttribute{
+						Required:    true,
+						Description: "The client CA certificate",
+					},
+					"client_certificate": schema.StringAttribute{
+						Required:    true,
+						Description: "The client certificate",
+					},
+					"client_key": schema.StringAttribute{
+						Required:    true,
+						Sensitive:   true,
+						Description: "The client key",
+					},
+				},
+				Required:    true,
+				Description: "The client configuration data",
+			},
+			"members": schema.ListNestedAttribute{
+				Computed:    true,
+				Description: "The etcd members, sorted by hostname",
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"id": schema.StringAttribute{
+							Computed:    true,
+							Description: "The member ID in hex, as shown by `talosctl etcd members`",
+						},
+						"hostname": schema.StringAttribute{
+							Computed:    true,
+							Description: "The member hostname",
+						},
+						"peer_urls": schema.ListAttribute{
+							Computed:    true,
+							ElementType: types.StringType,
+							Description: "The URLs the member listens on for peer traffic",
+						},
+						"client_urls": schema.ListAttribute{
+							Computed:    true,
+							ElementType: types.StringType,
+							Description: "The URLs the member listens on for client traffic",
+						},
+						"is_learner": schema.BoolAttribute{
+							Computed:    true,
+							Description: "Whether the member is a non-voting learner",
+						},
+						"is_leader": schema.BoolAttribute{
+							Computed:    true,
+							Description: "Whether the member is the raft leader",
+						},
+						"healthy": schema.BoolAttribute{
+							Computed:    true,
+							Description: "Whether the member answered the status request without reporting errors",
+						},
+						"protocol_version": schema.StringAttribute{
+							Computed:    true,
+							Description: "The etcd protocol version of the member",
+						},
+						"db_size": schema.Int64Attribute{
+							Computed:    true,
+							Description: "The size of the backend database in bytes",
+						},
+						"db_size_in_use": schema.Int64Attribute{
+							Computed:    true,
+							Description: "The size of the backend database logically in use in bytes",
+						},
+						"raft_index": schema.Int64Attribute{
+							Computed:    true,
+							Description: "The current raft index of the member",
+						},
+						"raft_term": schema.Int64Attribute{
+							Computed:    true,
+							Description: "The current raft term of the member",
+						},
+						"alarms": schema.ListAttribute{
+							Computed:    true,
+							ElementType: types.StringType,
+							Description: "Active alarms raised by the member, e.g. `NOSPACE`",
+						},
+						"errors": schema.ListAttribute{
+							Computed:    true,
+							ElementType: types.StringType,
+							Description: "Errors reported by the member, or the error reaching it",
+						},
+					},
+				},
+			},
+			"leader": schema.StringAttribute{
+				Computed:    true,
+				Description: "The hostname of the raft leader, empty when no member reports one",
+			},
+			"voting_members": schema.Int64Attribute{
+				Computed:    true,
+				Description: "The number of members that are not learners",
+			},
+			"healthy_voting_members": schema.Int64Attribute{
+				Computed:    true,
+				Description: "The number of healthy members that are not learners",
+			},
+			"quorum": schema.Int64Attribute{
+				Computed:    true,
+				Description: "The number of voting members required for quorum",
+			},
+			"has_quorum": schema.BoolAttribute{
+				Computed:    true,
+				Description: "Whether enough voting members are healthy to keep quorum",
+			},
+			"timeouts": timeouts.Attributes(ctx, timeouts.Opts{
+				Read: true,
+			}),
+		},
+	}
+}
+
+func (d *talosEtcdMembersDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var obj types.Object
+
+	diags := req.Config.Get(ctx, &obj)
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var state talosEtcdMembersDataSourceModelV0
+
+	diags = obj.As(ctx, &state, basetypes.ObjectAsOptions{
+		UnhandledNullAsEmpty:    true,
+		UnhandledUnknownAsEmpty: true,
+	})
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	talosConfig, err := talosClientTFConfigToTalosClientConfig(
+		"dynamic",
+		state.ClientConfiguration.CA.ValueString(),
+		state.ClientConfiguration.Cert.ValueString(),
+		state.ClientConfiguration.Key.ValueString(),
+	)
+	if err != nil {
+		resp.Diagnostics.AddError("failed to generate talos config", err.Error())
+
+		return
+	}
+
+	nodes := make([]string, 0, len(state.Nodes))
+
+	for _, node := range state.Nodes {
+		nodes = append(nodes, node.ValueString())
+	}
+
+	if state.Endpoint.IsNull() {
+		state.Endpoint = types.StringValue(nodes[0])
+	}
+
+	readTimeout, diags := state.Timeouts.Read(ctx, 10*time.Minute)
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctxDeadline, cancel := context.WithTimeout(ctx, readTimeout)
+	defer cancel()
+
+	var members []etcdMemberState
+
+	if err = retry.RetryContext(ctxDeadline, readTimeout, func() *retry.RetryError {
+		if clientOpErr := talosClientOp(ctxDeadline, state.Endpoint.ValueString(), nodes[0], talosConfig, func(nodeCtx context.Context, c *client.Client) error {
+			var stateErr error
+
+			members, stateErr = etcdClusterState(nodeCtx, c, nodes)
+
+			return stateErr
+		}); clientOpErr != nil {
+			if s := status.Code(clientOpErr); s == codes.InvalidArgument {
+				return retry.NonRetryableError(clientOpErr)
+			}
+
+			return retry.RetryableError(clientOpErr)
+		}
+
+		return nil
+	}); err != nil {
+		resp.Diagnostics.AddError("failed to read etcd members", err.Error())
+
+		return
+	}
+
+	state.Members = make([]etcdMemberModel, 0, len(members))
+	state.Leader = types.StringValue("")
+
+	leaders := map[uint64]struct{}{}
+
+	for _, m := range members {
+		if m.status.GetLeader() != 0 {
+			leaders[m.status.GetLeader()] = struct{}{}
+		}
+	}
+
+	for _, m := range members {
+		_, isLeader := leaders[m.member.GetId()]
+
+		model := etcdMemberToTFTypes(m, isLeader)
+
+		if isLeader {
+			state.Leader = model.Hostname
+		}
+
+		state.Members = append(state.Members, model)
+	}
+
+	if len(leaders) > 1 {
+		hostnames := make([]string, 0, len(members))
+
+		for _, m := range members {
+			hostnames = append(hostnames, m.member.GetHostname())
+		}
+
+		resp.Diagnostics.AddWarning("etcd members disagree on the leader",
+			"members "+strings.Join(hostnames, ", ")+" report different raft leaders, the cluster may be electing a new leader")
+	}
+
+	voters, healthyVoters := etcdVoterHealth(members, nil)
+
+	state.VotingMembers = types.Int64Value(int64(voters))
+	state.HealthyVotingMembers = types.Int64Value(int64(healthyVoters))
+	state.Quorum = types.Int64Value(int64(etcdQuorum(voters)))
+	state.HasQuorum = types.BoolValue(voters > 0 && healthyVoters >= etcdQuorum(voters))
+	state.ID = state.Endpoint
+
+	diags = resp.State.Set(ctx, &state)
+	resp.Diagnostics.Append(diags...)
+}
+
+func etcdMemberToTFTypes(m etcdMemberState, isLeader bool) etcdMemberModel {
+	model := etcdMemberModel{
+		ID:              types.StringValue(etcdresource.FormatMemberID(m.member.GetId())),
+		Hostname:        types.StringValue(m.member.GetHostname()),
+		PeerURLs:        stringsToTFTypes(m.member.GetPeerUrls()),
+		ClientURLs:      stringsToTFTypes(m.member.GetClientUrls()),
+		IsLearner:       types.BoolValue(m.member.GetIsLearner()),
+		IsLeader:        types.BoolValue(isLeader),
+		Healthy:         types.BoolValue(m.healthy()),
+		ProtocolVersion: types.StringNull(),
+		DBSize:          types.Int64Null(),
+		DBSizeInUse:     types.Int64Null(),
+		RaftIndex:       types.Int64Null(),
+		RaftTerm:        types.Int64Null(),
+		Alarms:          stringsToTFTypes(m.alarms),
+	}
+
+	if m.status != nil {
+		model.ProtocolVersion = types.StringValue(m.status.GetProtocolVersion())
+		model.DBSize = types.Int64Value(m.status.GetDbSize())
+		model.DBSizeInUse = types.Int64Value(m.status.GetDbSizeInUse())
+		model.RaftIndex = types.Int64Value(int64(m.status.GetRaftIndex()))
+		model.RaftTerm = types.Int64Value(int64(m.status.GetRaftTerm()))
+		model.Errors = stringsToTFTypes(m.status.GetErrors())
+	} else {
+		model.Errors = stringsToTFTypes([]string{m.statusErr.Error()})
+	}
+
+	return model
+}
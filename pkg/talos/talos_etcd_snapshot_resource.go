@@ -0,0 +1,552 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package talos
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"filippo.io/age"
+	"github.com/hashicorp/terraform-plugin-framework-timeouts/resource/timeouts"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/booldefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/boolplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/int64planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/listplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/mapplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-framework/types/basetypes"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/retry"
+	machineapi "github.com/siderolabs/talos/pkg/machinery/api/machine"
+	"github.com/siderolabs/talos/pkg/machinery/client"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type talosEtcdSnapshotResource struct{}
+
+var (
+	_ resource.Resource                   = &talosEtcdSnapshotResource{}
+	_ resource.ResourceWithValidateConfig = &talosEtcdSnapshotResource{}
+)
+
+type talosEtcdSnapshotResourceModel struct {
+	ID                    types.String          `tfsdk:"id"`
+	Node                  types.String          `tfsdk:"node"`
+	Endpoint              types.String          `tfsdk:"endpoint"`
+	ClientConfiguration   basetypes.ObjectValue `tfsdk:"client_configuration"`
+	ClientConfigurationWO basetypes.ObjectValue `tfsdk:"client_configuration_wo"`
+	Path                  types.String          `tfsdk:"path"`
+	Compress              types.Bool            `tfsdk:"compress"`
+	AgeRecipients         types.List            `tfsdk:"age_recipients"`
+	Triggers              types.Map             `tfsdk:"triggers"`
+	Size                  types.Int64           `tfsdk:"size"`
+	SHA256                types.String          `tfsdk:"sha256"`
+	RaftIndex             types.Int64           `tfsdk:"raft_index"`
+	Timestamp             types.String          `tfsdk:"timestamp"`
+	Timeouts              timeouts.Value        `tfsdk:"timeouts"`
+}
+
+// NewTalosEtcdSnapshotResource implements the resource.Resource interface.
+func NewTalosEtcdSnapshotResource() resource.Resource {
+	return &talosEtcdSnapshotResource{}
+}
+
+func (r *talosEtcdSnapshotResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_etcd_snapshot"
+}
+
+func (r *talosEtcdSnapshotResource) Schema(ctx context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Takes an etcd snapshot from a Talos control plane node and writes it to a local file. " +
+			"A missing or modified snapshot file is reported as a warning on refresh; replace the resource to take a new snapshot.",
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed: true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"node": schema.StringAttribute{
+				Required:    true,
+				Description: "The control plane node to take the snapshot from.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"endpoint": schema.StringAttribute{
+				Optional:    true,
+				Computed:    true,
+				Description: "The endpoint to use when connecting to the node. Defaults to node.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"client_configuration": schema.SingleNestedAttribute{
+				Optional:    true,
+				Description: "The Talos client configuration. Use client_configuration_wo when using ephemeral resources.",
+				Attributes: map[string]schema.Attribute{
+					"ca_certificate": schema.StringAttribute{
+						Required:    true,
+						Description: "The client CA certificate.",
+					},
+					"client_certificate": schema.StringAttribute{
+						Required:    true,
+						Description: "The client certificate.",
+					},
+					"client_key": schema.StringAttribute{
+						Required:    true,
+						Sensitive:   true,
+						Description: "The client key.",
+					},
+				},
+			},
+			"client_configuration_wo": schema.SingleNestedAttribute{
+				Optional:    true,
+				WriteOnly:   true,
+				Description: "Write-only variant of client_configuration for use with ephemeral resources. Requires Terraform 1.11+.",
+				Attributes: map[string]schema.Attribute{
+					"ca_certificate": schema.StringAttribute{
+						Required:    true,
+						WriteOnly:   true,
+						Description: "The client CA certificate.",
+					},
+					"client_certificate": schema.StringAttribute{
+						Required:    true,
+						WriteOnly:   true,
+						Description: "The client certificate.",
+					},
+					"client_key": schema.StringAttribute{
+						Required:    true,
+						Sensitive:   true,
+						WriteOnly:   true,
+						Description: "The client key.",
+					},
+				},
+			},
+			"path": schema.StringAttribute{
+				Required:    true,
+				Description: "The local file to write the snapshot to. Missing parent directories are created, and the file is kept when the resource is destroyed.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"compress": schema.BoolAttribute{
+				Optional:    true,
+				Computed:    true,
+				Default:     booldefault.StaticBool(false),
+				Description: "Compress the snapshot with gzip.",
+				PlanModifiers: []planmodifier.Bool{
+					boolplanmodifier.RequiresReplace(),
+				},
+			},
+			"age_recipients": schema.ListAttribute{
+				Optional:    true,
+				ElementType: types.StringType,
+				Description: "Encrypt the snapshot to these age recipients (`age1...`). Compression, if enabled, is applied before encryption.",
+				PlanModifiers: []planmodifier.List{
+					listplanmodifier.RequiresReplace(),
+				},
+			},
+			"triggers": schema.MapAttribute{
+				Optional:    true,
+				ElementType: types.StringType,
+				Description: "Arbitrary values that, when changed, take a new snapshot, e.g. the target Kubernetes version.",
+				PlanModifiers: []planmodifier.Map{
+					mapplanmodifier.RequiresReplace(),
+				},
+			},
+			"size": schema.Int64Attribute{
+				Computed:    true,
+				Description: "The size of the written file in bytes.",
+				PlanModifiers: []planmodifier.Int64{
+					int64planmodifier.UseStateForUnknown(),
+				},
+			},
+			"sha256": schema.StringAttribute{
+				Computed:    true,
+				Description: "SHA256 hex digest of the written file.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"raft_index": schema.Int64Attribute{
+				Computed:    true,
+				Description: "The raft index reported by the node right before the snapshot was taken. Talos does not expose the etcd key revision, so this is the closest ordering marker available.",
+				PlanModifiers: []planmodifier.Int64{
+					int64planmodifier.UseStateForUnknown(),
+				},
+			},
+			"timestamp": schema.StringAttribute{
+				Computed:    true,
+				Description: "The time the snapshot was taken, in RFC3339 format.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"timeouts": timeouts.Attributes(ctx, timeouts.Opts{
+				Create: true,
+			}),
+		},
+	}
+}
+
+func (r *talosEtcdSnapshotResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var cfg talosEtcdSnapshotResourceModel
+
+	resp.Diagnostics.Append(req.Config.Get(ctx, &cfg)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	clientSet := !cfg.ClientConfiguration.IsNull()
+	clientWOSet := !cfg.ClientConfigurationWO.IsNull()
+
+	if !clientSet && !clientWOSet {
+		resp.Diagnostics.AddError(
+			"Missing client configuration",
+			"Exactly one of client_configuration or client_configuration_wo must be set.",
+		)
+	}
+
+	if clientSet && clientWOSet {
+		resp.Diagnostics.AddError(
+			"Conflicting client configuration",
+			"Only one of client_configuration or client_configuration_wo can be set, not both.",
+		)
+	}
+
+	if cfg.AgeRecipients.IsNull() || cfg.AgeRecipients.IsUnknown() {
+		return
+	}
+
+	var recipients []types.String
+
+	resp.Diagnostics.Append(cfg.AgeRecipients.ElementsAs(ctx, &recipients, false)...)
+
+	for i, recipient := range recipients {
+		if recipient.IsNull() || recipient.IsUnknown() {
+			continue
+		}
+
+		if _, err := parseAgeRecipients([]string{recipient.ValueString()}); err != nil {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("age_recipients").AtListIndex(i),
+				"invalid age recipient",
+				err.Error(),
+			)
+		}
+	}
+}
+
+func (r *talosEtcdSnapshotResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var plan talosEtcdSnapshotResourceModel
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var cfgModel talosEtcdSnapshotResourceModel
+
+	resp.Diagnostics.Append(req.Config.Get(ctx, &cfgModel)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	talosConfig, err := resolveClientConfiguration(ctx, cfgModel.ClientConfigurationWO, plan.ClientConfiguration)
+	if err != nil {
+		resp.Diagnostics.AddError("failed to build talos config", err.Error())
+
+		return
+	}
+
+	var recipientValues []string
+
+	if !plan.AgeRecipients.IsNull() {
+		resp.Diagnostics.Append(plan.AgeRecipients.ElementsAs(ctx, &recipientValues, false)...)
+
+		if resp.Diagnostics.HasError() {
+			return
+		}
+	}
+
+	recipients, err := parseAgeRecipients(recipientValues)
+	if err != nil {
+		resp.Diagnostics.AddError("invalid age recipients", err.Error())
+
+		return
+	}
+
+	timeout, diags := plan.Timeouts.Create(ctx, 10*time.Minute)
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctxDeadline, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	if plan.Endpoint.IsUnknown() || plan.Endpoint.IsNull() {
+		plan.Endpoint = plan.Node
+	}
+
+	var snapshot *etcdSnapshotResult
+
+	if err = retry.RetryContext(ctxDeadline, timeout, func() *retry.RetryError {
+		if clientOpErr := talosClientOp(ctxDeadline, plan.Endpoint.ValueString(), plan.Node.ValueString(), talosConfig, func(nodeCtx context.Context, c *client.Client) error {
+			result, snapshotErr := etcdSnapshot(nodeCtx, c, plan.Path.ValueString(), plan.Compress.ValueBool(), recipients)
+			if snapshotErr != nil {
+				return snapshotErr
+			}
+
+			snapshot = result
+
+			return nil
+		}); clientOpErr != nil {
+			if s := status.Code(clientOpErr); s == codes.InvalidArgument || s == codes.PermissionDenied {
+				return retry.NonRetryableError(clientOpErr)
+			}
+
+			return retry.RetryableError(clientOpErr)
+		}
+
+		return nil
+	}); err != nil {
+		resp.Diagnostics.AddError("failed to take etcd snapshot", err.Error())
+
+		return
+	}
+
+	plan.ID = types.StringValue(plan.Path.ValueString())
+	plan.Size = types.Int64Value(snapshot.size)
+	plan.SHA256 = types.StringValue(snapshot.sha256)
+	plan.RaftIndex = types.Int64Value(int64(snapshot.raftIndex))
+	plan.Timestamp = types.StringValue(snapshot.timestamp.Format(time.RFC3339))
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+func (r *talosEtcdSnapshotResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var state talosEtcdSnapshotResourceModel
+
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	sum, err := fileSHA256(state.Path.ValueString())
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			resp.Diagnostics.AddError("failed to read etcd snapshot file", err.Error())
+
+			return
+		}
+
+		// Taking a snapshot again would silently replace the backup with the current state of etcd.
+		resp.Diagnostics.AddAttributeWarning(path.Root("path"), "etcd snapshot file is missing",
+			fmt.Sprintf("The snapshot %s no longer exists. Restore it from a copy, or replace this resource to take a new snapshot.", state.Path.ValueString()))
+	} else if sum != state.SHA256.ValueString() {
+		resp.Diagnostics.AddAttributeWarning(path.Root("path"), "etcd snapshot file was modified",
+			fmt.Sprintf("The sha256 of %s is %s instead of %s. Restore it from a copy, or replace this resource to take a new snapshot.", state.Path.ValueString(), sum, state.SHA256.ValueString()))
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
+}
+
+func (r *talosEtcdSnapshotResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var plan talosEtcdSnapshotResourceModel
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Only endpoint, client_configuration and timeouts can change in place; none of them affect the snapshot.
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+func (r *talosEtcdSnapshotResource) Delete(_ context.Context, _ resource.DeleteRequest, _ *resource.DeleteResponse) {
+	// The snapshot file is a backup, destroying the resource deliberately leaves it on disk.
+}
+
+type etcdSnapshotResult struct {
+	timestamp time.Time
+	sha256    string
+	size      int64
+	raftIndex uint64
+}
+
+// etcdSnapshot streams an etcd snapshot from the node in ctx to dest, optionally gzip-compressed
+// and age-encrypted. The file is written next to dest and renamed into place once complete.
+func etcdSnapshot(ctx context.Context, c *client.Client, dest string, compress bool, recipients []age.Recipient) (*etcdSnapshotResult, error) {
+	statusResp, err := c.EtcdStatus(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error getting etcd status: %w", err)
+	}
+
+	var raftIndex uint64
+
+	if msgs := statusResp.GetMessages(); len(msgs) > 0 {
+		raftIndex = msgs[0].GetMemberStatus().GetRaftIndex()
+	}
+
+	timestamp := time.Now().UTC()
+
+	r, err := c.EtcdSnapshot(ctx, &machineapi.EtcdSnapshotRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("error starting etcd snapshot: %w", err)
+	}
+
+	defer r.Close() //nolint:errcheck
+
+	if err = os.MkdirAll(filepath.Dir(dest), 0o700); err != nil {
+		return nil, err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(dest), "."+filepath.Base(dest)+".*.part")
+	if err != nil {
+		return nil, err
+	}
+
+	defer os.Remove(tmp.Name()) //nolint:errcheck
+
+	sum, size, err := writeEtcdSnapshot(tmp, r, compress, recipients)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("error writing etcd snapshot: %w", err)
+	}
+
+	if err = os.Rename(tmp.Name(), dest); err != nil {
+		return nil, err
+	}
+
+	return &etcdSnapshotResult{
+		timestamp: timestamp,
+		sha256:    sum,
+		size:      size,
+		raftIndex: raftIndex,
+	}, nil
+}
+
+// writeEtcdSnapshot copies the snapshot from r to w through the optional gzip and age stages
+// and returns the digest and size of what ended up in w. An empty snapshot stream is an error,
+// even though the gzip and age stages still write their headers.
+func writeEtcdSnapshot(w io.Writer, r io.Reader, compress bool, recipients []age.Recipient) (string, int64, error) {
+	hasher := sha256.New()
+	counter := &countingWriter{w: io.MultiWriter(w, hasher)}
+
+	var (
+		out     io.Writer = counter
+		closers []io.Closer
+	)
+
+	if len(recipients) > 0 {
+		encrypted, err := age.Encrypt(out, recipients...)
+		if err != nil {
+			return "", 0, err
+		}
+
+		out = encrypted
+		closers = append(closers, encrypted)
+	}
+
+	if compress {
+		gz := gzip.NewWriter(out)
+
+		out = gz
+		closers = append(closers, gz)
+	}
+
+	copied, err := io.Copy(out, r)
+	if err != nil {
+		return "", 0, err
+	}
+
+	if copied == 0 {
+		return "", 0, errors.New("etcd snapshot is empty")
+	}
+
+	// Close the innermost stage first so each one flushes into the next.
+	for i := len(closers) - 1; i >= 0; i-- {
+		if err := closers[i].Close(); err != nil {
+			return "", 0, err
+		}
+	}
+
+	return hex.EncodeToString(hasher.Sum(nil)), counter.n, nil
+}
+
+// parseAgeRecipients parses age recipient strings, ignoring blank entries.
+func parseAgeRecipients(values []string) ([]age.Recipient, error) {
+	var recipients []age.Recipient
+
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+
+		parsed, err := age.ParseRecipients(strings.NewReader(value))
+		if err != nil {
+			return nil, fmt.Errorf("error parsing age recipient %q: %w", value, err)
+		}
+
+		recipients = append(recipients, parsed...)
+	}
+
+	return recipients, nil
+}
+
+func fileSHA256(name string) (string, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return "", err
+	}
+
+	defer f.Close() //nolint:errcheck
+
+	hasher := sha256.New()
+
+	if _, err = io.Copy(hasher, f); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+
+	return n, err
+}
@@ -0,0 +1,112 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package talos //nolint:testpackage // needs access to unexported snapshot writer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+)
+
+func TestWriteEtcdSnapshot(t *testing.T) {
+	t.Parallel()
+
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recipients, err := parseAgeRecipients([]string{"", identity.Recipient().String()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(recipients) != 1 {
+		t.Fatalf("expected blank recipients to be skipped, got %d recipients", len(recipients))
+	}
+
+	payload := strings.Repeat("etcd snapshot data ", 1024)
+
+	for _, tc := range []struct {
+		name       string
+		recipients []age.Recipient
+		compress   bool
+	}{
+		{name: "plain"},
+		{name: "gzip", compress: true},
+		{name: "age", recipients: recipients},
+		{name: "gzip+age", compress: true, recipients: recipients},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+
+			sum, size, err := writeEtcdSnapshot(&buf, strings.NewReader(payload), tc.compress, tc.recipients)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if digest := sha256.Sum256(buf.Bytes()); hex.EncodeToString(digest[:]) != sum {
+				t.Errorf("sha256 = %s, want digest of written bytes", sum)
+			}
+
+			if size != int64(buf.Len()) {
+				t.Errorf("size = %d, want %d", size, buf.Len())
+			}
+
+			var r io.Reader = &buf
+
+			if len(tc.recipients) > 0 {
+				if r, err = age.Decrypt(r, identity); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if tc.compress {
+				if r, err = gzip.NewReader(r); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			out, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(out) != payload {
+				t.Error("decoded snapshot does not match the original")
+			}
+		})
+	}
+}
+
+func TestWriteEtcdSnapshotEmpty(t *testing.T) {
+	t.Parallel()
+
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the gzip and age headers are written even for an empty stream
+	if _, _, err = writeEtcdSnapshot(io.Discard, strings.NewReader(""), true, []age.Recipient{identity.Recipient()}); err == nil {
+		t.Fatal("expected an error for an empty snapshot")
+	}
+}
+
+func TestParseAgeRecipients_Invalid(t *testing.T) {
+	t.Parallel()
+
+	if _, err := parseAgeRecipients([]string{"not-a-recipient"}); err == nil {
+		t.Fatal("expected an error for an invalid recipient")
+	}
+}
@@ -6,19 +6,22 @@ package talos
 
 import (
 	"context"
+	"fmt"
+	"strings"
 	"time"
 
+	"filippo.io/age"
 	"github.com/hashicorp/terraform-plugin-framework-timeouts/resource/timeouts"
 	"github.com/hashicorp/terraform-plugin-framework/attr"
 	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/booldefault"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/types"
 	"github.com/hashicorp/terraform-plugin-framework/types/basetypes"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/retry"
-	machineapi "github.com/siderolabs/talos/pkg/machinery/api/machine"
 	"github.com/siderolabs/talos/pkg/machinery/client"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -46,6 +49,7 @@ type talosMachineBootstrapResourceModelV1 struct {
 	Node                  types.String          `tfsdk:"node"`
 	ClientConfiguration   basetypes.ObjectValue `tfsdk:"client_configuration"`
 	ClientConfigurationWO basetypes.ObjectValue `tfsdk:"client_configuration_wo"`
+	RecoverFromSnapshot   *etcdRecoverOptions   `tfsdk:"recover_from_snapshot"`
 	Timeouts              timeouts.Value        `tfsdk:"timeouts"`
 }
 
@@ -120,6 +124,60 @@ func getBootstrapClientConfiguration(state *talosMachineBootstrapResourceModelV1
 	}), "both client_configuration and client_configuration_wo are null"
 }
 
+// etcdRecoverOptions configures bootstrapping etcd from a snapshot instead of an empty data directory.
+type etcdRecoverOptions struct {
+	Path          types.String `tfsdk:"path"`
+	AgeIdentityWO types.String `tfsdk:"age_identity_wo"`
+	SkipHashCheck types.Bool   `tfsdk:"skip_hash_check"`
+}
+
+func etcdRecoverSchemaAttribute() schema.SingleNestedAttribute {
+	return schema.SingleNestedAttribute{
+		Optional: true,
+		Description: "Bootstrap etcd from a snapshot, e.g. one written by `talos_etcd_snapshot`, instead of starting with an empty cluster. " +
+			"Only used when etcd is bootstrapped, changing it afterwards has no effect.",
+		Attributes: map[string]schema.Attribute{
+			"path": schema.StringAttribute{
+				Required:    true,
+				Description: "The local snapshot file. Gzip-compressed and age-encrypted snapshots are detected automatically.",
+			},
+			"age_identity_wo": schema.StringAttribute{
+				Optional:  true,
+				Sensitive: true,
+				WriteOnly: true,
+				Description: "The age identity (`AGE-SECRET-KEY-1...`) used to decrypt an encrypted snapshot. " +
+					"Defaults to the identities in `SOPS_AGE_KEY` or the file in `SOPS_AGE_KEY_FILE`, as for SOPS. Requires Terraform 1.11+.",
+			},
+			"skip_hash_check": schema.BoolAttribute{
+				Optional:    true,
+				Computed:    true,
+				Default:     booldefault.StaticBool(false),
+				Description: "Skip the snapshot integrity check. Required for snapshots copied from an etcd data directory rather than taken with the snapshot API.",
+			},
+		},
+	}
+}
+
+// ageIdentities returns the identities to decrypt the snapshot with: age_identity_wo, or those in the environment.
+// age_identity_wo is only set when the options were read from the configuration.
+func (o *etcdRecoverOptions) ageIdentities() ([]age.Identity, error) {
+	if o.AgeIdentityWO.ValueString() == "" {
+		identities, err := ageIdentitiesFromEnvironment()
+		if err != nil {
+			return nil, fmt.Errorf("age_identity_wo is not set: %w", err)
+		}
+
+		return identities, nil
+	}
+
+	identities, err := age.ParseIdentities(strings.NewReader(o.AgeIdentityWO.ValueString()))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing age identity: %w", err)
+	}
+
+	return identities, nil
+}
+
 func (r *talosMachineBootstrapResource) Schema(ctx context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
 	resp.Schema = schema.Schema{
 		Version:     1,
@@ -183,6 +241,7 @@ func (r *talosMachineBootstrapResource) Schema(ctx context.Context, _ resource.S
 				WriteOnly:   true,
 				Description: "The client configuration data (write-only). Use this instead of client_configuration when using ephemeral resources. Requires Terraform 1.11+",
 			},
+			"recover_from_snapshot": etcdRecoverSchemaAttribute(),
 			"timeouts": timeouts.Attributes(ctx, timeouts.Opts{
 				Create: true,
 			}),
@@ -215,6 +274,10 @@ func (r *talosMachineBootstrapResource) Create(ctx context.Context, req resource
 		state.ClientConfigurationWO = configState.ClientConfigurationWO
 	}
 
+	if state.RecoverFromSnapshot != nil && configState.RecoverFromSnapshot != nil {
+		state.RecoverFromSnapshot.AgeIdentityWO = configState.RecoverFromSnapshot.AgeIdentityWO
+	}
+
 	clientConfig, configDiag := getBootstrapClientConfiguration(&state)
 	if configDiag != "" {
 		resp.Diagnostics.AddError(
@@ -250,6 +313,18 @@ func (r *talosMachineBootstrapResource) Create(ctx context.Context, req resource
 		return
 	}
 
+	if state.RecoverFromSnapshot != nil {
+		if err := etcdCheckSnapshot(state.RecoverFromSnapshot); err != nil {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("recover_from_snapshot"),
+				"Error reading etcd snapshot",
+				err.Error(),
+			)
+
+			return
+		}
+	}
+
 	createTimeout, diags := state.Timeouts.Create(ctx, 10*time.Minute)
 	resp.Diagnostics.Append(diags...)
 
@@ -268,7 +343,19 @@ func (r *talosMachineBootstrapResource) Create(ctx context.Context, req resource
 
 		defer c.Close() //nolint:errcheck
 
-		if err := c.Bootstrap(client.WithNode(ctxDeadline, state.Node.ValueString()), &machineapi.BootstrapRequest{}); err != nil {
+		nodeCtx := client.WithNode(ctxDeadline, state.Node.ValueString())
+
+		if state.RecoverFromSnapshot != nil {
+			if err := etcdUploadSnapshot(nodeCtx, c, state.RecoverFromSnapshot); err != nil {
+				if status.Code(err) == codes.InvalidArgument {
+					return retry.NonRetryableError(err)
+				}
+
+				return retry.RetryableError(err)
+			}
+		}
+
+		if err := c.Bootstrap(nodeCtx, etcdBootstrapRequest(state.RecoverFromSnapshot)); err != nil {
 			if s := status.Code(err); s == codes.InvalidArgument {
 				return retry.NonRetryableError(err)
 			}
@@ -286,6 +373,19 @@ func (r *talosMachineBootstrapResource) Create(ctx context.Context, req resource
 		return
 	}
 
+	if state.RecoverFromSnapshot != nil {
+		if err := talosClientOp(ctxDeadline, state.Endpoint.ValueString(), state.Node.ValueString(), talosClientConfig, func(nodeCtx context.Context, c *client.Client) error {
+			return etcdWaitForHealthy(nodeCtx, c, createTimeout)
+		}); err != nil {
+			resp.Diagnostics.AddError(
+				"Error waiting for etcd to become healthy after recovery",
+				err.Error(),
+			)
+
+			return
+		}
+	}
+
 	state.ID = basetypes.NewStringValue("machine_bootstrap")
 
 	// Set state to fully populated data
@@ -0,0 +1,339 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package talos
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/cosi-project/runtime/pkg/safe"
+	"github.com/hashicorp/terraform-plugin-framework-timeouts/resource/timeouts"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-framework/types/basetypes"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/retry"
+	imagefactory "github.com/siderolabs/image-factory/pkg/client"
+	"github.com/siderolabs/talos/pkg/machinery/client"
+	"github.com/siderolabs/talos/pkg/machinery/resources/runtime"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// schematicExtensionName is the name of the pseudo-extension Image Factory adds to every image,
+// its version is the ID of the schematic the image was built from.
+const schematicExtensionName = "schematic"
+
+type talosMachineExtensionsDataSource struct {
+	imageFactoryClient *imagefactory.Client
+}
+
+var (
+	_ datasource.DataSource              = &talosMachineExtensionsDataSource{}
+	_ datasource.DataSourceWithConfigure = &talosMachineExtensionsDataSource{}
+)
+
+type talosMachineExtensionsDataSourceModelV0 struct { //nolint:govet
+	ID                  types.String            `tfsdk:"id"`
+	Node                types.String            `tfsdk:"node"`
+	Endpoint            types.String            `tfsdk:"endpoint"`
+	ClientConfiguration clientConfiguration     `tfsdk:"client_configuration"`
+	SchematicID         types.String            `tfsdk:"schematic_id"`
+	Extensions          []machineExtensionModel `tfsdk:"extensions"`
+	CurrentSchematicID  types.String            `tfsdk:"current_schematic_id"`
+	SchematicMatches    types.Bool              `tfsdk:"schematic_matches"`
+	ExpectedExtensions  []types.String          `tfsdk:"expected_extensions"`
+	MissingExtensions   []types.String          `tfsdk:"missing_extensions"`
+	Timeouts            timeouts.Value          `tfsdk:"timeouts"`
+}
+
+type machineExtensionModel struct {
+	Name        types.String `tfsdk:"name"`
+	Version     types.String `tfsdk:"version"`
+	Author      types.String `tfsdk:"author"`
+	Description types.String `tfsdk:"description"`
+	Image       types.String `tfsdk:"image"`
+}
+
+// NewTalosMachineExtensionsDataSource implements the datasource.DataSource interface.
+func NewTalosMachineExtensionsDataSource() datasource.DataSource {
+	return &talosMachineExtensionsDataSource{}
+}
+
+func (d *talosMachineExtensionsDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_machine_extensions"
+}
+
+func (d *talosMachineExtensionsDataSource) Schema(ctx context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Lists the system extensions installed on a Talos node",
+		MarkdownDescription: "Lists the system extensions installed on a Talos node. " +
+			"When `schematic_id` is set, the extensions of that Image Factory schematic are compared to the installed ones, " +
+			"so a `postcondition` on `missing_extensions` or `schematic_matches` can catch nodes that didn't pick up the expected image.",
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed: true,
+			},
+			"node": schema.StringAttribute{
+				Required:    true,
+				Description: "node to list the extensions of",
+			},
+			"endpoint": schema.StringAttribute{
+				Optional:    true,
+				Computed:    true,
+				Description: "endpoint to use for the talosclient. If not set, the node value will be used",
+			},
+			"client_configuration": schema.SingleNestedAttribute{
+				Attributes: map[string]schema.Attribute{
+					"ca_certificate": schema.StringAttribute{
+						Required:    true,
+						Description: "The client CA certificate",
+					},
+					"client_certificate": schema.StringAttribute{
+						Required:    true,
+						Description: "The client certificate",
+					},
+					"client_key": schema.StringAttribute{
+						Required:    true,
+						Sensitive:   true,
+						Description: "The client key",
+					},
+				},
+				Required:    true,
+				Description: "The client configuration data",
+			},
+			"schematic_id": schema.StringAttribute{
+				Optional:    true,
+				Description: "The Image Factory schematic ID the node is expected to run, e.g. `talos_image_factory_schematic.this.id`",
+			},
+			"extensions": schema.ListNestedAttribute{
+				Computed:    true,
+				Description: "The installed extensions, sorted by name",
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"name": schema.StringAttribute{
+							Computed:    true,
+							Description: "The extension name",
+						},
+						"version": schema.StringAttribute{
+							Computed:    true,
+							Description: "The extension version",
+						},
+						"author": schema.StringAttribute{
+							Computed:    true,
+							Description: "The extension author",
+						},
+						"description": schema.StringAttribute{
+							Computed:    true,
+							Description: "The extension description",
+						},
+						"image": schema.StringAttribute{
+							Computed:    true,
+							Description: "The extension image the extension was installed from",
+						},
+					},
+				},
+			},
+			"current_schematic_id": schema.StringAttribute{
+				Computed:    true,
+				Description: "The schematic ID of the image the node booted from. Null if the image wasn't built by Image Factory",
+			},
+			"schematic_matches": schema.BoolAttribute{
+				Computed:    true,
+				Description: "Whether `current_schematic_id` equals `schematic_id`. Null if `schematic_id` is not set",
+			},
+			"expected_extensions": schema.ListAttribute{
+				ElementType: types.StringType,
+				Computed:    true,
+				Description: "The names of the extensions in the `schematic_id` schematic. Null if `schematic_id` is not set",
+			},
+			"missing_extensions": schema.ListAttribute{
+				ElementType: types.StringType,
+				Computed:    true,
+				Description: "The names of the extensions in the `schematic_id` schematic that are not installed on the node. Null if `schematic_id` is not set",
+			},
+			"timeouts": timeouts.Attributes(ctx, timeouts.Opts{
+				Read: true,
+			}),
+		},
+	}
+}
+
+func (d *talosMachineExtensionsDataSource) Configure(_ context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	imageFactoryClient, ok := req.ProviderData.(*imagefactory.Client)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"failed to get image factory client",
+			fmt.Sprintf("Expected *client.Client, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	d.imageFactoryClient = imageFactoryClient
+}
+
+func (d *talosMachineExtensionsDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var obj types.Object
+
+	diags := req.Config.Get(ctx, &obj)
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var state talosMachineExtensionsDataSourceModelV0
+
+	diags = obj.As(ctx, &state, basetypes.ObjectAsOptions{
+		UnhandledNullAsEmpty:    true,
+		UnhandledUnknownAsEmpty: true,
+	})
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	talosConfig, err := talosClientTFConfigToTalosClientConfig(
+		"dynamic",
+		state.ClientConfiguration.CA.ValueString(),
+		state.ClientConfiguration.Cert.ValueString(),
+		state.ClientConfiguration.Key.ValueString(),
+	)
+	if err != nil {
+		resp.Diagnostics.AddError("failed to generate talos config", err.Error())
+
+		return
+	}
+
+	if state.Endpoint.IsNull() {
+		state.Endpoint = state.Node
+	}
+
+	readTimeout, diags := state.Timeouts.Read(ctx, 10*time.Minute)
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctxDeadline, cancel := context.WithTimeout(ctx, readTimeout)
+	defer cancel()
+
+	var installed []*runtime.ExtensionStatus
+
+	if err = retry.RetryContext(ctxDeadline, readTimeout, func() *retry.RetryError {
+		if clientOpErr := talosClientOp(ctxDeadline, state.Endpoint.ValueString(), state.Node.ValueString(), talosConfig, func(nodeCtx context.Context, c *client.Client) error {
+			list, listErr := safe.StateListAll[*runtime.ExtensionStatus](nodeCtx, c.COSI)
+			if listErr != nil {
+				return listErr
+			}
+
+			installed = slices.Collect(list.All())
+
+			return nil
+		}); clientOpErr != nil {
+			if s := status.Code(clientOpErr); s == codes.InvalidArgument || s == codes.PermissionDenied {
+				return retry.NonRetryableError(clientOpErr)
+			}
+
+			return retry.RetryableError(clientOpErr)
+		}
+
+		return nil
+	}); err != nil {
+		resp.Diagnostics.AddError("failed to list machine extensions", err.Error())
+
+		return
+	}
+
+	state.Extensions = make([]machineExtensionModel, 0, len(installed))
+	state.CurrentSchematicID = types.StringNull()
+
+	installedNames := make([]string, 0, len(installed))
+
+	for _, ext := range installed {
+		spec := ext.TypedSpec()
+
+		if spec.Metadata.Name == schematicExtensionName {
+			state.CurrentSchematicID = types.StringValue(spec.Metadata.Version)
+		}
+
+		installedNames = append(installedNames, spec.Metadata.Name)
+
+		state.Extensions = append(state.Extensions, machineExtensionModel{
+			Name:        types.StringValue(spec.Metadata.Name),
+			Version:     types.StringValue(spec.Metadata.Version),
+			Author:      types.StringValue(spec.Metadata.Author),
+			Description: types.StringValue(spec.Metadata.Description),
+			Image:       types.StringValue(spec.Image),
+		})
+	}
+
+	slices.SortFunc(state.Extensions, func(a, b machineExtensionModel) int {
+		return strings.Compare(a.Name.ValueString(), b.Name.ValueString())
+	})
+
+	state.SchematicMatches = types.BoolNull()
+	state.ExpectedExtensions = nil
+	state.MissingExtensions = nil
+
+	if schematicID := state.SchematicID.ValueString(); schematicID != "" {
+		if d.imageFactoryClient == nil {
+			resp.Diagnostics.AddError("image factory client is not configured", "Please report this issue to the provider developers.")
+
+			return
+		}
+
+		schematic, schematicErr := d.imageFactoryClient.SchematicGet(ctx, schematicID)
+		if schematicErr != nil {
+			resp.Diagnostics.AddError("failed to get schematic", schematicErr.Error())
+
+			return
+		}
+
+		expected, missing := compareExtensions(schematic.Customization.SystemExtensions.OfficialExtensions, installedNames)
+
+		state.SchematicMatches = types.BoolValue(state.CurrentSchematicID.ValueString() == schematicID)
+		state.ExpectedExtensions = stringsToTFTypes(expected)
+		state.MissingExtensions = stringsToTFTypes(missing)
+	}
+
+	state.ID = state.Node
+
+	diags = resp.State.Set(ctx, &state)
+	resp.Diagnostics.Append(diags...)
+}
+
+// compareExtensions returns the short names of the schematic extensions (`siderolabs/iscsi-tools` becomes `iscsi-tools`)
+// and the ones among them that are not installed.
+func compareExtensions(schematicExtensions, installed []string) (expected, missing []string) {
+	expected = make([]string, 0, len(schematicExtensions))
+	missing = []string{}
+
+	for _, ext := range schematicExtensions {
+		name := path.Base(ext)
+
+		expected = append(expected, name)
+
+		if !slices.Contains(installed, name) {
+			missing = append(missing, name)
+		}
+	}
+
+	slices.Sort(expected)
+	slices.Sort(missing)
+
+	return expected, missing
+}
@@ -0,0 +1,31 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package talos //nolint:testpackage // needs access to unexported compareExtensions
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestCompareExtensions(t *testing.T) {
+	t.Parallel()
+
+	expected, missing := compareExtensions(
+		[]string{"siderolabs/util-linux-tools", "siderolabs/iscsi-tools", "siderolabs/qemu-guest-agent"},
+		[]string{"schematic", "iscsi-tools", "util-linux-tools"},
+	)
+
+	if want := []string{"iscsi-tools", "qemu-guest-agent", "util-linux-tools"}; !slices.Equal(expected, want) {
+		t.Errorf("expected = %q, want %q", expected, want)
+	}
+
+	if want := []string{"qemu-guest-agent"}; !slices.Equal(missing, want) {
+		t.Errorf("missing = %q, want %q", missing, want)
+	}
+
+	if _, missing = compareExtensions(nil, []string{"schematic"}); missing == nil || len(missing) != 0 {
+		t.Errorf("expected an empty, non-nil missing list, got %#v", missing)
+	}
+}
@@ -0,0 +1,384 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package talos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/cosi-project/runtime/pkg/safe"
+	cosistate "github.com/cosi-project/runtime/pkg/state"
+	"github.com/hashicorp/terraform-plugin-framework-timeouts/resource/timeouts"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-framework/types/basetypes"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/retry"
+	"github.com/siderolabs/talos/pkg/machinery/client"
+	"github.com/siderolabs/talos/pkg/machinery/resources/cluster"
+	"github.com/siderolabs/talos/pkg/machinery/resources/hardware"
+	"github.com/siderolabs/talos/pkg/machinery/resources/network"
+	"github.com/siderolabs/talos/pkg/machinery/resources/runtime"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+type talosMachineInfoDataSource struct{}
+
+var _ datasource.DataSource = &talosMachineInfoDataSource{}
+
+type talosMachineInfoDataSourceModelV0 struct { //nolint:govet
+	ID                  types.String        `tfsdk:"id"`
+	Node                types.String        `tfsdk:"node"`
+	Endpoint            types.String        `tfsdk:"endpoint"`
+	ClientConfiguration clientConfiguration `tfsdk:"client_configuration"`
+	TalosVersion        types.String        `tfsdk:"talos_version"`
+	TalosSHA            types.String        `tfsdk:"talos_sha"`
+	Arch                types.String        `tfsdk:"arch"`
+	Platform            types.String        `tfsdk:"platform"`
+	PlatformMode        types.String        `tfsdk:"platform_mode"`
+	NodeID              types.String        `tfsdk:"node_id"`
+	MachineID           types.String        `tfsdk:"machine_id"`
+	Hostname            types.String        `tfsdk:"hostname"`
+	Domainname          types.String        `tfsdk:"domainname"`
+	Manufacturer        types.String        `tfsdk:"manufacturer"`
+	ProductName         types.String        `tfsdk:"product_name"`
+	UUID                types.String        `tfsdk:"uuid"`
+	SerialNumber        types.String        `tfsdk:"serial_number"`
+	CPUCount            types.Int64         `tfsdk:"cpu_count"`
+	CPUModel            types.String        `tfsdk:"cpu_model"`
+	MemoryTotal         types.Int64         `tfsdk:"memory_total"`
+	SecureBoot          types.Bool          `tfsdk:"secure_boot"`
+	BootedWithUKI       types.Bool          `tfsdk:"booted_with_uki"`
+	TPMVersion          types.String        `tfsdk:"tpm_version"`
+	Timeouts            timeouts.Value      `tfsdk:"timeouts"`
+}
+
+// NewTalosMachineInfoDataSource implements the datasource.DataSource interface.
+func NewTalosMachineInfoDataSource() datasource.DataSource {
+	return &talosMachineInfoDataSource{}
+}
+
+func (d *talosMachineInfoDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_machine_info"
+}
+
+func (d *talosMachineInfoDataSource) Schema(ctx context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Reads the Talos version, identity and hardware information of a node",
+		MarkdownDescription: "Reads the Talos version, identity and hardware information of a node. " +
+			"Values the node does not provide, e.g. SMBIOS data on some virtual or ARM machines, are null.",
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed: true,
+			},
+			"node": schema.StringAttribute{
+				Required:    true,
+				Description: "node to read the information from",
+			},
+			"endpoint": schema.StringAttribute{
+				Optional:    true,
+				Computed:    true,
+				Description: "endpoint to use for the talosclient. If not set, the node value will be used",
+			},
+			"client_configuration": schema.SingleNestedAttribute{
+				Attributes: map[string]schema.Attribute{
+					"ca_certificate": schema.StringAttribute{
+						Required:    true,
+						Description: "The client CA certificate",
+					},
+					"client_certificate": schema.StringAttribute{
+						Required:    true,
+						Description: "The client certificate",
+					},
+					"client_key": schema.StringAttribute{
+						Required:    true,
+						Sensitive:   true,
+						Description: "The client key",
+					},
+				},
+				Required:    true,
+				Description: "The client configuration data",
+			},
+			"talos_version": schema.StringAttribute{
+				Computed:    true,
+				Description: "The Talos version tag, e.g. `v1.9.0`",
+			},
+			"talos_sha": schema.StringAttribute{
+				Computed:    true,
+				Description: "The git SHA Talos was built from",
+			},
+			"arch": schema.StringAttribute{
+				Computed:    true,
+				Description: "The node architecture, e.g. `amd64`",
+			},
+			"platform": schema.StringAttribute{
+				Computed:    true,
+				Description: "The platform Talos runs on, e.g. `metal` or `aws`",
+			},
+			"platform_mode": schema.StringAttribute{
+				Computed:    true,
+				Description: "The platform mode, e.g. `metal`, `cloud` or `container`",
+			},
+			"node_id": schema.StringAttribute{
+				Computed:    true,
+				Description: "The node identity used by cluster discovery. It survives reboots but changes when the node is wiped",
+			},
+			"machine_id": schema.StringAttribute{
+				Computed:    true,
+				Description: "The contents of /etc/machine-id",
+			},
+			"hostname": schema.StringAttribute{
+				Computed:    true,
+				Description: "The current hostname",
+			},
+			"domainname": schema.StringAttribute{
+				Computed:    true,
+				Description: "The current domain name",
+			},
+			"manufacturer": schema.StringAttribute{
+				Computed:    true,
+				Description: "The SMBIOS system manufacturer",
+			},
+			"product_name": schema.StringAttribute{
+				Computed:    true,
+				Description: "The SMBIOS system product name",
+			},
+			"uuid": schema.StringAttribute{
+				Computed:    true,
+				Description: "The SMBIOS system UUID",
+			},
+			"serial_number": schema.StringAttribute{
+				Computed:    true,
+				Description: "The SMBIOS system serial number",
+			},
+			"cpu_count": schema.Int64Attribute{
+				Computed:    true,
+				Description: "The number of logical CPUs",
+			},
+			"cpu_model": schema.StringAttribute{
+				Computed:    true,
+				Description: "The CPU model name",
+			},
+			"memory_total": schema.Int64Attribute{
+				Computed:    true,
+				Description: "The total memory in bytes",
+			},
+			"secure_boot": schema.BoolAttribute{
+				Computed:    true,
+				Description: "Whether the node booted with SecureBoot enabled",
+			},
+			"booted_with_uki": schema.BoolAttribute{
+				Computed:    true,
+				Description: "Whether the node booted from a Unified Kernel Image",
+			},
+			"tpm_version": schema.StringAttribute{
+				Computed:    true,
+				Description: "The major version of the TPM, e.g. `2`. Null when the node has no TPM",
+			},
+			"timeouts": timeouts.Attributes(ctx, timeouts.Opts{
+				Read: true,
+			}),
+		},
+	}
+}
+
+func (d *talosMachineInfoDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var obj types.Object
+
+	diags := req.Config.Get(ctx, &obj)
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var state talosMachineInfoDataSourceModelV0
+
+	diags = obj.As(ctx, &state, basetypes.ObjectAsOptions{
+		UnhandledNullAsEmpty:    true,
+		UnhandledUnknownAsEmpty: true,
+	})
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	talosConfig, err := talosClientTFConfigToTalosClientConfig(
+		"dynamic",
+		state.ClientConfiguration.CA.ValueString(),
+		state.ClientConfiguration.Cert.ValueString(),
+		state.ClientConfiguration.Key.ValueString(),
+	)
+	if err != nil {
+		resp.Diagnostics.AddError("failed to generate talos config", err.Error())
+
+		return
+	}
+
+	if state.Endpoint.IsNull() {
+		state.Endpoint = state.Node
+	}
+
+	readTimeout, diags := state.Timeouts.Read(ctx, 10*time.Minute)
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctxDeadline, cancel := context.WithTimeout(ctx, readTimeout)
+	defer cancel()
+
+	if err = retry.RetryContext(ctxDeadline, readTimeout, func() *retry.RetryError {
+		if clientOpErr := talosClientOp(ctxDeadline, state.Endpoint.ValueString(), state.Node.ValueString(), talosConfig, func(nodeCtx context.Context, c *client.Client) error {
+			return readMachineInfo(nodeCtx, c, &state)
+		}); clientOpErr != nil {
+			if s := status.Code(clientOpErr); s == codes.InvalidArgument || s == codes.PermissionDenied {
+				return retry.NonRetryableError(clientOpErr)
+			}
+
+			return retry.RetryableError(clientOpErr)
+		}
+
+		return nil
+	}); err != nil {
+		resp.Diagnostics.AddError("failed to read machine info", err.Error())
+
+		return
+	}
+
+	state.ID = state.Node
+
+	diags = resp.State.Set(ctx, &state)
+	resp.Diagnostics.Append(diags...)
+}
+
+// readMachineInfo fills the computed attributes of state. The version and hostname are required,
+// everything else is left null when the node doesn't expose it.
+func readMachineInfo(ctx context.Context, c *client.Client, state *talosMachineInfoDataSourceModelV0) error {
+	version, err := c.Version(ctx)
+	if err != nil {
+		return fmt.Errorf("error getting Talos version: %w", err)
+	}
+
+	if len(version.GetMessages()) == 0 {
+		return errors.New("empty version response")
+	}
+
+	msg := version.GetMessages()[0]
+
+	state.TalosVersion = types.StringValue(msg.GetVersion().GetTag())
+	state.TalosSHA = types.StringValue(msg.GetVersion().GetSha())
+	state.Arch = types.StringValue(msg.GetVersion().GetArch())
+	state.Platform = types.StringValue(msg.GetPlatform().GetName())
+	state.PlatformMode = types.StringValue(msg.GetPlatform().GetMode())
+
+	hostname, err := safe.StateGetByID[*network.HostnameStatus](ctx, c.COSI, network.HostnameID)
+	if err != nil {
+		return fmt.Errorf("error getting hostname: %w", err)
+	}
+
+	state.Hostname = types.StringValue(hostname.TypedSpec().Hostname)
+	state.Domainname = types.StringValue(hostname.TypedSpec().Domainname)
+
+	state.NodeID = types.StringNull()
+
+	if identity, identityErr := safe.StateGetByID[*cluster.Identity](ctx, c.COSI, cluster.LocalIdentity); identityErr == nil {
+		state.NodeID = types.StringValue(identity.TypedSpec().NodeID)
+	} else if !cosistate.IsNotFoundError(identityErr) {
+		return fmt.Errorf("error getting node identity: %w", identityErr)
+	}
+
+	state.Manufacturer = types.StringNull()
+	state.ProductName = types.StringNull()
+	state.UUID = types.StringNull()
+	state.SerialNumber = types.StringNull()
+
+	if sysInfo, sysInfoErr := safe.StateGetByID[*hardware.SystemInformation](ctx, c.COSI, hardware.SystemInformationID); sysInfoErr == nil {
+		state.Manufacturer = types.StringValue(sysInfo.TypedSpec().Manufacturer)
+		state.ProductName = types.StringValue(sysInfo.TypedSpec().ProductName)
+		state.UUID = types.StringValue(sysInfo.TypedSpec().UUID)
+		state.SerialNumber = types.StringValue(sysInfo.TypedSpec().SerialNumber)
+	} else if !cosistate.IsNotFoundError(sysInfoErr) {
+		return fmt.Errorf("error getting system information: %w", sysInfoErr)
+	}
+
+	state.SecureBoot = types.BoolNull()
+	state.BootedWithUKI = types.BoolNull()
+
+	if security, securityErr := safe.StateGetByID[*runtime.SecurityState](ctx, c.COSI, runtime.SecurityStateID); securityErr == nil {
+		state.SecureBoot = types.BoolValue(security.TypedSpec().SecureBoot)
+		state.BootedWithUKI = types.BoolValue(security.TypedSpec().BootedWithUKI)
+	} else if !cosistate.IsNotFoundError(securityErr) {
+		return fmt.Errorf("error getting security state: %w", securityErr)
+	}
+
+	memory, err := c.Memory(ctx)
+	if err != nil {
+		return fmt.Errorf("error getting memory info: %w", err)
+	}
+
+	state.MemoryTotal = types.Int64Null()
+
+	if msgs := memory.GetMessages(); len(msgs) > 0 {
+		// /proc/meminfo reports kibibytes
+		state.MemoryTotal = types.Int64Value(int64(msgs[0].GetMeminfo().GetMemtotal()) * 1024)
+	}
+
+	cpus, err := c.MachineClient.CPUInfo(ctx, &emptypb.Empty{})
+	if err != nil {
+		return fmt.Errorf("error getting CPU info: %w", err)
+	}
+
+	state.CPUCount = types.Int64Null()
+	state.CPUModel = types.StringNull()
+
+	if msgs := cpus.GetMessages(); len(msgs) > 0 {
+		cpuInfo := msgs[0].GetCpuInfo()
+
+		state.CPUCount = types.Int64Value(int64(len(cpuInfo)))
+
+		if len(cpuInfo) > 0 {
+			state.CPUModel = types.StringValue(cpuInfo[0].GetModelName())
+		}
+	}
+
+	// Both files are optional: containers have no TPM, and /etc/machine-id may not be readable with every role.
+	state.MachineID = readMachineFile(ctx, c, "/etc/machine-id")
+	state.TPMVersion = readMachineFile(ctx, c, "/sys/class/tpm/tpm0/tpm_version_major")
+
+	return nil
+}
+
+// readMachineFile returns the trimmed contents of a small file on the node, or null if it can't be read.
+func readMachineFile(ctx context.Context, c *client.Client, path string) types.String {
+	r, err := c.Read(ctx, path)
+	if err != nil {
+		tflog.Debug(ctx, "failed to read file", map[string]any{"path": path, "error": err.Error()})
+
+		return types.StringNull()
+	}
+
+	defer r.Close() //nolint:errcheck
+
+	data, err := io.ReadAll(io.LimitReader(r, 4096))
+	if err != nil || len(data) == 0 {
+		if err != nil {
+			tflog.Debug(ctx, "failed to read file", map[string]any{"path": path, "error": err.Error()})
+		}
+
+		return types.StringNull()
+	}
+
+	return types.StringValue(strings.TrimSpace(string(data)))
+}
@@ -0,0 +1,748 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package talos
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"net/netip"
+	"slices"
+	"time"
+
+	"github.com/cosi-project/runtime/pkg/safe"
+	"github.com/hashicorp/terraform-plugin-framework-timeouts/resource/timeouts"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-framework/types/basetypes"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/retry"
+	networkpb "github.com/siderolabs/talos/pkg/machinery/api/resource/definitions/network"
+	"github.com/siderolabs/talos/pkg/machinery/cel"
+	"github.com/siderolabs/talos/pkg/machinery/cel/celenv"
+	"github.com/siderolabs/talos/pkg/machinery/client"
+	"github.com/siderolabs/talos/pkg/machinery/nethelpers"
+	"github.com/siderolabs/talos/pkg/machinery/proto"
+	"github.com/siderolabs/talos/pkg/machinery/resources/kubespan"
+	"github.com/siderolabs/talos/pkg/machinery/resources/network"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type talosMachineNetworkDataSource struct{}
+
+var _ datasource.DataSource = &talosMachineNetworkDataSource{}
+
+type talosMachineNetworkDataSourceModelV0 struct { //nolint:govet
+	ID                  types.String          `tfsdk:"id"`
+	Node                types.String          `tfsdk:"node"`
+	Endpoint            types.String          `tfsdk:"endpoint"`
+	ClientConfiguration clientConfiguration   `tfsdk:"client_configuration"`
+	Links               []types.String        `tfsdk:"links"`
+	LinkSelector        types.String          `tfsdk:"link_selector"`
+	AddressFamily       types.String          `tfsdk:"address_family"`
+	AddressScope        types.String          `tfsdk:"address_scope"`
+	Hostname            types.String          `tfsdk:"hostname"`
+	Domainname          types.String          `tfsdk:"domainname"`
+	Resolvers           []types.String        `tfsdk:"resolvers"`
+	SearchDomains       []types.String        `tfsdk:"search_domains"`
+	LinkStatuses        []networkLinkModel    `tfsdk:"link_statuses"`
+	Addresses           []networkAddressModel `tfsdk:"addresses"`
+	Routes              []networkRouteModel   `tfsdk:"routes"`
+	KubeSpanPeers       []kubespanPeerModel   `tfsdk:"kubespan_peers"`
+	Timeouts            timeouts.Value        `tfsdk:"timeouts"`
+}
+
+type networkLinkModel struct {
+	Name             types.String   `tfsdk:"name"`
+	Alias            types.String   `tfsdk:"alias"`
+	AltNames         []types.String `tfsdk:"alt_names"`
+	Index            types.Int64    `tfsdk:"index"`
+	Type             types.String   `tfsdk:"type"`
+	Kind             types.String   `tfsdk:"kind"`
+	Master           types.String   `tfsdk:"master"`
+	HardwareAddr     types.String   `tfsdk:"hardware_addr"`
+	PermanentAddr    types.String   `tfsdk:"permanent_addr"`
+	MTU              types.Int64    `tfsdk:"mtu"`
+	OperationalState types.String   `tfsdk:"operational_state"`
+	LinkState        types.Bool     `tfsdk:"link_state"`
+	SpeedMbit        types.Int64    `tfsdk:"speed_mbit"`
+	Driver           types.String   `tfsdk:"driver"`
+}
+
+type networkAddressModel struct {
+	Link         types.String `tfsdk:"link"`
+	Address      types.String `tfsdk:"address"`
+	IP           types.String `tfsdk:"ip"`
+	PrefixLength types.Int64  `tfsdk:"prefix_length"`
+	Family       types.String `tfsdk:"family"`
+	Scope        types.String `tfsdk:"scope"`
+}
+
+type networkRouteModel struct {
+	Link        types.String `tfsdk:"link"`
+	Destination types.String `tfsdk:"destination"`
+	Gateway     types.String `tfsdk:"gateway"`
+	Source      types.String `tfsdk:"source"`
+	Family      types.String `tfsdk:"family"`
+	Scope       types.String `tfsdk:"scope"`
+	Table       types.String `tfsdk:"table"`
+	Priority    types.Int64  `tfsdk:"priority"`
+	Protocol    types.String `tfsdk:"protocol"`
+}
+
+type kubespanPeerModel struct {
+	PublicKey         types.String `tfsdk:"public_key"`
+	Label             types.String `tfsdk:"label"`
+	Endpoint          types.String `tfsdk:"endpoint"`
+	State             types.String `tfsdk:"state"`
+	ReceiveBytes      types.Int64  `tfsdk:"receive_bytes"`
+	TransmitBytes     types.Int64  `tfsdk:"transmit_bytes"`
+	LastHandshakeTime types.String `tfsdk:"last_handshake_time"`
+}
+
+// machineNetworkFilter narrows down the links, and the addresses and routes on them.
+type machineNetworkFilter struct {
+	names    []string
+	selector *cel.Expression
+	family   nethelpers.Family
+	scope    *nethelpers.Scope
+}
+
+// NewTalosMachineNetworkDataSource implements the datasource.DataSource interface.
+func NewTalosMachineNetworkDataSource() datasource.DataSource {
+	return &talosMachineNetworkDataSource{}
+}
+
+func (d *talosMachineNetworkDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_machine_network"
+}
+
+func (d *talosMachineNetworkDataSource) Schema(ctx context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Reads the network state of a Talos node: links, addresses, routes, resolvers, hostname and KubeSpan peers",
+		MarkdownDescription: "Reads the network state of a Talos node: links, addresses, routes, resolvers, hostname and KubeSpan peers. " +
+			"The link filters also apply to `addresses` and `routes`, so only addresses and routes on the matching links are returned.",
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed: true,
+			},
+			"node": schema.StringAttribute{
+				Required:    true,
+				Description: "node to read the network state from",
+			},
+			"endpoint": schema.StringAttribute{
+				Optional:    true,
+				Computed:    true,
+				Description: "endpoint to use for the talosclient. If not set, the node value will be used",
+			},
+			"client_configuration": schema.SingleNestedAttribute{
+				Attributes: map[string]schema.Attribute{
+					"ca_certificate": schema.StringAttribute{
+						Required:    true,
+						Description: "The client CA certificate",
+					},
+					"client_certificate": schema.StringAttribute{
+						Required:    true,
+						Description: "The client certificate",
+					},
+					"client_key": schema.StringAttribute{
+						Required:    true,
+						Sensitive:   true,
+						Description: "The client key",
+					},
+				},
+				Required:    true,
+				Description: "The client configuration data",
+			},
+			"links": schema.ListAttribute{
+				ElementType: types.StringType,
+				Optional:    true,
+				Description: "Only return links with one of these names or alternative names",
+			},
+			"link_selector": schema.StringAttribute{
+				Optional: true,
+				MarkdownDescription: `The CEL expression to filter the links, e.g. ` + "`link.driver == \"virtio_net\"`" + ` or ` +
+					"`glob(\"00:1a:2b:*\", mac(link.permanent_addr))`" + `.
+If not set, all links will be returned.
+See [CEL documentation](https://www.talos.dev/latest/talos-guides/network/device-selector/).`,
+			},
+			"address_family": schema.StringAttribute{
+				Optional:    true,
+				Description: "Only return addresses and routes of this family, `inet4` or `inet6`",
+				Validators: []validator.String{
+					stringvalidator.OneOf(nethelpers.FamilyInet4.String(), nethelpers.FamilyInet6.String()),
+				},
+			},
+			"address_scope": schema.StringAttribute{
+				Optional:    true,
+				Description: "Only return addresses and routes of this scope, e.g. `global` or `link`",
+				Validators: []validator.String{
+					stringvalidator.OneOf(nethelpers.ScopeStrings()...),
+				},
+			},
+			"hostname": schema.StringAttribute{
+				Computed:    true,
+				Description: "The current hostname",
+			},
+			"domainname": schema.StringAttribute{
+				Computed:    true,
+				Description: "The current domain name",
+			},
+			"resolvers": schema.ListAttribute{
+				ElementType: types.StringType,
+				Computed:    true,
+				Description: "The DNS servers in use",
+			},
+			"search_domains": schema.ListAttribute{
+				ElementType: types.StringType,
+				Computed:    true,
+				Description: "The DNS search domains in use",
+			},
+			"link_statuses": schema.ListNestedAttribute{
+				Computed:    true,
+				Description: "The links that match the filters, sorted by name",
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"name": schema.StringAttribute{
+							Computed:    true,
+							Description: "The link name",
+						},
+						"alias": schema.StringAttribute{
+							Computed:    true,
+							Description: "The link alias",
+						},
+						"alt_names": schema.ListAttribute{
+							ElementType: types.StringType,
+							Computed:    true,
+							Description: "The alternative link names",
+						},
+						"index": schema.Int64Attribute{
+							Computed:    true,
+							Description: "The link index",
+						},
+						"type": schema.StringAttribute{
+							Computed:    true,
+							Description: "The link type, e.g. `ether` or `loopback`",
+						},
+						"kind": schema.StringAttribute{
+							Computed:    true,
+							Description: "The link kind, e.g. `bond`, `vlan` or `wireguard`. Empty for physical links",
+						},
+						"master": schema.StringAttribute{
+							Computed:    true,
+							Description: "The name of the bond or bridge this link is enslaved to",
+						},
+						"hardware_addr": schema.StringAttribute{
+							Computed:    true,
+							Description: "The current MAC address",
+						},
+						"permanent_addr": schema.StringAttribute{
+							Computed:    true,
+							Description: "The permanent MAC address",
+						},
+						"mtu": schema.Int64Attribute{
+							Computed:    true,
+							Description: "The link MTU",
+						},
+						"operational_state": schema.StringAttribute{
+							Computed:    true,
+							Description: "The operational state, e.g. `up` or `down`",
+						},
+						"link_state": schema.BoolAttribute{
+							Computed:    true,
+							Description: "Whether the link has a carrier",
+						},
+						"speed_mbit": schema.Int64Attribute{
+							Computed:    true,
+							Description: "The link speed in Mbit/s",
+						},
+						"driver": schema.StringAttribute{
+							Computed:    true,
+							Description: "The kernel driver of the link",
+						},
+					},
+				},
+			},
+			"addresses": schema.ListNestedAttribute{
+				Computed:    true,
+				Description: "The addresses on the matching links, sorted by link and address",
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"link": schema.StringAttribute{
+							Computed:    true,
+							Description: "The link the address is assigned to",
+						},
+						"address": schema.StringAttribute{
+							Computed:    true,
+							Description: "The address in CIDR notation",
+						},
+						"ip": schema.StringAttribute{
+							Computed:    true,
+							Description: "The address without the prefix length",
+						},
+						"prefix_length": schema.Int64Attribute{
+							Computed:    true,
+							Description: "The prefix length",
+						},
+						"family": schema.StringAttribute{
+							Computed:    true,
+							Description: "The address family, `inet4` or `inet6`",
+						},
+						"scope": schema.StringAttribute{
+							Computed:    true,
+							Description: "The address scope",
+						},
+					},
+				},
+			},
+			"routes": schema.ListNestedAttribute{
+				Computed:    true,
+				Description: "The routes via the matching links, sorted by table, destination and priority",
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"link": schema.StringAttribute{
+							Computed:    true,
+							Description: "The outgoing link",
+						},
+						"destination": schema.StringAttribute{
+							Computed:    true,
+							Description: "The destination in CIDR notation. Null for the default route",
+						},
+						"gateway": schema.StringAttribute{
+							Computed:    true,
+							Description: "The gateway address",
+						},
+						"source": schema.StringAttribute{
+							Computed:    true,
+							Description: "The preferred source address",
+						},
+						"family": schema.StringAttribute{
+							Computed:    true,
+							Description: "The route family, `inet4` or `inet6`",
+						},
+						"scope": schema.StringAttribute{
+							Computed:    true,
+							Description: "The route scope",
+						},
+						"table": schema.StringAttribute{
+							Computed:    true,
+							Description: "The routing table",
+						},
+						"priority": schema.Int64Attribute{
+							Computed:    true,
+							Description: "The route priority (metric)",
+						},
+						"protocol": schema.StringAttribute{
+							Computed:    true,
+							Description: "The protocol that installed the route",
+						},
+					},
+				},
+			},
+			"kubespan_peers": schema.ListNestedAttribute{
+				Computed:    true,
+				Description: "The KubeSpan peers as seen by the node. Empty if KubeSpan is disabled",
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"public_key": schema.StringAttribute{
+							Computed:    true,
+							Description: "The WireGuard public key of the peer",
+						},
+						"label": schema.StringAttribute{
+							Computed:    true,
+							Description: "The peer label, usually its hostname",
+						},
+						"endpoint": schema.StringAttribute{
+							Computed:    true,
+							Description: "The active endpoint of the peer",
+						},
+						"state": schema.StringAttribute{
+							Computed:    true,
+							Description: "The peer state, e.g. `up` or `down`",
+						},
+						"receive_bytes": schema.Int64Attribute{
+							Computed:    true,
+							Description: "The number of bytes received from the peer",
+						},
+						"transmit_bytes": schema.Int64Attribute{
+							Computed:    true,
+							Description: "The number of bytes sent to the peer",
+						},
+						"last_handshake_time": schema.StringAttribute{
+							Computed:    true,
+							Description: "The time of the last handshake in RFC3339 format",
+						},
+					},
+				},
+			},
+			"timeouts": timeouts.Attributes(ctx, timeouts.Opts{
+				Read: true,
+			}),
+		},
+	}
+}
+
+func (d *talosMachineNetworkDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var obj types.Object
+
+	diags := req.Config.Get(ctx, &obj)
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var state talosMachineNetworkDataSourceModelV0
+
+	diags = obj.As(ctx, &state, basetypes.ObjectAsOptions{
+		UnhandledNullAsEmpty:    true,
+		UnhandledUnknownAsEmpty: true,
+	})
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	talosConfig, err := talosClientTFConfigToTalosClientConfig(
+		"dynamic",
+		state.ClientConfiguration.CA.ValueString(),
+		state.ClientConfiguration.Cert.ValueString(),
+		state.ClientConfiguration.Key.ValueString(),
+	)
+	if err != nil {
+		resp.Diagnostics.AddError("failed to generate talos config", err.Error())
+
+		return
+	}
+
+	var filter machineNetworkFilter
+
+	for _, name := range state.Links {
+		filter.names = append(filter.names, name.ValueString())
+	}
+
+	if selector := state.LinkSelector.ValueString(); selector != "" {
+		exp, parseErr := cel.ParseBooleanExpression(selector, celenv.LinkLocator())
+		if parseErr != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("link_selector"), "failed to parse celenv selector", parseErr.Error())
+
+			return
+		}
+
+		filter.selector = &exp
+	}
+
+	if family := state.AddressFamily.ValueString(); family != "" {
+		if filter.family, err = nethelpers.FamilyString(family); err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("address_family"), "invalid address family", err.Error())
+
+			return
+		}
+	}
+
+	if scope := state.AddressScope.ValueString(); scope != "" {
+		parsed, scopeErr := nethelpers.ScopeString(scope)
+		if scopeErr != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("address_scope"), "invalid address scope", scopeErr.Error())
+
+			return
+		}
+
+		filter.scope = &parsed
+	}
+
+	if state.Endpoint.IsNull() {
+		state.Endpoint = state.Node
+	}
+
+	readTimeout, diags := state.Timeouts.Read(ctx, 10*time.Minute)
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctxDeadline, cancel := context.WithTimeout(ctx, readTimeout)
+	defer cancel()
+
+	if err = retry.RetryContext(ctxDeadline, readTimeout, func() *retry.RetryError {
+		if clientOpErr := talosClientOp(ctxDeadline, state.Endpoint.ValueString(), state.Node.ValueString(), talosConfig, func(nodeCtx context.Context, c *client.Client) error {
+			return readMachineNetwork(nodeCtx, c, filter, &state)
+		}); clientOpErr != nil {
+			if s := status.Code(clientOpErr); s == codes.InvalidArgument || s == codes.PermissionDenied {
+				return retry.NonRetryableError(clientOpErr)
+			}
+
+			return retry.RetryableError(clientOpErr)
+		}
+
+		return nil
+	}); err != nil {
+		resp.Diagnostics.AddError("failed to read machine network", err.Error())
+
+		return
+	}
+
+	state.ID = state.Node
+
+	diags = resp.State.Set(ctx, &state)
+	resp.Diagnostics.Append(diags...)
+}
+
+// readMachineNetwork fills the computed attributes of state from the network and KubeSpan resources of the node.
+func readMachineNetwork(ctx context.Context, c *client.Client, filter machineNetworkFilter, state *talosMachineNetworkDataSourceModelV0) error { //nolint:gocognit,gocyclo,cyclop
+	hostname, err := safe.StateGetByID[*network.HostnameStatus](ctx, c.COSI, network.HostnameID)
+	if err != nil {
+		return fmt.Errorf("error getting hostname: %w", err)
+	}
+
+	state.Hostname = types.StringValue(hostname.TypedSpec().Hostname)
+	state.Domainname = types.StringValue(hostname.TypedSpec().Domainname)
+
+	resolvers, err := safe.StateGetByID[*network.ResolverStatus](ctx, c.COSI, network.ResolverID)
+	if err != nil {
+		return fmt.Errorf("error getting resolvers: %w", err)
+	}
+
+	state.Resolvers = make([]types.String, 0, len(resolvers.TypedSpec().DNSServers))
+
+	for _, server := range resolvers.TypedSpec().DNSServers {
+		state.Resolvers = append(state.Resolvers, types.StringValue(server.String()))
+	}
+
+	state.SearchDomains = make([]types.String, 0, len(resolvers.TypedSpec().SearchDomains))
+
+	for _, domain := range resolvers.TypedSpec().SearchDomains {
+		state.SearchDomains = append(state.SearchDomains, types.StringValue(domain))
+	}
+
+	links, err := safe.StateListAll[*network.LinkStatus](ctx, c.COSI)
+	if err != nil {
+		return fmt.Errorf("error listing links: %w", err)
+	}
+
+	linkNames := make(map[uint32]string, links.Len())
+
+	for link := range links.All() {
+		linkNames[link.TypedSpec().Index] = link.Metadata().ID()
+	}
+
+	matchedLinks := map[string]struct{}{}
+	state.LinkStatuses = []networkLinkModel{}
+
+	for link := range links.All() {
+		matches, matchErr := filter.matchLink(link)
+		if matchErr != nil {
+			return matchErr
+		}
+
+		if !matches {
+			continue
+		}
+
+		matchedLinks[link.Metadata().ID()] = struct{}{}
+		state.LinkStatuses = append(state.LinkStatuses, networkLinkToTFTypes(link, linkNames))
+	}
+
+	addresses, err := safe.StateListAll[*network.AddressStatus](ctx, c.COSI)
+	if err != nil {
+		return fmt.Errorf("error listing addresses: %w", err)
+	}
+
+	state.Addresses = []networkAddressModel{}
+
+	for address := range addresses.All() {
+		spec := address.TypedSpec()
+
+		if _, ok := matchedLinks[spec.LinkName]; !ok || !filter.matchFamilyScope(spec.Family, spec.Scope) {
+			continue
+		}
+
+		state.Addresses = append(state.Addresses, networkAddressModel{
+			Link:         types.StringValue(spec.LinkName),
+			Address:      types.StringValue(spec.Address.String()),
+			IP:           types.StringValue(spec.Address.Addr().String()),
+			PrefixLength: types.Int64Value(int64(spec.Address.Bits())),
+			Family:       types.StringValue(spec.Family.String()),
+			Scope:        types.StringValue(spec.Scope.String()),
+		})
+	}
+
+	routes, err := safe.StateListAll[*network.RouteStatus](ctx, c.COSI)
+	if err != nil {
+		return fmt.Errorf("error listing routes: %w", err)
+	}
+
+	state.Routes = []networkRouteModel{}
+
+	for route := range routes.All() {
+		spec := route.TypedSpec()
+
+		if _, ok := matchedLinks[spec.OutLinkName]; !ok && (spec.OutLinkName != "" || filter.filtersLinks()) {
+			continue
+		}
+
+		if !filter.matchFamilyScope(spec.Family, spec.Scope) {
+			continue
+		}
+
+		state.Routes = append(state.Routes, networkRouteModel{
+			Link:        stringValueOrNull(spec.OutLinkName),
+			Destination: prefixValueOrNull(spec.Destination),
+			Gateway:     addrValueOrNull(spec.Gateway),
+			Source:      addrValueOrNull(spec.Source),
+			Family:      types.StringValue(spec.Family.String()),
+			Scope:       types.StringValue(spec.Scope.String()),
+			Table:       types.StringValue(spec.Table.String()),
+			Priority:    types.Int64Value(int64(spec.Priority)),
+			Protocol:    types.StringValue(spec.Protocol.String()),
+		})
+	}
+
+	peers, err := safe.StateListAll[*kubespan.PeerStatus](ctx, c.COSI)
+	if err != nil {
+		return fmt.Errorf("error listing KubeSpan peers: %w", err)
+	}
+
+	state.KubeSpanPeers = []kubespanPeerModel{}
+
+	for peer := range peers.All() {
+		spec := peer.TypedSpec()
+
+		lastHandshake := types.StringNull()
+		if !spec.LastHandshakeTime.IsZero() {
+			lastHandshake = types.StringValue(spec.LastHandshakeTime.UTC().Format(time.RFC3339))
+		}
+
+		endpoint := types.StringNull()
+		if spec.Endpoint.IsValid() {
+			endpoint = types.StringValue(spec.Endpoint.String())
+		}
+
+		state.KubeSpanPeers = append(state.KubeSpanPeers, kubespanPeerModel{
+			PublicKey:         types.StringValue(peer.Metadata().ID()),
+			Label:             types.StringValue(spec.Label),
+			Endpoint:          endpoint,
+			State:             types.StringValue(spec.State.String()),
+			ReceiveBytes:      types.Int64Value(spec.ReceiveBytes),
+			TransmitBytes:     types.Int64Value(spec.TransmitBytes),
+			LastHandshakeTime: lastHandshake,
+		})
+	}
+
+	// links and peers are listed sorted by ID already, addresses and routes have opaque IDs
+	slices.SortStableFunc(state.Addresses, func(a, b networkAddressModel) int {
+		return cmp.Or(
+			cmp.Compare(a.Link.ValueString(), b.Link.ValueString()),
+			cmp.Compare(a.Address.ValueString(), b.Address.ValueString()),
+		)
+	})
+
+	slices.SortStableFunc(state.Routes, func(a, b networkRouteModel) int {
+		return cmp.Or(
+			cmp.Compare(a.Table.ValueString(), b.Table.ValueString()),
+			cmp.Compare(a.Destination.ValueString(), b.Destination.ValueString()),
+			cmp.Compare(a.Priority.ValueInt64(), b.Priority.ValueInt64()),
+		)
+	})
+
+	return nil
+}
+
+func (f machineNetworkFilter) filtersLinks() bool {
+	return len(f.names) > 0 || f.selector != nil
+}
+
+func (f machineNetworkFilter) matchLink(link *network.LinkStatus) (bool, error) {
+	if len(f.names) > 0 {
+		names := append([]string{link.Metadata().ID()}, link.TypedSpec().AltNames...)
+
+		if !slices.ContainsFunc(names, func(name string) bool { return slices.Contains(f.names, name) }) {
+			return false, nil
+		}
+	}
+
+	if f.selector == nil {
+		return true, nil
+	}
+
+	spec := &networkpb.LinkStatusSpec{}
+
+	if err := proto.ResourceSpecToProto(link, spec); err != nil {
+		return false, err
+	}
+
+	return f.selector.EvalBool(celenv.LinkLocator(), map[string]any{
+		"link": spec,
+	})
+}
+
+func (f machineNetworkFilter) matchFamilyScope(family nethelpers.Family, scope nethelpers.Scope) bool {
+	if f.family != 0 && family != f.family {
+		return false
+	}
+
+	return f.scope == nil || scope == *f.scope
+}
+
+func networkLinkToTFTypes(link *network.LinkStatus, linkNames map[uint32]string) networkLinkModel {
+	spec := link.TypedSpec()
+
+	altNames := make([]types.String, 0, len(spec.AltNames))
+	for _, name := range spec.AltNames {
+		altNames = append(altNames, types.StringValue(name))
+	}
+
+	master := types.StringNull()
+	if spec.MasterIndex != 0 {
+		master = stringValueOrNull(linkNames[spec.MasterIndex])
+	}
+
+	return networkLinkModel{
+		Name:             types.StringValue(link.Metadata().ID()),
+		Alias:            stringValueOrNull(spec.Alias),
+		AltNames:         altNames,
+		Index:            types.Int64Value(int64(spec.Index)),
+		Type:             types.StringValue(spec.Type.String()),
+		Kind:             types.StringValue(spec.Kind),
+		Master:           master,
+		HardwareAddr:     types.StringValue(spec.HardwareAddr.String()),
+		PermanentAddr:    stringValueOrNull(spec.PermanentAddr.String()),
+		MTU:              types.Int64Value(int64(spec.MTU)),
+		OperationalState: types.StringValue(spec.OperationalState.String()),
+		LinkState:        types.BoolValue(spec.LinkState),
+		SpeedMbit:        types.Int64Value(int64(spec.SpeedMegabits)),
+		Driver:           stringValueOrNull(spec.Driver),
+	}
+}
+
+func stringValueOrNull(s string) types.String {
+	if s == "" {
+		return types.StringNull()
+	}
+
+	return types.StringValue(s)
+}
+
+func addrValueOrNull(addr netip.Addr) types.String {
+	if !addr.IsValid() || addr.IsUnspecified() {
+		return types.StringNull()
+	}
+
+	return types.StringValue(addr.String())
+}
+
+func prefixValueOrNull(prefix netip.Prefix) types.String {
+	if !prefix.IsValid() || prefix.Bits() == 0 {
+		return types.StringNull()
+	}
+
+	return types.StringValue(prefix.String())
+}
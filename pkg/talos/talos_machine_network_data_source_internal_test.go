@@ -0,0 +1,78 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package talos //nolint:testpackage // needs access to unexported machineNetworkFilter
+
+import (
+	"testing"
+
+	"github.com/siderolabs/talos/pkg/machinery/cel"
+	"github.com/siderolabs/talos/pkg/machinery/cel/celenv"
+	"github.com/siderolabs/talos/pkg/machinery/nethelpers"
+	"github.com/siderolabs/talos/pkg/machinery/resources/network"
+)
+
+func TestMachineNetworkFilterMatchLink(t *testing.T) {
+	t.Parallel()
+
+	eth0 := network.NewLinkStatus(network.NamespaceName, "eth0")
+	eth0.TypedSpec().AltNames = []string{"enp0s3"}
+	eth0.TypedSpec().Driver = "virtio_net"
+
+	lo := network.NewLinkStatus(network.NamespaceName, "lo")
+
+	byDriver := cel.MustExpression(cel.ParseBooleanExpression(`link.driver == "virtio_net"`, celenv.LinkLocator()))
+
+	for _, tc := range []struct {
+		name   string
+		filter machineNetworkFilter
+		eth0   bool
+		lo     bool
+	}{
+		{name: "no filter", eth0: true, lo: true},
+		{name: "by name", filter: machineNetworkFilter{names: []string{"lo"}}, lo: true},
+		{name: "by alt name", filter: machineNetworkFilter{names: []string{"enp0s3"}}, eth0: true},
+		{name: "by selector", filter: machineNetworkFilter{selector: &byDriver}, eth0: true},
+		{name: "by name and selector", filter: machineNetworkFilter{names: []string{"lo"}, selector: &byDriver}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			for link, want := range map[*network.LinkStatus]bool{eth0: tc.eth0, lo: tc.lo} {
+				got, err := tc.filter.matchLink(link)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if got != want {
+					t.Errorf("matchLink(%s) = %v, want %v", link.Metadata().ID(), got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestMachineNetworkFilterMatchFamilyScope(t *testing.T) {
+	t.Parallel()
+
+	global := nethelpers.ScopeGlobal
+
+	filter := machineNetworkFilter{family: nethelpers.FamilyInet6, scope: &global}
+
+	if !filter.matchFamilyScope(nethelpers.FamilyInet6, nethelpers.ScopeGlobal) {
+		t.Error("expected a global inet6 address to match")
+	}
+
+	if filter.matchFamilyScope(nethelpers.FamilyInet4, nethelpers.ScopeGlobal) {
+		t.Error("expected an inet4 address not to match")
+	}
+
+	if filter.matchFamilyScope(nethelpers.FamilyInet6, nethelpers.ScopeLink) {
+		t.Error("expected a link-local address not to match")
+	}
+
+	if !(machineNetworkFilter{}).matchFamilyScope(nethelpers.FamilyInet4, nethelpers.ScopeHost) {
+		t.Error("expected an empty filter to match everything")
+	}
+}
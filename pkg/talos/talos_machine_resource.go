@@ -9,11 +9,13 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"slices"
 	"strings"
 	"time"
 
 	cosiresource "github.com/cosi-project/runtime/pkg/resource"
 	"github.com/cosi-project/runtime/pkg/safe"
+	cosistate "github.com/cosi-project/runtime/pkg/state"
 	"github.com/hashicorp/terraform-plugin-framework-timeouts/resource/timeouts"
 	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
 	"github.com/hashicorp/terraform-plugin-framework/path"
@@ -36,6 +38,7 @@ import (
 	"github.com/siderolabs/talos/pkg/machinery/client"
 	clientconfig "github.com/siderolabs/talos/pkg/machinery/client/config"
 	configresource "github.com/siderolabs/talos/pkg/machinery/resources/config"
+	etcdresource "github.com/siderolabs/talos/pkg/machinery/resources/etcd"
 	talosreporter "github.com/siderolabs/talos/pkg/reporter"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -231,10 +234,12 @@ func (r *talosMachineResource) Schema(ctx context.Context, _ resource.SchemaRequ
 				Optional:            true,
 				Attributes: map[string]schema.Attribute{
 					"reset": schema.BoolAttribute{
-						Description: "Reset the machine to the initial state (STATE and EPHEMERAL will be wiped).",
-						Optional:    true,
-						Computed:    true,
-						Default:     booldefault.StaticBool(false),
+						Description: "Reset the machine to the initial state (STATE and EPHEMERAL will be wiped). " +
+							"On control plane nodes the etcd member is removed first, unless it is the last one; " +
+							"the reset waits, up to the delete timeout, while the remaining etcd members would lose quorum.",
+						Optional: true,
+						Computed: true,
+						Default:  booldefault.StaticBool(false),
 					},
 					"graceful": schema.BoolAttribute{
 						Description: "Graceful indicates whether node should leave etcd before the reset.",
@@ -727,6 +732,15 @@ func (r *talosMachineResource) Delete(ctx context.Context, req resource.DeleteRe
 		return
 	}
 
+	leaveCtx, cancel := context.WithTimeout(ctx, deleteTimeout)
+	defer cancel()
+
+	if err := talosMachineLeaveEtcd(leaveCtx, endpoint, state.Node.ValueString(), talosConfig, state.OnDestroy.Graceful.ValueBool(), deleteTimeout); err != nil {
+		resp.Diagnostics.AddError("error removing etcd member", err.Error())
+
+		return
+	}
+
 	resetRequest := &machineapi.ResetRequest{
 		Graceful: state.OnDestroy.Graceful.ValueBool(),
 		Reboot:   state.OnDestroy.Reboot.ValueBool(),
@@ -751,6 +765,50 @@ func (r *talosMachineResource) Delete(ctx context.Context, req resource.DeleteRe
 	}
 }
 
+// talosMachineLeaveEtcd removes the etcd member of a control plane node before it is reset, waiting up to timeout while the
+// remaining members would lose quorum, e.g. while other control planes destroyed in parallel are being reset.
+// A graceful reset leaves etcd on its own, so only the quorum check runs then. Worker nodes and the last etcd member are left alone.
+func talosMachineLeaveEtcd(ctx context.Context, endpoint, node string, talosConfig *clientconfig.Config, graceful bool, timeout time.Duration) error {
+	return talosClientOp(ctx, endpoint, node, talosConfig, func(nodeCtx context.Context, c *client.Client) error {
+		local, err := safe.StateGet[*etcdresource.Member](nodeCtx, c.COSI, etcdresource.NewMember(etcdresource.NamespaceName, etcdresource.LocalMemberID).Metadata())
+		if err != nil {
+			if cosistate.IsNotFoundError(err) {
+				return nil
+			}
+
+			return err
+		}
+
+		memberID, err := etcdresource.ParseMemberID(local.TypedSpec().MemberID)
+		if err != nil {
+			return err
+		}
+
+		states, err := etcdWaitForRemovable(ctx, timeout, memberID, func(ctx context.Context) ([]etcdMemberState, error) {
+			members, err := etcdMemberList(ctx, c, node)
+			if err != nil {
+				return nil, err
+			}
+
+			return etcdMembersStatus(ctx, c, members, nil), nil
+		})
+		if err != nil || states == nil || graceful {
+			return err
+		}
+
+		idx := slices.IndexFunc(states, func(m etcdMemberState) bool { return m.member.GetId() == memberID })
+
+		viaIdx := slices.IndexFunc(states, func(m etcdMemberState) bool {
+			return m.member.GetId() != memberID && !m.member.GetIsLearner() && m.healthy()
+		})
+		if viaIdx == -1 {
+			return nil
+		}
+
+		return etcdRemoveMember(ctx, c, node, states[idx].member, etcdMemberHost(states[viaIdx].member))
+	})
+}
+
 // talosMachineApplyConfig applies the machine configuration with retry and waits for
 // the node to be reachable afterwards (it reboots on first config apply).
 func talosMachineApplyConfig(ctx context.Context, endpoint, node string, talosConfig *clientconfig.Config, cfgBytes []byte) error {
@@ -0,0 +1,504 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package talos
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/cosi-project/runtime/pkg/resource"
+	cosistate "github.com/cosi-project/runtime/pkg/state"
+	"github.com/hashicorp/terraform-plugin-framework-timeouts/resource/timeouts"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-framework/types/basetypes"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/retry"
+	"github.com/siderolabs/talos/pkg/machinery/client"
+	"go.yaml.in/yaml/v4"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type talosMachineResourcesDataSource struct{}
+
+var _ datasource.DataSource = &talosMachineResourcesDataSource{}
+
+type talosMachineResourcesDataSourceModelV0 struct { //nolint:govet
+	ID                  types.String        `tfsdk:"id"`
+	Node                types.String        `tfsdk:"node"`
+	Endpoint            types.String        `tfsdk:"endpoint"`
+	ClientConfiguration clientConfiguration `tfsdk:"client_configuration"`
+	Namespace           types.String        `tfsdk:"namespace"`
+	Type                types.String        `tfsdk:"type"`
+	ResourceID          types.String        `tfsdk:"resource_id"`
+	LabelSelector       types.String        `tfsdk:"label_selector"`
+	Resources           []machineResource   `tfsdk:"resources"`
+	Decoded             types.Dynamic       `tfsdk:"decoded"`
+	Timeouts            timeouts.Value      `tfsdk:"timeouts"`
+}
+
+type machineResource struct {
+	Namespace    types.String            `tfsdk:"namespace"`
+	Type         types.String            `tfsdk:"type"`
+	ID           types.String            `tfsdk:"id"`
+	Version      types.String            `tfsdk:"version"`
+	Phase        types.String            `tfsdk:"phase"`
+	Labels       map[string]types.String `tfsdk:"labels"`
+	MetadataYAML types.String            `tfsdk:"metadata_yaml"`
+	MetadataJSON types.String            `tfsdk:"metadata_json"`
+	SpecYAML     types.String            `tfsdk:"spec_yaml"`
+	SpecJSON     types.String            `tfsdk:"spec_json"`
+}
+
+// NewTalosMachineResourcesDataSource implements the datasource.DataSource interface.
+func NewTalosMachineResourcesDataSource() datasource.DataSource {
+	return &talosMachineResourcesDataSource{}
+}
+
+func (d *talosMachineResourcesDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_machine_resources"
+}
+
+func (d *talosMachineResourcesDataSource) Schema(ctx context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Reads arbitrary Talos resources from a node, like talosctl get",
+		MarkdownDescription: "Reads arbitrary Talos resources from a node, like `talosctl get`. " +
+			"Use `talosctl get rd` to list the resource types a node exposes.",
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed: true,
+			},
+			"node": schema.StringAttribute{
+				Required:    true,
+				Description: "node to read the resources from",
+			},
+			"endpoint": schema.StringAttribute{
+				Optional:    true,
+				Computed:    true,
+				Description: "endpoint to use for the talosclient. If not set, the node value will be used",
+			},
+			"client_configuration": schema.SingleNestedAttribute{
+				Attributes: map[string]schema.Attribute{
+					"ca_certificate": schema.StringAttribute{
+						Required:    true,
+						Description: "The client CA certificate",
+					},
+					"client_certificate": schema.StringAttribute{
+						Required:    true,
+						Description: "The client certificate",
+					},
+					"client_key": schema.StringAttribute{
+						Required:    true,
+						Sensitive:   true,
+						Description: "The client key",
+					},
+				},
+				Required:    true,
+				Description: "The client configuration data",
+			},
+			"type": schema.StringAttribute{
+				Required:    true,
+				Description: "The resource type or one of its aliases, e.g. `addresses`, `routes` or `ExtensionStatuses.runtime.talos.dev`",
+			},
+			"namespace": schema.StringAttribute{
+				Optional:    true,
+				Computed:    true,
+				Description: "The resource namespace. Defaults to the default namespace of the resource type",
+			},
+			"resource_id": schema.StringAttribute{
+				Optional:    true,
+				Description: "Read only the resource with this ID. It is an error if it doesn't exist",
+			},
+			"label_selector": schema.StringAttribute{
+				Optional:    true,
+				Description: "Comma-separated label terms that all have to match: `key`, `!key`, `key=value` or `key!=value`",
+				Validators: []validator.String{
+					stringvalidator.ConflictsWith(path.MatchRoot("resource_id")),
+				},
+			},
+			"resources": schema.ListNestedAttribute{
+				Computed:    true,
+				Description: "The matching resources, sorted by ID",
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"namespace": schema.StringAttribute{
+							Computed:    true,
+							Description: "The resource namespace",
+						},
+						"type": schema.StringAttribute{
+							Computed:    true,
+							Description: "The resource type",
+						},
+						"id": schema.StringAttribute{
+							Computed:    true,
+							Description: "The resource ID",
+						},
+						"version": schema.StringAttribute{
+							Computed:    true,
+							Description: "The resource version",
+						},
+						"phase": schema.StringAttribute{
+							Computed:    true,
+							Description: "The resource phase, `running` or `tearingDown`",
+						},
+						"labels": schema.MapAttribute{
+							Computed:    true,
+							ElementType: types.StringType,
+							Description: "The resource labels",
+						},
+						"metadata_yaml": schema.StringAttribute{
+							Computed:    true,
+							Description: "The resource metadata as YAML",
+						},
+						"metadata_json": schema.StringAttribute{
+							Computed:    true,
+							Description: "The resource metadata as JSON",
+						},
+						"spec_yaml": schema.StringAttribute{
+							Computed:    true,
+							Description: "The resource spec as YAML, as printed by `talosctl get -o yaml`",
+						},
+						"spec_json": schema.StringAttribute{
+							Computed:    true,
+							Description: "The resource spec as JSON",
+						},
+					},
+				},
+			},
+			"decoded": schema.DynamicAttribute{
+				Computed: true,
+				Description: "The matching resources as a list of objects with `metadata` and `spec` attributes, in the same order as `resources`. " +
+					"Prefer `jsondecode(spec_json)` when the shape of the spec differs between resources",
+			},
+			"timeouts": timeouts.Attributes(ctx, timeouts.Opts{
+				Read: true,
+			}),
+		},
+	}
+}
+
+func (d *talosMachineResourcesDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var obj types.Object
+
+	diags := req.Config.Get(ctx, &obj)
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var state talosMachineResourcesDataSourceModelV0
+
+	diags = obj.As(ctx, &state, basetypes.ObjectAsOptions{
+		UnhandledNullAsEmpty:    true,
+		UnhandledUnknownAsEmpty: true,
+	})
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	talosConfig, err := talosClientTFConfigToTalosClientConfig(
+		"dynamic",
+		state.ClientConfiguration.CA.ValueString(),
+		state.ClientConfiguration.Cert.ValueString(),
+		state.ClientConfiguration.Key.ValueString(),
+	)
+	if err != nil {
+		resp.Diagnostics.AddError("failed to generate talos config", err.Error())
+
+		return
+	}
+
+	labelQuery, err := parseLabelSelector(state.LabelSelector.ValueString())
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("label_selector"), "invalid label selector", err.Error())
+
+		return
+	}
+
+	if state.Endpoint.IsNull() {
+		state.Endpoint = state.Node
+	}
+
+	readTimeout, diags := state.Timeouts.Read(ctx, 10*time.Minute)
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctxDeadline, cancel := context.WithTimeout(ctx, readTimeout)
+	defer cancel()
+
+	var (
+		items     []resource.Resource
+		namespace resource.Namespace
+	)
+
+	if err = retry.RetryContext(ctxDeadline, readTimeout, func() *retry.RetryError {
+		if clientOpErr := talosClientOp(ctxDeadline, state.Endpoint.ValueString(), state.Node.ValueString(), talosConfig, func(nodeCtx context.Context, c *client.Client) error {
+			namespace = state.Namespace.ValueString()
+
+			rd, resolveErr := c.ResolveResourceKind(nodeCtx, &namespace, state.Type.ValueString())
+			if resolveErr != nil {
+				return status.Error(codes.InvalidArgument, resolveErr.Error())
+			}
+
+			md := resource.NewMetadata(namespace, rd.TypedSpec().Type, state.ResourceID.ValueString(), resource.VersionUndefined)
+
+			if state.ResourceID.ValueString() != "" {
+				r, getErr := c.COSI.Get(nodeCtx, md, cosistate.WithGetUnmarshalOptions(cosistate.WithSkipProtobufUnmarshal()))
+				if getErr != nil {
+					if cosistate.IsNotFoundError(getErr) {
+						return status.Error(codes.InvalidArgument, getErr.Error())
+					}
+
+					return getErr
+				}
+
+				items = []resource.Resource{r}
+
+				return nil
+			}
+
+			list, listErr := c.COSI.List(nodeCtx, md,
+				cosistate.WithLabelQuery(resource.RawLabelQuery(labelQuery)),
+				cosistate.WithListUnmarshalOptions(cosistate.WithSkipProtobufUnmarshal()),
+			)
+			if listErr != nil {
+				return listErr
+			}
+
+			items = list.Items
+
+			return nil
+		}); clientOpErr != nil {
+			if s := status.Code(clientOpErr); s == codes.InvalidArgument || s == codes.PermissionDenied {
+				return retry.NonRetryableError(clientOpErr)
+			}
+
+			return retry.RetryableError(clientOpErr)
+		}
+
+		return nil
+	}); err != nil {
+		resp.Diagnostics.AddError("failed to read machine resources", err.Error())
+
+		return
+	}
+
+	slices.SortFunc(items, func(a, b resource.Resource) int {
+		return strings.Compare(a.Metadata().ID(), b.Metadata().ID())
+	})
+
+	state.Resources = make([]machineResource, 0, len(items))
+	decoded := make([]attr.Value, 0, len(items))
+
+	for _, item := range items {
+		r, value, convErr := machineResourceToTFTypes(item)
+		if convErr != nil {
+			resp.Diagnostics.AddError("failed to decode resource "+resource.String(item), convErr.Error())
+
+			return
+		}
+
+		state.Resources = append(state.Resources, r)
+		decoded = append(decoded, value)
+	}
+
+	decodedTypes := make([]attr.Type, 0, len(decoded))
+
+	for _, v := range decoded {
+		decodedTypes = append(decodedTypes, v.Type(ctx))
+	}
+
+	decodedTuple, diags := types.TupleValue(decodedTypes, decoded)
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	state.Decoded = types.DynamicValue(decodedTuple)
+	state.Namespace = types.StringValue(namespace)
+	state.ID = types.StringValue(fmt.Sprintf("%s/%s/%s", state.Node.ValueString(), namespace, state.Type.ValueString()))
+
+	diags = resp.State.Set(ctx, &state)
+	resp.Diagnostics.Append(diags...)
+}
+
+// machineResourceToTFTypes converts a resource to its flat representation and to a decoded object value.
+func machineResourceToTFTypes(r resource.Resource) (machineResource, attr.Value, error) {
+	md := r.Metadata()
+
+	metadataYAML, err := yaml.Marshal(md)
+	if err != nil {
+		return machineResource{}, nil, err
+	}
+
+	specYAML, err := yaml.Marshal(r.Spec())
+	if err != nil {
+		return machineResource{}, nil, err
+	}
+
+	var metadata, spec any
+
+	if err = yaml.Unmarshal(metadataYAML, &metadata); err != nil {
+		return machineResource{}, nil, err
+	}
+
+	if err = yaml.Unmarshal(specYAML, &spec); err != nil {
+		return machineResource{}, nil, err
+	}
+
+	metadataJSON, err := json.Marshal(metadata)
+	if err != nil {
+		return machineResource{}, nil, err
+	}
+
+	specJSON, err := json.Marshal(spec)
+	if err != nil {
+		return machineResource{}, nil, err
+	}
+
+	labels := map[string]types.String{}
+
+	if m, ok := metadata.(map[string]any); ok {
+		if rawLabels, ok := m["labels"].(map[string]any); ok {
+			for k, v := range rawLabels {
+				labels[k] = types.StringValue(fmt.Sprint(v))
+			}
+		}
+	}
+
+	value, err := goValueToTFValue(map[string]any{"metadata": metadata, "spec": spec})
+	if err != nil {
+		return machineResource{}, nil, err
+	}
+
+	return machineResource{
+		Namespace:    types.StringValue(md.Namespace()),
+		Type:         types.StringValue(md.Type()),
+		ID:           types.StringValue(md.ID()),
+		Version:      types.StringValue(md.Version().String()),
+		Phase:        types.StringValue(md.Phase().String()),
+		Labels:       labels,
+		MetadataYAML: types.StringValue(string(metadataYAML)),
+		MetadataJSON: types.StringValue(string(metadataJSON)),
+		SpecYAML:     types.StringValue(string(specYAML)),
+		SpecJSON:     types.StringValue(string(specJSON)),
+	}, value, nil
+}
+
+// goValueToTFValue converts a value decoded from YAML into a Terraform value:
+// maps become objects, lists become tuples and null becomes a null string.
+func goValueToTFValue(v any) (attr.Value, error) {
+	switch v := v.(type) {
+	case nil:
+		return types.StringNull(), nil
+	case string:
+		return types.StringValue(v), nil
+	case bool:
+		return types.BoolValue(v), nil
+	case int:
+		return types.NumberValue(new(big.Float).SetInt64(int64(v))), nil
+	case int64:
+		return types.NumberValue(new(big.Float).SetInt64(v)), nil
+	case uint64:
+		return types.NumberValue(new(big.Float).SetUint64(v)), nil
+	case float64:
+		return types.NumberValue(big.NewFloat(v)), nil
+	case time.Time:
+		return types.StringValue(v.Format(time.RFC3339Nano)), nil
+	case map[string]any:
+		attrTypes := make(map[string]attr.Type, len(v))
+		attrs := make(map[string]attr.Value, len(v))
+
+		for key, elem := range v {
+			value, err := goValueToTFValue(elem)
+			if err != nil {
+				return nil, err
+			}
+
+			attrTypes[key] = value.Type(context.Background())
+			attrs[key] = value
+		}
+
+		obj, diags := types.ObjectValue(attrTypes, attrs)
+		if diags.HasError() {
+			return nil, fmt.Errorf("%v", diags)
+		}
+
+		return obj, nil
+	case []any:
+		elemTypes := make([]attr.Type, 0, len(v))
+		elems := make([]attr.Value, 0, len(v))
+
+		for _, elem := range v {
+			value, err := goValueToTFValue(elem)
+			if err != nil {
+				return nil, err
+			}
+
+			elemTypes = append(elemTypes, value.Type(context.Background()))
+			elems = append(elems, value)
+		}
+
+		tuple, diags := types.TupleValue(elemTypes, elems)
+		if diags.HasError() {
+			return nil, fmt.Errorf("%v", diags)
+		}
+
+		return tuple, nil
+	default:
+		return types.StringValue(fmt.Sprint(v)), nil
+	}
+}
+
+// parseLabelSelector parses a comma-separated list of `key`, `!key`, `key=value` and `key!=value` terms.
+func parseLabelSelector(selector string) (resource.LabelQuery, error) {
+	var query resource.LabelQuery
+
+	for term := range strings.SplitSeq(selector, ",") {
+		term = strings.TrimSpace(term)
+
+		var opt resource.LabelQueryOption
+
+		switch {
+		case term == "":
+			continue
+		case strings.Contains(term, "!="):
+			key, value, _ := strings.Cut(term, "!=")
+			opt = resource.LabelEqual(strings.TrimSpace(key), strings.TrimSpace(value), resource.NotMatches)
+		case strings.Contains(term, "="):
+			key, value, _ := strings.Cut(term, "=")
+			opt = resource.LabelEqual(strings.TrimSpace(key), strings.TrimSpace(value))
+		case strings.HasPrefix(term, "!"):
+			opt = resource.LabelExists(strings.TrimSpace(term[1:]), resource.NotMatches)
+		default:
+			opt = resource.LabelExists(term)
+		}
+
+		opt(&query)
+
+		if key := query.Terms[len(query.Terms)-1].Key; key == "" {
+			return resource.LabelQuery{}, fmt.Errorf("label term %q has no key", term)
+		}
+	}
+
+	return query, nil
+}
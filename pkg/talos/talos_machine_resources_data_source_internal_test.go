@@ -0,0 +1,108 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package talos //nolint:testpackage // needs access to unexported label selector parser and value conversion
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+func TestParseLabelSelector(t *testing.T) {
+	t.Parallel()
+
+	query, err := parseLabelSelector("talos.dev/managed-by, !internal ,role=controlplane,zone!=a")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(query.Terms) != 4 {
+		t.Fatalf("expected 4 terms, got %d", len(query.Terms))
+	}
+
+	for i, want := range []struct {
+		key    string
+		value  string
+		invert bool
+	}{
+		{key: "talos.dev/managed-by"},
+		{key: "internal", invert: true},
+		{key: "role", value: "controlplane"},
+		{key: "zone", value: "a", invert: true},
+	} {
+		term := query.Terms[i]
+
+		if term.Key != want.key || term.Invert != want.invert {
+			t.Errorf("term %d = %+v, want key %q invert %v", i, term, want.key, want.invert)
+		}
+
+		if want.value != "" && (len(term.Value) != 1 || term.Value[0] != want.value) {
+			t.Errorf("term %d value = %v, want %q", i, term.Value, want.value)
+		}
+	}
+
+	if query, err = parseLabelSelector(""); err != nil || len(query.Terms) != 0 {
+		t.Errorf("expected an empty selector to match everything, got %+v, %v", query, err)
+	}
+
+	for _, selector := range []string{"=value", "!", "a,!=b"} {
+		if _, err = parseLabelSelector(selector); err == nil {
+			t.Errorf("expected an error for selector %q", selector)
+		}
+	}
+}
+
+func TestGoValueToTFValue(t *testing.T) {
+	t.Parallel()
+
+	value, err := goValueToTFValue(map[string]any{
+		"name":    "eth0",
+		"up":      true,
+		"mtu":     1500,
+		"missing": nil,
+		"addresses": []any{
+			"10.5.0.2/24",
+			map[string]any{"family": "inet6"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	obj, ok := value.(types.Object)
+	if !ok {
+		t.Fatalf("expected an object, got %T", value)
+	}
+
+	attrs := obj.Attributes()
+
+	if v := attrs["name"].(types.String).ValueString(); v != "eth0" {
+		t.Errorf("name = %q", v)
+	}
+
+	if v := attrs["up"].(types.Bool).ValueBool(); !v {
+		t.Error("up = false")
+	}
+
+	if v := attrs["mtu"].(types.Number).ValueBigFloat(); v.Cmp(big.NewFloat(1500)) != 0 {
+		t.Errorf("mtu = %s", v)
+	}
+
+	if !attrs["missing"].IsNull() {
+		t.Error("expected missing to be null")
+	}
+
+	addresses, ok := attrs["addresses"].(types.Tuple)
+	if !ok {
+		t.Fatalf("expected addresses to be a tuple, got %T", attrs["addresses"])
+	}
+
+	if elems := addresses.Elements(); len(elems) != 2 {
+		t.Errorf("expected 2 addresses, got %d", len(elems))
+	} else if _, ok := elems[1].(types.Object); !ok {
+		t.Errorf("expected the second address to be an object, got %T", elems[1])
+	}
+}
@@ -8,8 +8,11 @@ import (
 	"context"
 	"time"
 
+	"github.com/hashicorp/terraform-plugin-framework-validators/listvalidator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
 	"github.com/hashicorp/terraform-plugin-framework/ephemeral"
 	"github.com/hashicorp/terraform-plugin-framework/ephemeral/schema"
+	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
 	"github.com/hashicorp/terraform-plugin-framework/types"
 	"github.com/hashicorp/terraform-plugin-framework/types/basetypes"
@@ -26,6 +29,12 @@ type talosMachineSecretsEphemeralResourceModel struct {
 	TalosVersion        types.String        `tfsdk:"talos_version"`
 	MachineSecrets      machineSecrets      `tfsdk:"machine_secrets"`
 	ClientConfiguration clientConfiguration `tfsdk:"client_configuration"`
+	SecretsYAML         types.String        `tfsdk:"secrets_yaml"`
+	AgeRecipients       []types.String      `tfsdk:"age_recipients"`
+	SOPSFile            types.String        `tfsdk:"sops_file"`
+	EncryptedSecrets    types.String        `tfsdk:"encrypted_secrets"`
+	Seed                types.String        `tfsdk:"seed"`
+	SeedNotBefore       types.String        `tfsdk:"seed_not_before"`
 }
 
 // NewTalosMachineSecretsEphemeralResource implements the ephemeral.EphemeralResource interface.
@@ -39,7 +48,9 @@ func (r *talosMachineSecretsEphemeralResource) Metadata(_ context.Context, req e
 
 func (r *talosMachineSecretsEphemeralResource) Schema(_ context.Context, _ ephemeral.SchemaRequest, resp *ephemeral.SchemaResponse) {
 	resp.Schema = schema.Schema{
-		Description: "Generate machine secrets for Talos cluster. This is an ephemeral resource that does not persist secrets in Terraform state.",
+		Description: "Generate machine secrets for Talos cluster. This is an ephemeral resource that does not persist secrets in Terraform state. " +
+			"It can also decrypt the `encrypted_secrets` of the `talos_machine_secrets` resource or a SOPS encrypted `secrets.yaml`, " +
+			"with the age identity from `SOPS_AGE_KEY`, the file in `SOPS_AGE_KEY_FILE` or `sops/age/keys.txt` in the user configuration directory.",
 		Attributes: map[string]schema.Attribute{
 			"talos_version": schema.StringAttribute{
 				Optional:    true,
@@ -69,6 +80,52 @@ func (r *talosMachineSecretsEphemeralResource) Schema(_ context.Context, _ ephem
 				Computed:    true,
 				Description: "The generated client configuration data",
 			},
+			"secrets_yaml": schema.StringAttribute{
+				Computed:    true,
+				Sensitive:   true,
+				Description: "The machine secrets in the `secrets.yaml` format of `talosctl gen secrets`, as accepted by `talosctl gen config --with-secrets`",
+			},
+			"age_recipients": schema.ListAttribute{
+				ElementType: types.StringType,
+				Optional:    true,
+				Description: "Encrypt `secrets_yaml` to these age recipients (`age1...`) into `encrypted_secrets`",
+				Validators: []validator.List{
+					listvalidator.SizeAtLeast(1),
+				},
+			},
+			"sops_file": schema.StringAttribute{
+				Optional:    true,
+				Description: "Path to a `secrets.yaml` encrypted with SOPS to decrypt instead of generating secrets, with the keys from the environment as the `sops` binary does",
+				Validators: []validator.String{
+					stringvalidator.ConflictsWith(path.MatchRoot("encrypted_secrets")),
+				},
+			},
+			"seed": schema.StringAttribute{
+				Optional:  true,
+				Sensitive: true,
+				Description: "Derive the secrets from this seed instead of generating them randomly, e.g. a random string kept in a secret manager. " +
+					"The same seed, `seed_not_before` and `talos_version` always derive the same secrets",
+				Validators: []validator.String{
+					stringvalidator.LengthAtLeast(seededSecretsMinLength),
+					stringvalidator.ConflictsWith(path.MatchRoot("encrypted_secrets"), path.MatchRoot("sops_file")),
+					stringvalidator.AlsoRequires(path.MatchRoot("seed_not_before")),
+				},
+			},
+			"seed_not_before": schema.StringAttribute{
+				Optional: true,
+				Description: "RFC3339 timestamp the CAs derived from `seed` are valid from, for 10 years. " +
+					"It is part of the derivation like the seed, so keep it stable, e.g. in a `terraform_data` resource",
+				Validators: []validator.String{
+					rfc3339Valid(),
+					stringvalidator.AlsoRequires(path.MatchRoot("seed")),
+				},
+			},
+			"encrypted_secrets": schema.StringAttribute{
+				Optional: true,
+				Computed: true,
+				Description: "The age encrypted `secrets.yaml` to decrypt instead of generating secrets, e.g. the `encrypted_secrets` of the `talos_machine_secrets` resource. " +
+					"If not set, the `secrets_yaml` encrypted to `age_recipients`, if any",
+			},
 		},
 	}
 }
@@ -112,15 +169,50 @@ func (r *talosMachineSecretsEphemeralResource) Open(ctx context.Context, req eph
 		return
 	}
 
-	// Generate secrets
-	secretsBundle, err := secrets.NewBundle(secrets.NewFixedClock(time.Now()), versionContract)
-	if err != nil {
-		resp.Diagnostics.AddError(
-			"failed to generate secrets bundle",
-			err.Error(),
-		)
-
-		return
+	var secretsBundle *secrets.Bundle
+
+	switch {
+	case config.EncryptedSecrets.ValueString() != "":
+		secretsBundle, err = ageSecretsBundle(config.EncryptedSecrets.ValueString())
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("encrypted_secrets"), "failed to decrypt machine secrets", err.Error())
+
+			return
+		}
+	case config.SOPSFile.ValueString() != "":
+		secretsBundle, err = sopsSecretsBundle(config.SOPSFile.ValueString())
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("sops_file"), "failed to read SOPS encrypted machine secrets", err.Error())
+
+			return
+		}
+	case config.Seed.ValueString() != "":
+		var notBefore time.Time
+
+		notBefore, err = time.Parse(time.RFC3339, config.SeedNotBefore.ValueString())
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("seed_not_before"), "failed to parse seed_not_before", err.Error())
+
+			return
+		}
+
+		secretsBundle, err = newSeededSecretsBundle([]byte(config.Seed.ValueString()), notBefore, versionContract)
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("seed"), "failed to derive secrets bundle", err.Error())
+
+			return
+		}
+	default:
+		// Generate secrets
+		secretsBundle, err = secrets.NewBundle(secrets.NewFixedClock(time.Now()), versionContract)
+		if err != nil {
+			resp.Diagnostics.AddError(
+				"failed to generate secrets bundle",
+				err.Error(),
+			)
+
+			return
+		}
 	}
 
 	// Convert to model
@@ -136,6 +228,24 @@ func (r *talosMachineSecretsEphemeralResource) Open(ctx context.Context, req eph
 		TalosVersion:        types.StringValue(talosVersion),
 		MachineSecrets:      temp.MachineSecrets,
 		ClientConfiguration: temp.ClientConfiguration,
+		SecretsYAML:         temp.SecretsYAML,
+		AgeRecipients:       config.AgeRecipients,
+		SOPSFile:            config.SOPSFile,
+		EncryptedSecrets:    config.EncryptedSecrets,
+		Seed:                config.Seed,
+		SeedNotBefore:       config.SeedNotBefore,
+	}
+
+	if result.EncryptedSecrets.ValueString() == "" {
+		temp.AgeRecipients = config.AgeRecipients
+
+		if err = encryptMachineSecrets(&temp); err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("age_recipients"), "failed to encrypt machine secrets", err.Error())
+
+			return
+		}
+
+		result.EncryptedSecrets = temp.EncryptedSecrets
 	}
 
 	// Set result - ephemeral resources use Result instead of State
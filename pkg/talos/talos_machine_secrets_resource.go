@@ -8,30 +8,52 @@ import (
 	"context"
 	"crypto/x509"
 	"encoding/pem"
+	"fmt"
 	"os"
+	"slices"
 	"strings"
 	"time"
 
+	"github.com/cosi-project/runtime/pkg/safe"
+	"github.com/hashicorp/terraform-plugin-framework-validators/listvalidator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
 	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
 	"github.com/hashicorp/terraform-plugin-framework/types"
 	"github.com/hashicorp/terraform-plugin-framework/types/basetypes"
 	"github.com/hashicorp/terraform-plugin-log/tflog"
+	x509crypto "github.com/siderolabs/crypto/x509"
+	"github.com/siderolabs/talos/pkg/machinery/client"
+	"github.com/siderolabs/talos/pkg/machinery/config"
+	"github.com/siderolabs/talos/pkg/machinery/config/configloader"
 	"github.com/siderolabs/talos/pkg/machinery/config/generate/secrets"
+	"github.com/siderolabs/talos/pkg/machinery/constants"
 	"github.com/siderolabs/talos/pkg/machinery/gendata"
+	configresource "github.com/siderolabs/talos/pkg/machinery/resources/config"
+	"github.com/siderolabs/talos/pkg/machinery/role"
 	"go.yaml.in/yaml/v4"
 	"golang.org/x/mod/semver"
 )
 
 var (
-	_ resource.Resource                 = &talosMachineSecretsResource{}
-	_ resource.ResourceWithUpgradeState = &talosMachineSecretsResource{}
-	_ resource.ResourceWithImportState  = &talosMachineSecretsResource{}
-	_ resource.ResourceWithModifyPlan   = &talosMachineSecretsResource{}
+	_ resource.Resource                   = &talosMachineSecretsResource{}
+	_ resource.ResourceWithUpgradeState   = &talosMachineSecretsResource{}
+	_ resource.ResourceWithImportState    = &talosMachineSecretsResource{}
+	_ resource.ResourceWithModifyPlan     = &talosMachineSecretsResource{}
+	_ resource.ResourceWithValidateConfig = &talosMachineSecretsResource{}
+)
+
+const (
+	machineSecretsImportConfigPrefix = "config:"
+	machineSecretsImportNodePrefix   = "node:"
+	machineSecretsImportTimeout      = time.Minute
 )
 
 // OverridableTimeFunc is a function that returns the current time. It is used to allow tests to override the current time.
@@ -54,6 +76,16 @@ type talosMachineSecretsResourceModelV1 struct {
 	TalosVersion        types.String        `tfsdk:"talos_version"`
 	MachineSecrets      machineSecrets      `tfsdk:"machine_secrets"`
 	ClientConfiguration clientConfiguration `tfsdk:"client_configuration"`
+	ClientRoles         []types.String      `tfsdk:"client_roles"`
+	ClientCrtTTL        types.String        `tfsdk:"client_crt_ttl"`
+	// CertificateAuthorities is an object, so that CAs unknown until apply can be planned
+	CertificateAuthorities types.Object   `tfsdk:"certificate_authorities"`
+	SecretsYAML            types.String   `tfsdk:"secrets_yaml"`
+	AgeRecipients          []types.String `tfsdk:"age_recipients"`
+	SOPSFile               types.String   `tfsdk:"sops_file"`
+	EncryptedSecrets       types.String   `tfsdk:"encrypted_secrets"`
+	SeedWO                 types.String   `tfsdk:"seed_wo"`
+	SeedNotBefore          types.String   `tfsdk:"seed_not_before"`
 }
 
 type clientConfiguration struct {
@@ -153,7 +185,114 @@ func (r *talosMachineSecretsResource) Schema(_ context.Context, _ resource.Schem
 				Computed:    true,
 				Description: "The generated client configuration data",
 			},
+			"secrets_yaml": schema.StringAttribute{
+				Computed:    true,
+				Sensitive:   true,
+				Description: "The machine secrets in the `secrets.yaml` format of `talosctl gen secrets`, as accepted by `talosctl gen config --with-secrets` and by the import of this resource",
+			},
+			"client_roles": schema.ListAttribute{
+				ElementType: types.StringType,
+				Optional:    true,
+				Description: "The Talos API roles of the certificate in `client_configuration`, e.g. `[\"os:reader\"]`. Defaults to `[\"os:admin\"]`",
+				Validators: append(clientRolesValidators(),
+					listvalidator.ConflictsWith(path.MatchRoot("age_recipients")),
+				),
+			},
+			"age_recipients": schema.ListAttribute{
+				ElementType: types.StringType,
+				Optional:    true,
+				Description: "Encrypt the secrets to these age recipients (`age1...`). The state then only holds `encrypted_secrets`: " +
+					"`machine_secrets`, `client_configuration` and `secrets_yaml` are null, and the secrets are decrypted in memory by the `talos_machine_secrets` ephemeral resource. " +
+					"Changing the recipients, the CAs or going back to plain text requires an age identity to decrypt the secrets, " +
+					"read from `SOPS_AGE_KEY`, the file in `SOPS_AGE_KEY_FILE` or `sops/age/keys.txt` in the user configuration directory",
+				Validators: []validator.List{
+					listvalidator.SizeAtLeast(1),
+				},
+			},
+			"sops_file": schema.StringAttribute{
+				Optional: true,
+				Description: "Path to a `secrets.yaml` encrypted with SOPS to use instead of generated secrets, decrypted with the keys from the environment as the `sops` binary does, " +
+					"e.g. the age identity in `SOPS_AGE_KEY_FILE`. " +
+					"Requires `age_recipients`, so the decrypted secrets are encrypted again and never stored in plain text. " +
+					"Changes to the content of the file are not detected",
+				Validators: []validator.String{
+					stringvalidator.ConflictsWith(path.MatchRoot("certificate_authorities")),
+					stringvalidator.AlsoRequires(path.MatchRoot("age_recipients")),
+				},
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"seed_wo": schema.StringAttribute{
+				Optional:  true,
+				Sensitive: true,
+				WriteOnly: true,
+				Description: "Derive the secrets from this seed instead of generating them randomly, e.g. a random string kept in a secret manager. " +
+					"The same seed and `talos_version` always derive the same secrets, so the state can be rebuilt from the seed. " +
+					"The seed is only used when the resource is created, together with `seed_not_before`. Requires Terraform 1.11+.",
+				Validators: []validator.String{
+					stringvalidator.LengthAtLeast(seededSecretsMinLength),
+					stringvalidator.ConflictsWith(path.MatchRoot("sops_file")),
+					stringvalidator.AlsoRequires(path.MatchRoot("seed_not_before")),
+				},
+			},
+			"seed_not_before": schema.StringAttribute{
+				Optional: true,
+				Description: "RFC3339 timestamp the CAs derived from `seed_wo` are valid from, for 10 years. " +
+					"It is part of the derivation like the seed, so keep it to derive the same secrets again, and change it to derive new CAs before the current ones expire",
+				Validators: []validator.String{
+					rfc3339Valid(),
+					stringvalidator.AlsoRequires(path.MatchRoot("seed_wo")),
+				},
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"encrypted_secrets": schema.StringAttribute{
+				Computed:    true,
+				Description: "The `secrets_yaml` encrypted to `age_recipients` as an ASCII armored age file, if `age_recipients` is set",
+			},
+			"client_crt_ttl": schema.StringAttribute{
+				Optional: true,
+				Description: "The lifetime of the certificate in `client_configuration` as a Go duration string (e.g. \"720h\"). Defaults to \"8760h\" (1 year). " +
+					"The certificate is regenerated when a month or half of its lifetime, whichever is shorter, is left",
+				Validators: []validator.String{
+					goDurationValid(),
+					stringvalidator.ConflictsWith(path.MatchRoot("age_recipients")),
+				},
+			},
+			"certificate_authorities": schema.SingleNestedAttribute{
+				Attributes: map[string]schema.Attribute{
+					"etcd":           certificateAuthoritySchema("The etcd CA, with an ECDSA or RSA key"),
+					"k8s":            certificateAuthoritySchema("The Kubernetes CA, with an ECDSA key"),
+					"k8s_aggregator": certificateAuthoritySchema("The Kubernetes front proxy CA, with an ECDSA or RSA key"),
+					"os":             certificateAuthoritySchema("The Talos API CA, with an Ed25519 or ECDSA key"),
+				},
+				Optional: true,
+				Description: "Externally managed CAs to use instead of generated ones. " +
+					"A CA may be an intermediate CA, in which case its certificate should be followed by the rest of the chain, which is kept in the generated machine configurations. " +
+					"CAs removed from this attribute are kept as they are. A changed CA is swapped in place, which the plan warns about: " +
+					"replacing a CA of a running cluster requires a CA rotation, see `talos_ca_rotation`",
+			},
+		},
+	}
+}
+
+func certificateAuthoritySchema(description string) schema.SingleNestedAttribute {
+	return schema.SingleNestedAttribute{
+		Description: description,
+		Attributes: map[string]schema.Attribute{
+			"cert": schema.StringAttribute{
+				Description: "The PEM encoded CA certificate, optionally followed by the certificates of its issuers",
+				Required:    true,
+			},
+			"key": schema.StringAttribute{
+				Description: "The PEM encoded CA private key",
+				Required:    true,
+				Sensitive:   true,
+			},
 		},
+		Optional: true,
 	}
 }
 
@@ -251,6 +390,40 @@ func certSchema() schema.SingleNestedAttribute {
 	}
 }
 
+func (r *talosMachineSecretsResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	cas, diags := certificateAuthoritiesAt(ctx, req.Config.GetAttribute)
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	for _, name := range certificateAuthorityNames {
+		ca, ok := cas[name]
+		if !ok || ca.Cert.IsUnknown() || ca.Key.IsUnknown() {
+			continue
+		}
+
+		if _, err := parseCertificateAuthority(name, ca.Cert.ValueString(), ca.Key.ValueString(), OverridableTimeFunc()); err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("certificate_authorities").AtName(name), "invalid certificate authority", err.Error())
+		}
+	}
+
+	var recipients []types.String
+
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("age_recipients"), &recipients)...)
+
+	for i, recipient := range recipients {
+		if recipient.IsNull() || recipient.IsUnknown() {
+			continue
+		}
+
+		if _, err := parseAgeRecipients([]string{recipient.ValueString()}); err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("age_recipients").AtListIndex(i), "invalid age recipient", err.Error())
+		}
+	}
+}
+
 func (r *talosMachineSecretsResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
 	var obj types.Object
 
@@ -283,16 +456,70 @@ func (r *talosMachineSecretsResource) Create(ctx context.Context, req resource.C
 		return
 	}
 
-	secretsBundle, err := secrets.NewBundle(secrets.NewFixedClock(time.Now()), versionContract)
-	if err != nil {
-		resp.Diagnostics.AddError(
-			"failed to generate secrets bundle",
-			err.Error(),
-		)
+	var seed types.String
+
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("seed_wo"), &seed)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var secretsBundle *secrets.Bundle
 
+	switch {
+	case plan.SOPSFile.ValueString() != "":
+		secretsBundle, err = sopsSecretsBundle(plan.SOPSFile.ValueString())
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("sops_file"), "failed to read SOPS encrypted machine secrets", err.Error())
+
+			return
+		}
+	case seed.ValueString() != "":
+		var notBefore time.Time
+
+		notBefore, err = time.Parse(time.RFC3339, plan.SeedNotBefore.ValueString())
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("seed_not_before"), "failed to parse seed_not_before", err.Error())
+
+			return
+		}
+
+		secretsBundle, err = newSeededSecretsBundle([]byte(seed.ValueString()), notBefore, versionContract)
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("seed_wo"), "failed to derive secrets bundle", err.Error())
+
+			return
+		}
+	default:
+		secretsBundle, err = secrets.NewBundle(secrets.NewFixedClock(time.Now()), versionContract)
+		if err != nil {
+			resp.Diagnostics.AddError(
+				"failed to generate secrets bundle",
+				err.Error(),
+			)
+
+			return
+		}
+	}
+
+	cas, diags := certificateAuthoritiesAt(ctx, req.Plan.GetAttribute)
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
 		return
 	}
 
+	for name, ca := range cas {
+		pair, err := parseCertificateAuthority(name, ca.Cert.ValueString(), ca.Key.ValueString(), OverridableTimeFunc())
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("certificate_authorities").AtName(name), "invalid certificate authority", err.Error())
+
+			return
+		}
+
+		setBundleCertificateAuthority(secretsBundle, name, pair)
+	}
+
 	state, err := secretsBundleTomachineSecrets(secretsBundle)
 	if err != nil {
 		resp.Diagnostics.AddError("failed to convert secrets bundle to machine secrets", err.Error())
@@ -301,9 +528,37 @@ func (r *talosMachineSecretsResource) Create(ctx context.Context, req resource.C
 	}
 
 	state.TalosVersion = plan.TalosVersion
+	state.ClientRoles = plan.ClientRoles
+	state.ClientCrtTTL = plan.ClientCrtTTL
+	state.CertificateAuthorities = plan.CertificateAuthorities
+	state.SOPSFile = plan.SOPSFile
+	state.SeedNotBefore = plan.SeedNotBefore
+	state.AgeRecipients = plan.AgeRecipients
+
+	if len(plan.ClientRoles) > 0 || plan.ClientCrtTTL.ValueString() != "" {
+		roles, ttl, err := clientCertificateOptions(plan.ClientRoles, plan.ClientCrtTTL)
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("client_roles"), "invalid client roles", err.Error())
+
+			return
+		}
+
+		state.ClientConfiguration, err = generateClientConfigurationWithTTL(secretsBundle, roles, ttl)
+		if err != nil {
+			resp.Diagnostics.AddError("failed to generate client configuration", err.Error())
+
+			return
+		}
+	}
+
+	if err = encryptMachineSecrets(&state); err != nil {
+		resp.Diagnostics.AddError("failed to encrypt machine secrets", err.Error())
+
+		return
+	}
 
 	// Set state to fully populated data
-	diags = resp.State.Set(ctx, &state)
+	diags = setMachineSecretsState(ctx, &resp.State, state)
 	resp.Diagnostics.Append(diags...)
 
 	if resp.Diagnostics.HasError() {
@@ -320,6 +575,15 @@ func (r *talosMachineSecretsResource) ModifyPlan(ctx context.Context, req resour
 		return
 	}
 
+	if !req.State.Raw.IsNull() {
+		encrypted := r.planComputedSecrets(ctx, req, resp)
+
+		// the client certificate is not stored in the state to be renewed
+		if resp.Diagnostics.HasError() || encrypted {
+			return
+		}
+	}
+
 	clientConfigurationPath := path.Root("client_configuration")
 
 	var obj types.Object
@@ -369,9 +633,24 @@ func (r *talosMachineSecretsResource) ModifyPlan(ctx context.Context, req resour
 		return
 	}
 
-	// check if NotAfter expires in a month
-	if x509Cert.NotAfter.Before(OverridableTimeFunc().AddDate(0, 1, 0)) {
-		tflog.Info(ctx, "client certificate expires in a month, needs regeneration")
+	stateRoles, stateTTL, _, diags := clientCertificateOptionsAt(ctx, req.State.GetAttribute)
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	planRoles, planTTL, planKnown, diags := clientCertificateOptionsAt(ctx, req.Plan.GetAttribute)
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	optionsChanged := !planKnown || !slices.Equal(stateRoles.Strings(), planRoles.Strings()) || stateTTL != planTTL
+
+	if optionsChanged || clientCertificateExpiring(x509Cert, planTTL) {
+		tflog.Info(ctx, "client certificate expires soon or its roles or TTL changed, needs regeneration")
 
 		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("client_configuration").AtName("ca_certificate"), types.StringUnknown())...)
 		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("client_configuration").AtName("client_certificate"), types.StringUnknown())...)
@@ -383,129 +662,438 @@ func (r *talosMachineSecretsResource) ModifyPlan(ctx context.Context, req resour
 	}
 }
 
-func (r *talosMachineSecretsResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
-	var talosVersion string
+// planComputedSecrets keeps the machine secrets, client configuration, secrets.yaml and encrypted secrets of the state in the plan,
+// except for the CAs changed in certificate_authorities and the encryption changed by age_recipients, which become unknown.
+// It reports whether the secrets are planned to be stored encrypted only.
+func (r *talosMachineSecretsResource) planComputedSecrets(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) bool {
+	var (
+		stateRecipients, planRecipients types.List
+		encryptedSecrets                types.String
+	)
+
+	resp.Diagnostics.Append(req.State.GetAttribute(ctx, path.Root("age_recipients"), &stateRecipients)...)
+	resp.Diagnostics.Append(req.Plan.GetAttribute(ctx, path.Root("age_recipients"), &planRecipients)...)
+	resp.Diagnostics.Append(req.State.GetAttribute(ctx, path.Root("encrypted_secrets"), &encryptedSecrets)...)
+
+	stateCAs, diags := certificateAuthoritiesAt(ctx, req.State.GetAttribute)
+	resp.Diagnostics.Append(diags...)
 
-	diags := req.Plan.GetAttribute(ctx, path.Root("talos_version"), &talosVersion)
+	planCAs, diags := certificateAuthoritiesAt(ctx, req.Plan.GetAttribute)
 	resp.Diagnostics.Append(diags...)
 
 	if resp.Diagnostics.HasError() {
-		return
+		return false
 	}
 
-	// Set state to fully populated data
-	diags = resp.State.SetAttribute(ctx, path.Root("talos_version"), talosVersion)
-	resp.Diagnostics.Append(diags...)
+	changedCAs := changedCertificateAuthorities(planCAs, stateCAs)
 
-	if resp.Diagnostics.HasError() {
-		return
+	for _, name := range changedCAs {
+		resp.Diagnostics.AddAttributeWarning(
+			path.Root("certificate_authorities").AtName(name),
+			"certificate authority is swapped in place",
+			fmt.Sprintf("The %q CA is replaced in the machine secrets without a rotation: nodes of a running cluster keep trusting the old CA only, "+
+				"and clients and certificates issued by the new CA are rejected until every node is reconfigured. "+
+				"To change the CA of a running cluster, use the talos_ca_rotation resource instead.", name),
+		)
 	}
 
-	clientConfigurationPath := path.Root("client_configuration")
+	if !planRecipients.IsNull() {
+		if planRecipients.IsUnknown() || !planRecipients.Equal(stateRecipients) || encryptedSecrets.IsNull() || len(changedCAs) > 0 {
+			tflog.Info(ctx, "machine secrets need to be encrypted")
+
+			encryptedSecrets = types.StringUnknown()
+		}
+
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("encrypted_secrets"), encryptedSecrets)...)
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("machine_secrets"), (*machineSecrets)(nil))...)
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("client_configuration"), (*clientConfiguration)(nil))...)
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("secrets_yaml"), types.StringNull())...)
+
+		return true
+	}
+
+	resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("encrypted_secrets"), types.StringNull())...)
+
+	// the plain text secrets are decrypted from the state
+	if !encryptedSecrets.IsNull() {
+		tflog.Info(ctx, "machine secrets need to be decrypted")
+
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("machine_secrets"), types.ObjectUnknown(
+			machineSecretsOutputSchemaAttribute().GetType().(types.ObjectType).AttrTypes, //nolint:forcetypeassert
+		))...)
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("client_configuration"), types.ObjectUnknown(clientConfigurationAttributeTypes()))...)
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("secrets_yaml"), types.StringUnknown())...)
+
+		return false
+	}
 
+	for _, attribute := range []string{"machine_secrets", "client_configuration"} {
+		var obj types.Object
+
+		resp.Diagnostics.Append(req.State.GetAttribute(ctx, path.Root(attribute), &obj)...)
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root(attribute), obj)...)
+	}
+
+	var secretsYAML types.String
+
+	resp.Diagnostics.Append(req.State.GetAttribute(ctx, path.Root("secrets_yaml"), &secretsYAML)...)
+
+	// secrets_yaml is missing in the state of resources created by earlier provider versions
+	if secretsYAML.IsNull() || len(changedCAs) > 0 {
+		secretsYAML = types.StringUnknown()
+	}
+
+	resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("secrets_yaml"), secretsYAML)...)
+
+	for _, name := range changedCAs {
+		tflog.Info(ctx, "certificate authority changed, swapping it in place", map[string]any{"ca": name})
+
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("machine_secrets").AtName("certs").AtName(name), types.ObjectUnknown(map[string]attr.Type{
+			"cert": types.StringType,
+			"key":  types.StringType,
+		}))...)
+
+		if name == "os" {
+			resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("client_configuration"), types.ObjectUnknown(clientConfigurationAttributeTypes()))...)
+		}
+	}
+
+	return false
+}
+
+func clientConfigurationAttributeTypes() map[string]attr.Type {
+	return map[string]attr.Type{
+		"ca_certificate":     types.StringType,
+		"client_certificate": types.StringType,
+		"client_key":         types.StringType,
+	}
+}
+
+func (r *talosMachineSecretsResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
 	var obj types.Object
 
-	resp.Diagnostics.Append(req.State.GetAttribute(ctx, clientConfigurationPath, &obj)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &obj)...)
 
 	if resp.Diagnostics.HasError() {
 		return
 	}
 
-	var clientConfigurationData clientConfiguration
+	var state talosMachineSecretsResourceModelV1
 
-	diags = obj.As(ctx, &clientConfigurationData, basetypes.ObjectAsOptions{
+	diags := obj.As(ctx, &state, basetypes.ObjectAsOptions{
 		UnhandledNullAsEmpty:    true,
 		UnhandledUnknownAsEmpty: true,
 	})
 	resp.Diagnostics.Append(diags...)
 
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &obj)...)
+
 	if resp.Diagnostics.HasError() {
 		return
 	}
 
-	if clientConfigurationData.CA.IsNull() || clientConfigurationData.CA.IsUnknown() {
+	var plan talosMachineSecretsResourceModelV1
+
+	diags = obj.As(ctx, &plan, basetypes.ObjectAsOptions{
+		UnhandledNullAsEmpty:    true,
+		UnhandledUnknownAsEmpty: true,
+	})
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
 		return
 	}
 
-	clientCertificate := clientConfigurationData.Cert.ValueString()
+	stateRoles, stateTTL, _, diags := clientCertificateOptionsAt(ctx, req.State.GetAttribute)
+	resp.Diagnostics.Append(diags...)
 
-	clientCertificateBytes, err := base64ToBytes(clientCertificate)
-	if err != nil {
-		resp.Diagnostics.AddError("failed to decode client certificate", err.Error())
+	planRoles, planTTL, _, diags := clientCertificateOptionsAt(ctx, req.Plan.GetAttribute)
+	resp.Diagnostics.Append(diags...)
+
+	stateCAs, diags := certificateAuthoritiesAt(ctx, req.State.GetAttribute)
+	resp.Diagnostics.Append(diags...)
 
+	planCAs, diags := certificateAuthoritiesAt(ctx, req.Plan.GetAttribute)
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
 		return
 	}
 
-	block, _ := pem.Decode(clientCertificateBytes)
-	if block == nil {
-		resp.Diagnostics.AddError("failed to decode client certificate", "failed to parse PEM block")
+	state.TalosVersion = plan.TalosVersion
+	state.ClientRoles = plan.ClientRoles
+	state.ClientCrtTTL = plan.ClientCrtTTL
+	state.CertificateAuthorities = plan.CertificateAuthorities
+	state.AgeRecipients = plan.AgeRecipients
 
-		return
+	if !state.EncryptedSecrets.IsNull() {
+		// the encrypted secrets are kept as they are
+		if len(plan.AgeRecipients) > 0 && !plan.EncryptedSecrets.IsUnknown() {
+			resp.Diagnostics.Append(setMachineSecretsState(ctx, &resp.State, state)...)
+
+			return
+		}
+
+		if err := decryptMachineSecrets(&state); err != nil {
+			resp.Diagnostics.AddError("failed to decrypt machine secrets", err.Error())
+
+			return
+		}
 	}
 
-	x509Cert, err := x509.ParseCertificate(block.Bytes)
+	secretsBundle, err := machineSecretsToSecretsBundle(state)
 	if err != nil {
-		resp.Diagnostics.AddError("failed to parse client certificate", err.Error())
+		resp.Diagnostics.AddError("failed to convert machine secrets to secrets bundle", err.Error())
 
 		return
 	}
 
-	// check if NotAfter expires in a month
-	if x509Cert.NotAfter.Before(OverridableTimeFunc().AddDate(0, 1, 0)) {
-		tflog.Info(ctx, "client certificate expires in a month, regenerating")
+	changedCAs := changedCertificateAuthorities(planCAs, stateCAs)
 
-		var obj types.Object
+	for _, name := range changedCAs {
+		ca := planCAs[name]
 
-		diags := req.State.Get(ctx, &obj)
-		resp.Diagnostics.Append(diags...)
+		pair, err := parseCertificateAuthority(name, ca.Cert.ValueString(), ca.Key.ValueString(), OverridableTimeFunc())
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("certificate_authorities").AtName(name), "invalid certificate authority", err.Error())
 
-		if resp.Diagnostics.HasError() {
 			return
 		}
 
-		var config talosMachineSecretsResourceModelV1
+		tflog.Info(ctx, "replacing certificate authority", map[string]any{"ca": name})
 
-		diags = obj.As(ctx, &config, basetypes.ObjectAsOptions{
-			UnhandledNullAsEmpty:    true,
-			UnhandledUnknownAsEmpty: true,
-		})
-		resp.Diagnostics.Append(diags...)
+		setBundleCertificateAuthority(secretsBundle, name, pair)
+	}
+
+	if len(changedCAs) > 0 {
+		updated, err := secretsBundleTomachineSecrets(secretsBundle)
+		if err != nil {
+			resp.Diagnostics.AddError("failed to convert secrets bundle to machine secrets", err.Error())
 
-		if resp.Diagnostics.HasError() {
 			return
 		}
 
-		secretsBundle, err := machineSecretsToSecretsBundle(config)
+		state.MachineSecrets.Certs = updated.MachineSecrets.Certs
+	}
+
+	regenerate := slices.Contains(changedCAs, "os") || !slices.Equal(stateRoles.Strings(), planRoles.Strings()) || stateTTL != planTTL
+
+	if !regenerate && !state.ClientConfiguration.Cert.IsNull() {
+		clientCertificateBytes, err := base64ToBytes(state.ClientConfiguration.Cert.ValueString())
 		if err != nil {
-			resp.Diagnostics.AddError("failed to convert machine secrets to secrets bundle", err.Error())
+			resp.Diagnostics.AddError("failed to decode client certificate", err.Error())
 
 			return
 		}
 
-		if secretsBundle.Clock == nil {
-			secretsBundle.Clock = secrets.NewFixedClock(time.Now())
+		block, _ := pem.Decode(clientCertificateBytes)
+		if block == nil {
+			resp.Diagnostics.AddError("failed to decode client certificate", "failed to parse PEM block")
+
+			return
 		}
 
-		state, err := secretsBundleTomachineSecrets(secretsBundle)
+		x509Cert, err := x509.ParseCertificate(block.Bytes)
 		if err != nil {
-			resp.Diagnostics.AddError("failed to convert secrets bundle to machine secrets", err.Error())
+			resp.Diagnostics.AddError("failed to parse client certificate", err.Error())
 
 			return
 		}
 
-		resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("client_configuration").AtName("ca_certificate"), &state.ClientConfiguration.CA)...)
-		resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("client_configuration").AtName("client_certificate"), &state.ClientConfiguration.Cert)...)
-		resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("client_configuration").AtName("client_key"), &state.ClientConfiguration.Key)...)
+		regenerate = clientCertificateExpiring(x509Cert, planTTL)
+	}
+
+	if regenerate {
+		tflog.Info(ctx, "client certificate expires soon or its CA, roles or TTL changed, regenerating")
+
+		state.ClientConfiguration, err = generateClientConfigurationWithTTL(secretsBundle, planRoles, planTTL)
+		if err != nil {
+			resp.Diagnostics.AddError("failed to generate client configuration", err.Error())
 
-		if resp.Diagnostics.HasError() {
 			return
 		}
 	}
+
+	secretsYAML, err := machineSecretsToSecretsYAML(state)
+	if err != nil {
+		resp.Diagnostics.AddError("failed to serialize machine secrets", err.Error())
+
+		return
+	}
+
+	state.SecretsYAML = types.StringValue(secretsYAML)
+
+	if err = encryptMachineSecrets(&state); err != nil {
+		resp.Diagnostics.AddError("failed to encrypt machine secrets", err.Error())
+
+		return
+	}
+
+	// Set state to fully populated data
+	diags = setMachineSecretsState(ctx, &resp.State, state)
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+}
+
+// setMachineSecretsState sets the state to the model, without the plain text secrets if they are encrypted.
+func setMachineSecretsState(ctx context.Context, state *tfsdk.State, model talosMachineSecretsResourceModelV1) diag.Diagnostics {
+	diags := state.Set(ctx, &model)
+
+	if diags.HasError() || model.EncryptedSecrets.IsNull() {
+		return diags
+	}
+
+	diags.Append(state.SetAttribute(ctx, path.Root("machine_secrets"), (*machineSecrets)(nil))...)
+	diags.Append(state.SetAttribute(ctx, path.Root("client_configuration"), (*clientConfiguration)(nil))...)
+	diags.Append(state.SetAttribute(ctx, path.Root("secrets_yaml"), types.StringNull())...)
+
+	return diags
+}
+
+// encryptMachineSecrets encrypts the secrets.yaml of the model to its age recipients, if any.
+func encryptMachineSecrets(model *talosMachineSecretsResourceModelV1) error {
+	if len(model.AgeRecipients) == 0 {
+		model.EncryptedSecrets = types.StringNull()
+
+		return nil
+	}
+
+	recipientValues := make([]string, 0, len(model.AgeRecipients))
+
+	for _, recipient := range model.AgeRecipients {
+		recipientValues = append(recipientValues, recipient.ValueString())
+	}
+
+	recipients, err := parseAgeRecipients(recipientValues)
+	if err != nil {
+		return err
+	}
+
+	encrypted, err := encryptSecretsYAML(model.SecretsYAML.ValueString(), recipients)
+	if err != nil {
+		return err
+	}
+
+	model.EncryptedSecrets = types.StringValue(encrypted)
+
+	return nil
+}
+
+// decryptMachineSecrets restores the plain text secrets of the model from its encrypted secrets,
+// with the age identities from the environment. The client configuration is generated anew with the defaults.
+func decryptMachineSecrets(model *talosMachineSecretsResourceModelV1) error {
+	secretsBundle, err := ageSecretsBundle(model.EncryptedSecrets.ValueString())
+	if err != nil {
+		return err
+	}
+
+	decrypted, err := secretsBundleTomachineSecrets(secretsBundle)
+	if err != nil {
+		return err
+	}
+
+	model.MachineSecrets = decrypted.MachineSecrets
+	model.ClientConfiguration = decrypted.ClientConfiguration
+	model.SecretsYAML = decrypted.SecretsYAML
+
+	return nil
+}
+
+// ageSecretsBundle decrypts a secrets.yaml encrypted by encryptSecretsYAML, with the age identities from the environment.
+func ageSecretsBundle(encrypted string) (*secrets.Bundle, error) {
+	identities, err := ageIdentitiesFromEnvironment()
+	if err != nil {
+		return nil, err
+	}
+
+	secretsYAML, err := decryptSecretsYAML(encrypted, identities)
+	if err != nil {
+		return nil, err
+	}
+
+	return secretsBundleFromYAML([]byte(secretsYAML))
+}
+
+// sopsSecretsBundle reads a secrets.yaml encrypted with SOPS, with the keys from the environment.
+func sopsSecretsBundle(fileName string) (*secrets.Bundle, error) {
+	encrypted, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	secretsYAML, err := decryptSOPSYAML(encrypted)
+	if err != nil {
+		return nil, err
+	}
+
+	return secretsBundleFromYAML(secretsYAML)
 }
 
 func (r *talosMachineSecretsResource) Delete(_ context.Context, _ resource.DeleteRequest, _ *resource.DeleteResponse) {
 }
 
+// clientCertificateOptions resolves the roles and TTL of the client certificate, applying the defaults for unset attributes.
+func clientCertificateOptions(roles []types.String, crtTTL types.String) (role.Set, time.Duration, error) {
+	ttl := constants.TalosAPIDefaultCertificateValidityDuration
+
+	// invalid durations are rejected by goDurationValid
+	if parsed, err := time.ParseDuration(crtTTL.ValueString()); err == nil {
+		ttl = parsed
+	}
+
+	roleSet, err := clientRolesToRoleSet(roles)
+
+	return roleSet, ttl, err
+}
+
+// clientCertificateOptionsAt reads client_roles and client_crt_ttl using getAttribute (a plan or state getter).
+// known is false if either attribute is unknown.
+func clientCertificateOptionsAt(
+	ctx context.Context,
+	getAttribute func(context.Context, path.Path, any) diag.Diagnostics,
+) (roles role.Set, ttl time.Duration, known bool, diags diag.Diagnostics) {
+	var (
+		clientRoles  types.List
+		clientCrtTTL types.String
+	)
+
+	diags.Append(getAttribute(ctx, path.Root("client_roles"), &clientRoles)...)
+	diags.Append(getAttribute(ctx, path.Root("client_crt_ttl"), &clientCrtTTL)...)
+
+	if diags.HasError() || clientRoles.IsUnknown() || clientCrtTTL.IsUnknown() {
+		return roles, ttl, false, diags
+	}
+
+	var roleValues []types.String
+
+	diags.Append(clientRoles.ElementsAs(ctx, &roleValues, false)...)
+
+	roles, ttl, err := clientCertificateOptions(roleValues, clientCrtTTL)
+	if err != nil {
+		diags.AddAttributeError(path.Root("client_roles"), "invalid client roles", err.Error())
+
+		return roles, ttl, false, diags
+	}
+
+	return roles, ttl, true, diags
+}
+
+// clientCertificateExpiring reports whether the client certificate should be renewed:
+// a month before it expires, or halfway through its lifetime for certificates with a TTL shorter than two months.
+func clientCertificateExpiring(cert *x509.Certificate, ttl time.Duration) bool {
+	now := OverridableTimeFunc()
+
+	renewAt := now.AddDate(0, 1, 0)
+	if halfLife := now.Add(ttl / 2); halfLife.Before(renewAt) {
+		renewAt = halfLife
+	}
+
+	return cert.NotAfter.Before(renewAt)
+}
+
 func talosMachineFeaturesVersionDefaults() planmodifier.String {
 	return &talosMachineFeaturesVersionPlanModifier{}
 }
@@ -582,11 +1170,7 @@ func (r *talosMachineSecretsResource) UpgradeState(_ context.Context) map[int64]
 					return
 				}
 
-				state.TalosVersion = basetypes.NewStringValue("v1.3")
-
-				if secretsBundle.Secrets.AESCBCEncryptionSecret != "" {
-					state.TalosVersion = basetypes.NewStringValue("v1.2")
-				}
+				state.TalosVersion = basetypes.NewStringValue(secretsBundleVersionContract(secretsBundle))
 
 				// Set state to fully populated data
 				diags = resp.State.Set(ctx, &state)
@@ -601,46 +1185,146 @@ func (r *talosMachineSecretsResource) UpgradeState(_ context.Context) map[int64]
 }
 
 func (r *talosMachineSecretsResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
-	id := req.ID
-
-	if _, err := os.Stat(id); err != nil {
-		resp.Diagnostics.AddError("failed to import state", err.Error())
+	secretsBundle, versionContract, err := importSecretsBundle(ctx, req.ID)
+	if err != nil {
+		resp.Diagnostics.AddError("failed to import machine secrets", err.Error())
 
 		return
 	}
 
-	secretBytes, err := os.ReadFile(id)
+	state, err := secretsBundleTomachineSecrets(secretsBundle)
 	if err != nil {
-		resp.Diagnostics.AddError("failed to read machine secrets file", err.Error())
+		resp.Diagnostics.AddError("failed to convert secrets bundle to machine secrets", err.Error())
 
 		return
 	}
 
-	var secretsBundle *secrets.Bundle
-	if err = yaml.Unmarshal(secretBytes, &secretsBundle); err != nil {
-		resp.Diagnostics.AddError("failed to unmarshal machine secrets", err.Error())
+	state.TalosVersion = basetypes.NewStringValue(versionContract)
+
+	// Set state to fully populated data
+	diags := resp.State.Set(ctx, &state)
+	resp.Diagnostics.Append(diags...)
 
+	if resp.Diagnostics.HasError() {
 		return
 	}
+}
 
-	state, err := secretsBundleTomachineSecrets(secretsBundle)
+// importSecretsBundle loads the secrets bundle for the import ID, which is one of:
+//   - `config:<path>`: a control plane machine configuration file,
+//   - `node:<address>`: the active machine configuration of a control plane node, using the default talosconfig for authentication,
+//   - `<path>`: a secrets.yaml file as generated by `talosctl gen secrets`.
+//
+// It also returns the Talos version contract for talos_version: the version the node runs for node imports,
+// otherwise the oldest contract the secrets could have been generated with.
+func importSecretsBundle(ctx context.Context, id string) (*secrets.Bundle, string, error) {
+	var (
+		cfg             config.Provider
+		versionContract string
+		err             error
+	)
+
+	switch {
+	case strings.HasPrefix(id, machineSecretsImportConfigPrefix):
+		cfg, err = configloader.NewFromFile(strings.TrimPrefix(id, machineSecretsImportConfigPrefix))
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to load machine configuration: %w", err)
+		}
+	case strings.HasPrefix(id, machineSecretsImportNodePrefix):
+		cfg, versionContract, err = fetchActiveMachineConfiguration(ctx, strings.TrimPrefix(id, machineSecretsImportNodePrefix))
+		if err != nil {
+			return nil, "", err
+		}
+	default:
+		secretBytes, err := os.ReadFile(id)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to read machine secrets file: %w", err)
+		}
+
+		secretsBundle, err := secretsBundleFromYAML(secretBytes)
+		if err != nil {
+			return nil, "", err
+		}
+
+		return secretsBundle, secretsBundleVersionContract(secretsBundle), nil
+	}
+
+	secretsBundle, err := secrets.NewBundleFromConfig(secrets.NewFixedClock(time.Now()), cfg)
 	if err != nil {
-		resp.Diagnostics.AddError("failed to convert secrets bundle to machine secrets", err.Error())
+		return nil, "", fmt.Errorf("failed to extract machine secrets from machine configuration: %w", err)
+	}
 
-		return
+	// worker configurations don't carry the CA keys
+	if err = validateSecretsBundle(secretsBundle); err != nil {
+		return nil, "", fmt.Errorf("%w, import the configuration of a control plane node", err)
 	}
 
-	state.TalosVersion = basetypes.NewStringValue("v1.3")
+	if versionContract == "" {
+		versionContract = secretsBundleVersionContract(secretsBundle)
+	}
 
-	if secretsBundle.Secrets.AESCBCEncryptionSecret != "" {
-		state.TalosVersion = basetypes.NewStringValue("v1.2")
+	return secretsBundle, versionContract, nil
+}
+
+// fetchActiveMachineConfiguration reads the active machine configuration of the node over COSI,
+// along with the version contract of the Talos version the node runs.
+func fetchActiveMachineConfiguration(ctx context.Context, address string) (config.Provider, string, error) {
+	ctx, cancel := context.WithTimeout(ctx, machineSecretsImportTimeout)
+	defer cancel()
+
+	c, err := client.New(ctx, client.WithDefaultConfig(), client.WithEndpoints(address))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create talos client: %w", err)
 	}
 
-	// Set state to fully populated data
-	diags := resp.State.Set(ctx, &state)
-	resp.Diagnostics.Append(diags...)
+	defer c.Close() //nolint:errcheck
 
-	if resp.Diagnostics.HasError() {
-		return
+	mc, err := safe.StateGetByID[*configresource.MachineConfig](ctx, c.COSI, configresource.ActiveID)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to fetch machine configuration of node %s: %w", address, err)
+	}
+
+	versionResp, err := c.Version(ctx)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to fetch Talos version of node %s: %w", address, err)
+	}
+
+	if len(versionResp.Messages) == 0 {
+		return nil, "", fmt.Errorf("node %s returned no Talos version", address)
 	}
+
+	versionContract, err := talosVersionTagContract(versionResp.Messages[0].Version.Tag)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to parse Talos version of node %s: %w", address, err)
+	}
+
+	return mc.Provider(), versionContract, nil
+}
+
+// talosVersionTagContract returns the talos_version contract (`vX.Y`) of a Talos version tag such as `v1.10.3`.
+func talosVersionTagContract(tag string) (string, error) {
+	versionContract, err := validateVersionContract(tag)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("v%d.%d", versionContract.Major, versionContract.Minor), nil
+}
+
+// secretsBundleVersionContract guesses the oldest Talos version contract the secrets could have been generated with from the key material,
+// so that imported secrets are not replaced unless talos_version is explicitly lowered below it:
+// secrets generated for Talos < 1.3 use AES-CBC instead of secretbox encryption,
+// and Talos >= 1.7 generates an RSA instead of an ECDSA service account key.
+func secretsBundleVersionContract(secretsBundle *secrets.Bundle) string {
+	if secretsBundle.Secrets.AESCBCEncryptionSecret != "" {
+		return "v1.2"
+	}
+
+	if secretsBundle.Certs.K8sServiceAccount != nil {
+		if block, _ := pem.Decode(secretsBundle.Certs.K8sServiceAccount.Key); block != nil && block.Type == x509crypto.PEMTypeRSAPrivate {
+			return "v1.7"
+		}
+	}
+
+	return "v1.3"
 }
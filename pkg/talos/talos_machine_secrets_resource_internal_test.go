@@ -0,0 +1,272 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package talos //nolint:testpackage // needs access to unexported machine secrets helpers
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/siderolabs/talos/pkg/machinery/config"
+	"github.com/siderolabs/talos/pkg/machinery/config/generate"
+	"github.com/siderolabs/talos/pkg/machinery/config/generate/secrets"
+	"github.com/siderolabs/talos/pkg/machinery/config/machine"
+	"github.com/siderolabs/talos/pkg/machinery/constants"
+	"github.com/siderolabs/talos/pkg/machinery/gendata"
+	"github.com/siderolabs/talos/pkg/machinery/role"
+	"go.yaml.in/yaml/v4"
+	"golang.org/x/mod/semver"
+)
+
+func parseTestClientCertificate(t *testing.T, cc clientConfiguration) *x509.Certificate {
+	t.Helper()
+
+	certBytes, err := base64ToBytes(cc.Cert.ValueString())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	block, _ := pem.Decode(certBytes)
+	if block == nil {
+		t.Fatal("failed to decode client certificate PEM")
+	}
+
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return cert
+}
+
+func TestGenerateClientConfigurationRoles(t *testing.T) {
+	t.Parallel()
+
+	secretsBundle, err := secrets.NewBundle(secrets.NewFixedClock(time.Now()), config.TalosVersionCurrent)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	notBefore := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	notAfter := notBefore.Add(24 * time.Hour)
+
+	adminRoles, err := clientRolesToRoleSet(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	readerRoles, err := clientRolesToRoleSet([]types.String{types.StringValue("os:reader")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = clientRolesToRoleSet([]types.String{types.StringValue("os:unknown")}); err == nil {
+		t.Error("expected an unknown role to be rejected")
+	}
+
+	admin, err := generateClientConfiguration(secretsBundle, "test", adminRoles, notBefore, notAfter)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reader, err := generateClientConfiguration(secretsBundle, "test", readerRoles, notBefore, notAfter)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if org := parseTestClientCertificate(t, admin).Subject.Organization; !slices.Equal(org, []string{"os:admin"}) {
+		t.Errorf("admin certificate organization = %q", org)
+	}
+
+	if org := parseTestClientCertificate(t, reader).Subject.Organization; !slices.Equal(org, []string{"os:reader"}) {
+		t.Errorf("reader certificate organization = %q", org)
+	}
+
+	if admin.Key.ValueString() == reader.Key.ValueString() {
+		t.Error("expected certificates with different roles to use different keys")
+	}
+
+	again, err := generateClientConfiguration(secretsBundle, "test", role.MakeSet(role.Reader), notBefore, notAfter)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if again != reader {
+		t.Error("expected the generated client configuration to be deterministic")
+	}
+}
+
+func TestClientCertificateExpiring(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+
+	for _, test := range []struct {
+		name     string
+		notAfter time.Time
+		ttl      time.Duration
+		expected bool
+	}{
+		{name: "default ttl, months left", notAfter: now.AddDate(0, 6, 0), ttl: 365 * 24 * time.Hour},
+		{name: "default ttl, weeks left", notAfter: now.AddDate(0, 0, 20), ttl: 365 * 24 * time.Hour, expected: true},
+		{name: "short ttl, most left", notAfter: now.Add(20 * time.Hour), ttl: 24 * time.Hour},
+		{name: "short ttl, less than half left", notAfter: now.Add(10 * time.Hour), ttl: 24 * time.Hour, expected: true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := clientCertificateExpiring(&x509.Certificate{NotAfter: test.notAfter}, test.ttl); got != test.expected {
+				t.Errorf("clientCertificateExpiring() = %v, want %v", got, test.expected)
+			}
+		})
+	}
+}
+
+func TestMachineSecretsYAMLRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	for _, version := range []string{"v1.1", "v1.2", "v1.3", semver.MajorMinor(gendata.VersionTag)} {
+		t.Run(version, func(t *testing.T) {
+			t.Parallel()
+
+			versionContract, err := validateVersionContract(version)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			secretsBundle, err := secrets.NewBundle(secrets.NewFixedClock(time.Now()), versionContract)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			model, err := secretsBundleTomachineSecrets(secretsBundle)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// same as ImportState
+			var imported *secrets.Bundle
+			if err = yaml.Unmarshal([]byte(model.SecretsYAML.ValueString()), &imported); err != nil {
+				t.Fatal(err)
+			}
+
+			importedModel, err := secretsBundleTomachineSecrets(imported)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if importedModel.MachineSecrets != model.MachineSecrets {
+				t.Error("expected the imported machine secrets to match")
+			}
+
+			if importedModel.SecretsYAML != model.SecretsYAML {
+				t.Error("expected secrets_yaml to round-trip exactly")
+			}
+		})
+	}
+}
+
+func TestImportSecretsBundleFromConfig(t *testing.T) {
+	t.Parallel()
+
+	for _, version := range []string{"v1.2", "v1.6", "v1.7", semver.MajorMinor(gendata.VersionTag)} {
+		t.Run(version, func(t *testing.T) {
+			t.Parallel()
+
+			versionContract, err := validateVersionContract(version)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			secretsBundle, err := secrets.NewBundle(secrets.NewFixedClock(time.Now()), versionContract)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			input, err := generate.NewInput("import-test", "https://10.5.0.2:6443", constants.DefaultKubernetesVersion,
+				generate.WithVersionContract(versionContract),
+				generate.WithSecretsBundle(secretsBundle),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			dir := t.TempDir()
+
+			for _, machineType := range []machine.Type{machine.TypeControlPlane, machine.TypeWorker} {
+				cfg, err := input.Config(machineType)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				cfgBytes, err := cfg.EncodeBytes()
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if err = os.WriteFile(filepath.Join(dir, machineType.String()+".yaml"), cfgBytes, 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if _, _, err = importSecretsBundle(t.Context(), "config:"+filepath.Join(dir, machine.TypeWorker.String()+".yaml")); err == nil {
+				t.Error("expected importing a worker configuration to fail")
+			}
+
+			imported, importedVersion, err := importSecretsBundle(t.Context(), "config:"+filepath.Join(dir, machine.TypeControlPlane.String()+".yaml"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			expected, err := secretsBundleTomachineSecrets(secretsBundle)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			actual, err := secretsBundleTomachineSecrets(imported)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if actual.MachineSecrets != expected.MachineSecrets {
+				t.Error("expected the machine secrets extracted from the configuration to match")
+			}
+
+			// the oldest contract generating the same secrets
+			expectedVersion := map[string]string{"v1.2": "v1.2", "v1.6": "v1.3"}[version]
+			if expectedVersion == "" {
+				expectedVersion = "v1.7"
+			}
+
+			if importedVersion != expectedVersion {
+				t.Errorf("detected version contract %s, expected %s", importedVersion, expectedVersion)
+			}
+		})
+	}
+}
+
+func TestTalosVersionTagContract(t *testing.T) {
+	t.Parallel()
+
+	for tag, expected := range map[string]string{
+		"v1.10.3":        "v1.10",
+		"v1.12.0-beta.1": "v1.12",
+		"1.7.0":          "v1.7",
+	} {
+		versionContract, err := talosVersionTagContract(tag)
+		if err != nil {
+			t.Fatalf("talosVersionTagContract(%q): %v", tag, err)
+		}
+
+		if versionContract != expected {
+			t.Errorf("talosVersionTagContract(%q) = %s, expected %s", tag, versionContract, expected)
+		}
+	}
+}
@@ -9,8 +9,10 @@ import (
 	"testing"
 	"time"
 
+	"filippo.io/age"
 	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
 	"github.com/hashicorp/terraform-plugin-testing/plancheck"
+	"github.com/hashicorp/terraform-plugin-testing/tfversion"
 	"github.com/siderolabs/talos/pkg/machinery/gendata"
 	"golang.org/x/mod/semver"
 
@@ -36,6 +38,7 @@ func TestAccTalosMachineSecretsResource(t *testing.T) {
 					resource.TestCheckResourceAttrSet("talos_machine_secrets.this", "machine_secrets.secrets.bootstrap_token"),
 					resource.TestCheckResourceAttrSet("talos_machine_secrets.this", "machine_secrets.secrets.secretbox_encryption_secret"),
 					resource.TestCheckResourceAttrSet("talos_machine_secrets.this", "machine_secrets.trustdinfo.token"),
+					resource.TestCheckResourceAttrSet("talos_machine_secrets.this", "secrets_yaml"),
 					resource.TestCheckResourceAttrSet("talos_machine_secrets.this", "machine_secrets.certs.etcd.cert"),
 					resource.TestCheckResourceAttrSet("talos_machine_secrets.this", "machine_secrets.certs.etcd.key"),
 					resource.TestCheckResourceAttrSet("talos_machine_secrets.this", "machine_secrets.certs.k8s.cert"),
@@ -434,6 +437,96 @@ func TestAccTalosMachineSecretsResourceUpgrade3(t *testing.T) { //nolint:dupl
 	})
 }
 
+func TestAccTalosMachineSecretsResourceAgeRecipients(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("SOPS_AGE_KEY", identity.String())
+
+	encryptedConfig := fmt.Sprintf(`
+resource "talos_machine_secrets" "this" {
+	age_recipients = [%q]
+}
+`, identity.Recipient().String())
+
+	resource.Test(t, resource.TestCase{
+		IsUnitTest:               true, // this is a local only resource, so can be unit tested
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: encryptedConfig,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttrSet("talos_machine_secrets.this", "encrypted_secrets"),
+					resource.TestCheckNoResourceAttr("talos_machine_secrets.this", "machine_secrets.cluster.id"),
+					resource.TestCheckNoResourceAttr("talos_machine_secrets.this", "client_configuration.client_key"),
+					resource.TestCheckNoResourceAttr("talos_machine_secrets.this", "secrets_yaml"),
+				),
+			},
+			{
+				Config:   encryptedConfig,
+				PlanOnly: true,
+			},
+			// decrypt the secrets back into the state
+			{
+				Config: testAccTalosMachineSecretsResourceConfig(""),
+				ConfigPlanChecks: resource.ConfigPlanChecks{
+					PreApply: []plancheck.PlanCheck{
+						plancheck.ExpectResourceAction("talos_machine_secrets.this", plancheck.ResourceActionUpdate),
+					},
+				},
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckNoResourceAttr("talos_machine_secrets.this", "encrypted_secrets"),
+					resource.TestCheckResourceAttrSet("talos_machine_secrets.this", "machine_secrets.cluster.id"),
+					resource.TestCheckResourceAttrSet("talos_machine_secrets.this", "client_configuration.client_key"),
+					resource.TestCheckResourceAttrSet("talos_machine_secrets.this", "secrets_yaml"),
+				),
+			},
+			{
+				Config: encryptedConfig,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttrSet("talos_machine_secrets.this", "encrypted_secrets"),
+					resource.TestCheckNoResourceAttr("talos_machine_secrets.this", "secrets_yaml"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccTalosMachineSecretsResourceSeed(t *testing.T) {
+	resource.ParallelTest(t, resource.TestCase{
+		IsUnitTest:               true, // this is a local only resource, so can be unit tested
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		TerraformVersionChecks: []tfversion.TerraformVersionCheck{
+			tfversion.SkipBelow(tfversion.Version1_11_0),
+		},
+		Steps: []resource.TestStep{
+			{
+				Config: `
+resource "talos_machine_secrets" "this" {
+	talos_version   = "v1.6"
+	seed_wo         = "0123456789abcdef0123456789abcdef"
+	seed_not_before = "2025-01-01T00:00:00Z"
+}
+
+resource "talos_machine_secrets" "again" {
+	talos_version   = "v1.6"
+	seed_wo         = "0123456789abcdef0123456789abcdef"
+	seed_not_before = "2025-01-01T00:00:00Z"
+}
+`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("talos_machine_secrets.this", "machine_secrets.secrets.bootstrap_token", "3t64nu.cpiybp3gcbdlgps6"),
+					resource.TestCheckNoResourceAttr("talos_machine_secrets.this", "seed_wo"),
+					resource.TestCheckResourceAttr("talos_machine_secrets.this", "seed_not_before", "2025-01-01T00:00:00Z"),
+					resource.TestCheckResourceAttrPair("talos_machine_secrets.this", "secrets_yaml", "talos_machine_secrets.again", "secrets_yaml"),
+				),
+			},
+		},
+	})
+}
+
 func testAccTalosMachineSecretsResourceConfig(talosConfigVersion string) string {
 	if talosConfigVersion != "" {
 		return fmt.Sprintf(`
@@ -0,0 +1,364 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package talos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework-timeouts/resource/timeouts"
+	"github.com/hashicorp/terraform-plugin-framework-validators/listvalidator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-framework/types/basetypes"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/retry"
+	machineapi "github.com/siderolabs/talos/pkg/machinery/api/machine"
+	"github.com/siderolabs/talos/pkg/machinery/client"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// serviceStates are the states a Talos service can report.
+var serviceStates = []string{"Initialized", "Preparing", "Waiting", "Running", "Stopping", "Finished", "Failed", "Skipped", "Starting"}
+
+type talosMachineServicesDataSource struct{}
+
+var _ datasource.DataSource = &talosMachineServicesDataSource{}
+
+type talosMachineServicesDataSourceModelV0 struct { //nolint:govet
+	ID                  types.String          `tfsdk:"id"`
+	Node                types.String          `tfsdk:"node"`
+	Endpoint            types.String          `tfsdk:"endpoint"`
+	ClientConfiguration clientConfiguration   `tfsdk:"client_configuration"`
+	WaitFor             *serviceWaitForModel  `tfsdk:"wait_for"`
+	Services            []machineServiceModel `tfsdk:"services"`
+	Timeouts            timeouts.Value        `tfsdk:"timeouts"`
+}
+
+type serviceWaitForModel struct {
+	Services []types.String `tfsdk:"services"`
+	State    types.String   `tfsdk:"state"`
+	Healthy  types.Bool     `tfsdk:"healthy"`
+}
+
+type machineServiceModel struct {
+	ID               types.String `tfsdk:"id"`
+	State            types.String `tfsdk:"state"`
+	Healthy          types.Bool   `tfsdk:"healthy"`
+	HealthMessage    types.String `tfsdk:"health_message"`
+	LastEventMessage types.String `tfsdk:"last_event_message"`
+	LastEventState   types.String `tfsdk:"last_event_state"`
+	LastEventTime    types.String `tfsdk:"last_event_time"`
+	RestartCount     types.Int64  `tfsdk:"restart_count"`
+}
+
+// NewTalosMachineServicesDataSource implements the datasource.DataSource interface.
+func NewTalosMachineServicesDataSource() datasource.DataSource {
+	return &talosMachineServicesDataSource{}
+}
+
+func (d *talosMachineServicesDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_machine_services"
+}
+
+func (d *talosMachineServicesDataSource) Schema(ctx context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Lists the services of a Talos node, optionally waiting for some of them to become healthy",
+		MarkdownDescription: "Lists the services of a Talos node, optionally waiting for some of them to become healthy. " +
+			"With `wait_for` set the read polls the node until every listed service is in the expected state or the read timeout expires, " +
+			"which makes the data source usable as a gate for resources that need e.g. `etcd` or `kubelet` to be up.",
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed: true,
+			},
+			"node": schema.StringAttribute{
+				Required:    true,
+				Description: "node to list the services of",
+			},
+			"endpoint": schema.StringAttribute{
+				Optional:    true,
+				Computed:    true,
+				Description: "endpoint to use for the talosclient. If not set, the node value will be used",
+			},
+			"client_configuration": schema.SingleNestedAttribute{
+				Attributes: map[string]schema.Attribute{
+					"ca_certificate": schema.StringAttribute{
+						Required:    true,
+						Description: "The client CA certificate",
+					},
+					"client_certificate": schema.StringAttribute{
+						Required:    true,
+						Description: "The client certificate",
+					},
+					"client_key": schema.StringAttribute{
+						Required:    true,
+						Sensitive:   true,
+						Description: "The client key",
+					},
+				},
+				Required:    true,
+				Description: "The client configuration data",
+			},
+			"wait_for": schema.SingleNestedAttribute{
+				Optional:    true,
+				Description: "Wait until the listed services reach the given state before returning",
+				Attributes: map[string]schema.Attribute{
+					"services": schema.ListAttribute{
+						ElementType: types.StringType,
+						Required:    true,
+						Description: "The IDs of the services to wait for, e.g. `etcd`, `kubelet` or `ext-iscsid`. Services that don't exist yet are waited for as well",
+						Validators: []validator.List{
+							listvalidator.SizeAtLeast(1),
+						},
+					},
+					"state": schema.StringAttribute{
+						Optional:    true,
+						Description: "The state the services have to be in. Defaults to `Running`",
+						Validators: []validator.String{
+							stringvalidator.OneOf(serviceStates...),
+						},
+					},
+					"healthy": schema.BoolAttribute{
+						Optional: true,
+						Description: "Whether the services also have to pass their health check. Defaults to `true`. " +
+							"Set to `false` for services without a health check, which never report being healthy",
+					},
+				},
+			},
+			"services": schema.ListNestedAttribute{
+				Computed:    true,
+				Description: "The services of the node, sorted by ID",
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"id": schema.StringAttribute{
+							Computed:    true,
+							Description: "The service ID",
+						},
+						"state": schema.StringAttribute{
+							Computed:    true,
+							Description: "The service state, e.g. `Running` or `Waiting`",
+						},
+						"healthy": schema.BoolAttribute{
+							Computed:    true,
+							Description: "Whether the service passes its health check. Null if the health is unknown, e.g. for services without a health check",
+						},
+						"health_message": schema.StringAttribute{
+							Computed:    true,
+							Description: "The last message of the health check",
+						},
+						"last_event_message": schema.StringAttribute{
+							Computed:    true,
+							Description: "The message of the last service event",
+						},
+						"last_event_state": schema.StringAttribute{
+							Computed:    true,
+							Description: "The state of the last service event",
+						},
+						"last_event_time": schema.StringAttribute{
+							Computed:    true,
+							Description: "The time of the last service event in RFC3339 format",
+						},
+						"restart_count": schema.Int64Attribute{
+							Computed:    true,
+							Description: "How many times the service was restarted, as far as the event history kept by the node goes back",
+						},
+					},
+				},
+			},
+			"timeouts": timeouts.Attributes(ctx, timeouts.Opts{
+				Read: true,
+			}),
+		},
+	}
+}
+
+func (d *talosMachineServicesDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var obj types.Object
+
+	diags := req.Config.Get(ctx, &obj)
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var state talosMachineServicesDataSourceModelV0
+
+	diags = obj.As(ctx, &state, basetypes.ObjectAsOptions{
+		UnhandledNullAsEmpty:    true,
+		UnhandledUnknownAsEmpty: true,
+	})
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	talosConfig, err := talosClientTFConfigToTalosClientConfig(
+		"dynamic",
+		state.ClientConfiguration.CA.ValueString(),
+		state.ClientConfiguration.Cert.ValueString(),
+		state.ClientConfiguration.Key.ValueString(),
+	)
+	if err != nil {
+		resp.Diagnostics.AddError("failed to generate talos config", err.Error())
+
+		return
+	}
+
+	if state.Endpoint.IsNull() {
+		state.Endpoint = state.Node
+	}
+
+	readTimeout, diags := state.Timeouts.Read(ctx, 10*time.Minute)
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctxDeadline, cancel := context.WithTimeout(ctx, readTimeout)
+	defer cancel()
+
+	var services []*machineapi.ServiceInfo
+
+	if err = retry.RetryContext(ctxDeadline, readTimeout, func() *retry.RetryError {
+		if clientOpErr := talosClientOp(ctxDeadline, state.Endpoint.ValueString(), state.Node.ValueString(), talosConfig, func(nodeCtx context.Context, c *client.Client) error {
+			list, listErr := c.ServiceList(nodeCtx)
+			if listErr != nil {
+				return listErr
+			}
+
+			if len(list.GetMessages()) == 0 {
+				return errors.New("empty service list response")
+			}
+
+			services = list.GetMessages()[0].GetServices()
+
+			return nil
+		}); clientOpErr != nil {
+			if s := status.Code(clientOpErr); s == codes.InvalidArgument || s == codes.PermissionDenied {
+				return retry.NonRetryableError(clientOpErr)
+			}
+
+			return retry.RetryableError(clientOpErr)
+		}
+
+		if state.WaitFor != nil {
+			if pending := pendingServices(services, state.WaitFor); len(pending) > 0 {
+				return retry.RetryableError(fmt.Errorf("waiting for services: %s", strings.Join(pending, ", ")))
+			}
+		}
+
+		return nil
+	}); err != nil {
+		resp.Diagnostics.AddError("failed to list machine services", err.Error())
+
+		return
+	}
+
+	slices.SortFunc(services, func(a, b *machineapi.ServiceInfo) int {
+		return strings.Compare(a.GetId(), b.GetId())
+	})
+
+	state.Services = make([]machineServiceModel, 0, len(services))
+
+	for _, svc := range services {
+		state.Services = append(state.Services, machineServiceToTFTypes(svc))
+	}
+
+	state.ID = state.Node
+
+	diags = resp.State.Set(ctx, &state)
+	resp.Diagnostics.Append(diags...)
+}
+
+// pendingServices returns a description of every service in waitFor that hasn't reached the expected state yet.
+func pendingServices(services []*machineapi.ServiceInfo, waitFor *serviceWaitForModel) []string {
+	wantState := "Running"
+	if !waitFor.State.IsNull() && waitFor.State.ValueString() != "" {
+		wantState = waitFor.State.ValueString()
+	}
+
+	wantHealthy := waitFor.Healthy.IsNull() || waitFor.Healthy.ValueBool()
+
+	var pending []string
+
+	for _, id := range waitFor.Services {
+		idx := slices.IndexFunc(services, func(svc *machineapi.ServiceInfo) bool { return svc.GetId() == id.ValueString() })
+		if idx == -1 {
+			pending = append(pending, id.ValueString()+" (not found)")
+
+			continue
+		}
+
+		svc := services[idx]
+
+		switch {
+		case svc.GetState() != wantState:
+			pending = append(pending, fmt.Sprintf("%s (%s)", svc.GetId(), svc.GetState()))
+		case wantHealthy && (svc.GetHealth().GetUnknown() || !svc.GetHealth().GetHealthy()):
+			pending = append(pending, svc.GetId()+" (unhealthy)")
+		}
+	}
+
+	return pending
+}
+
+func machineServiceToTFTypes(svc *machineapi.ServiceInfo) machineServiceModel {
+	model := machineServiceModel{
+		ID:               types.StringValue(svc.GetId()),
+		State:            types.StringValue(svc.GetState()),
+		Healthy:          types.BoolNull(),
+		HealthMessage:    stringValueOrNull(svc.GetHealth().GetLastMessage()),
+		LastEventMessage: types.StringNull(),
+		LastEventState:   types.StringNull(),
+		LastEventTime:    types.StringNull(),
+		RestartCount:     types.Int64Value(serviceRestartCount(svc.GetEvents().GetEvents())),
+	}
+
+	if health := svc.GetHealth(); health != nil && !health.GetUnknown() {
+		model.Healthy = types.BoolValue(health.GetHealthy())
+	}
+
+	if events := svc.GetEvents().GetEvents(); len(events) > 0 {
+		last := events[len(events)-1]
+
+		model.LastEventMessage = types.StringValue(last.GetMsg())
+		model.LastEventState = types.StringValue(last.GetState())
+
+		if last.GetTs() != nil {
+			model.LastEventTime = types.StringValue(last.GetTs().AsTime().UTC().Format(time.RFC3339))
+		}
+	}
+
+	return model
+}
+
+// serviceRestartCount counts how often the service went back to running after it had been running before.
+// Health check results are reported as events in the running state, so only transitions into it are counted.
+func serviceRestartCount(events []*machineapi.ServiceEvent) int64 {
+	var (
+		runs int64
+		prev string
+	)
+
+	for _, event := range events {
+		if event.GetState() == "Running" && prev != "Running" {
+			runs++
+		}
+
+		prev = event.GetState()
+	}
+
+	return max(runs-1, 0)
+}
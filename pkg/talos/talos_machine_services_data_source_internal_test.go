@@ -0,0 +1,93 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package talos //nolint:testpackage // needs access to unexported service helpers
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	machineapi "github.com/siderolabs/talos/pkg/machinery/api/machine"
+)
+
+func TestPendingServices(t *testing.T) {
+	t.Parallel()
+
+	services := []*machineapi.ServiceInfo{
+		{Id: "etcd", State: "Preparing", Health: &machineapi.ServiceHealth{Unknown: true}},
+		{Id: "kubelet", State: "Running", Health: &machineapi.ServiceHealth{Healthy: true}},
+		{Id: "cri", State: "Running", Health: &machineapi.ServiceHealth{Healthy: false}},
+		{Id: "ext-iscsid", State: "Running", Health: &machineapi.ServiceHealth{Unknown: true}},
+	}
+
+	ids := func(ids ...string) []types.String {
+		out := make([]types.String, 0, len(ids))
+		for _, id := range ids {
+			out = append(out, types.StringValue(id))
+		}
+
+		return out
+	}
+
+	for _, tc := range []struct {
+		name    string
+		waitFor serviceWaitForModel
+		want    []string
+	}{
+		{
+			name:    "defaults",
+			waitFor: serviceWaitForModel{Services: ids("etcd", "kubelet", "cri", "missing")},
+			want:    []string{"etcd (Preparing)", "cri (unhealthy)", "missing (not found)"},
+		},
+		{
+			name:    "health not required",
+			waitFor: serviceWaitForModel{Services: ids("ext-iscsid", "cri"), Healthy: types.BoolValue(false)},
+		},
+		{
+			name:    "health required for service without check",
+			waitFor: serviceWaitForModel{Services: ids("ext-iscsid")},
+			want:    []string{"ext-iscsid (unhealthy)"},
+		},
+		{
+			name:    "custom state",
+			waitFor: serviceWaitForModel{Services: ids("etcd", "kubelet"), State: types.StringValue("Preparing"), Healthy: types.BoolValue(false)},
+			want:    []string{"kubelet (Running)"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := pendingServices(services, &tc.waitFor); !slices.Equal(got, tc.want) {
+				t.Errorf("pendingServices() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestServiceRestartCount(t *testing.T) {
+	t.Parallel()
+
+	events := func(states ...string) []*machineapi.ServiceEvent {
+		out := make([]*machineapi.ServiceEvent, 0, len(states))
+		for _, state := range states {
+			out = append(out, &machineapi.ServiceEvent{State: state})
+		}
+
+		return out
+	}
+
+	for _, tc := range []struct {
+		events []*machineapi.ServiceEvent
+		want   int64
+	}{
+		{events: nil, want: 0},
+		{events: events("Preparing", "Running", "Running"), want: 0},
+		{events: events("Preparing", "Running", "Waiting", "Running", "Running", "Failed", "Running"), want: 2},
+	} {
+		if got := serviceRestartCount(tc.events); got != tc.want {
+			t.Errorf("serviceRestartCount() = %d, want %d", got, tc.want)
+		}
+	}
+}
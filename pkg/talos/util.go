@@ -15,14 +15,19 @@ import (
 	"crypto/x509/pkix"
 	"encoding/base64"
 	"encoding/pem"
+	"errors"
 	"fmt"
 	"math/big"
 	"net/url"
+	"slices"
 	"strings"
 	"time"
 
+	"github.com/hashicorp/terraform-plugin-framework-validators/listvalidator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
 	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
 	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-framework/types/basetypes"
 	"github.com/siderolabs/crypto/x509"
 	sideronet "github.com/siderolabs/net"
 	"github.com/siderolabs/talos/cmd/talosctl/pkg/talos/action"
@@ -38,6 +43,7 @@ import (
 	"github.com/siderolabs/talos/pkg/machinery/config/machine"
 	"github.com/siderolabs/talos/pkg/machinery/constants"
 	"github.com/siderolabs/talos/pkg/machinery/role"
+	"go.yaml.in/yaml/v4"
 	"golang.org/x/crypto/hkdf"
 )
 
@@ -174,9 +180,10 @@ func secretsBundleTomachineSecrets(secretsBundle *secrets.Bundle) (talosMachineS
 				},
 			},
 		},
+		CertificateAuthorities: types.ObjectNull(certificateAuthoritiesAttributeTypes()),
 	}
 
-	if secretsBundle.Certs.K8sAggregator.Crt != nil {
+	if secretsBundle.Certs.K8sAggregator != nil && secretsBundle.Certs.K8sAggregator.Crt != nil {
 		model.MachineSecrets.Certs.K8sAggregator.Cert = types.StringValue(bytesToBase64(secretsBundle.Certs.K8sAggregator.Crt))
 		model.MachineSecrets.Certs.K8sAggregator.Key = types.StringValue(bytesToBase64(secretsBundle.Certs.K8sAggregator.Key))
 	}
@@ -186,24 +193,31 @@ func secretsBundleTomachineSecrets(secretsBundle *secrets.Bundle) (talosMachineS
 		model.MachineSecrets.Secrets.AESCBCEncryptionSecret = types.StringValue(secretsBundle.Secrets.AESCBCEncryptionSecret)
 	}
 
-	cc, err := generateClientConfigurationWithTTL(secretsBundle, constants.TalosAPIDefaultCertificateValidityDuration)
+	cc, err := generateClientConfigurationWithTTL(secretsBundle, role.MakeSet(role.Admin), constants.TalosAPIDefaultCertificateValidityDuration)
 	if err != nil {
 		return model, err
 	}
 
 	model.ClientConfiguration = cc
 
+	secretsYAML, err := machineSecretsToSecretsYAML(model)
+	if err != nil {
+		return model, err
+	}
+
+	model.SecretsYAML = types.StringValue(secretsYAML)
+
 	return model, nil
 }
 
 // generateClientConfigurationWithTTL generates a clientConfiguration with a TTL-based cert.
 // Used by the managed talos_machine_secrets resource which stores and renews certs in state.
-func generateClientConfigurationWithTTL(secretsBundle *secrets.Bundle, ttl time.Duration) (clientConfiguration, error) {
+func generateClientConfigurationWithTTL(secretsBundle *secrets.Bundle, roles role.Set, ttl time.Duration) (clientConfiguration, error) {
 	if secretsBundle.Clock == nil {
 		secretsBundle.Clock = secrets.NewFixedClock(time.Now())
 	}
 
-	clientcert, err := secretsBundle.GenerateTalosAPIClientCertificateWithTTL(role.MakeSet(role.Admin), ttl)
+	clientcert, err := secretsBundle.GenerateTalosAPIClientCertificateWithTTL(roles, ttl)
 	if err != nil {
 		return clientConfiguration{}, err
 	}
@@ -217,13 +231,13 @@ func generateClientConfigurationWithTTL(secretsBundle *secrets.Bundle, ttl time.
 
 // generateClientConfiguration generates a clientConfiguration from a secrets bundle.
 //
-// The admin client certificate and key are derived deterministically using HKDF
+// The client certificate and key are derived deterministically using HKDF
 // (RFC 5869) seeded from the OS CA private key and all cert-relevant inputs.
 // Same inputs always produce byte-identical output — no crypto/rand is used.
 //
 // Supports Ed25519 and ECDSA P-256 OS CA keys (Talos uses Ed25519 since v1.x).
 // Ed25519 signing is deterministic by design (RFC 8032); ECDSA uses RFC 6979.
-func generateClientConfiguration(secretsBundle *secrets.Bundle, clusterName string, notBefore, notAfter time.Time) (clientConfiguration, error) {
+func generateClientConfiguration(secretsBundle *secrets.Bundle, clusterName string, roles role.Set, notBefore, notAfter time.Time) (clientConfiguration, error) {
 	caCertBlock, _ := pem.Decode(secretsBundle.Certs.OS.Crt)
 	if caCertBlock == nil {
 		return clientConfiguration{}, fmt.Errorf("error decoding OS CA certificate PEM")
@@ -240,6 +254,12 @@ func generateClientConfiguration(secretsBundle *secrets.Bundle, clusterName stri
 	}
 
 	info := fmt.Sprintf("talos-clientconfig:v1:%s:%d:%d", clusterName, notBefore.Unix(), notAfter.Unix())
+
+	// admin certificates keep the original derivation, so configs generated before roles were supported stay byte-identical
+	if !slices.Equal(roles.Strings(), []string{string(role.Admin)}) {
+		info += ":" + strings.Join(roles.Strings(), ",")
+	}
+
 	deterministicReader := hkdf.New(sha256.New, secretsBundle.Certs.OS.Key, []byte("talos-clientconfig-v1"), []byte(info))
 
 	serialBytes := make([]byte, 16)
@@ -250,7 +270,7 @@ func generateClientConfiguration(secretsBundle *secrets.Bundle, clusterName stri
 	template := &stdlibx509.Certificate{
 		SerialNumber: new(big.Int).SetBytes(serialBytes),
 		Subject: pkix.Name{
-			Organization: []string{"os:admin"},
+			Organization: roles.Strings(),
 		},
 		NotBefore:   notBefore,
 		NotAfter:    notAfter,
@@ -328,6 +348,34 @@ func generateClientConfiguration(secretsBundle *secrets.Bundle, clusterName stri
 	}, nil
 }
 
+// clientRolesValidators validates a list of Talos API roles for generated client certificates.
+func clientRolesValidators() []validator.List {
+	return []validator.List{
+		listvalidator.SizeAtLeast(1),
+		listvalidator.ValueStringsAre(stringvalidator.OneOf(role.All.Strings()...)),
+	}
+}
+
+// clientRolesToRoleSet converts the roles attribute of a client configuration, defaulting to os:admin when not set.
+func clientRolesToRoleSet(roles []types.String) (role.Set, error) {
+	if len(roles) == 0 {
+		return role.MakeSet(role.Admin), nil
+	}
+
+	names := make([]string, 0, len(roles))
+
+	for _, r := range roles {
+		names = append(names, r.ValueString())
+	}
+
+	set, unknownRoles := role.Parse(names)
+	if len(unknownRoles) > 0 {
+		return role.Set{}, fmt.Errorf("unknown roles: %s", strings.Join(unknownRoles, ", "))
+	}
+
+	return set, nil
+}
+
 // parseCAPrivateKey parses a PEM block into either an ed25519.PrivateKey or *ecdsa.PrivateKey.
 func parseCAPrivateKey(block *pem.Block) (any, error) {
 	switch block.Type {
@@ -366,7 +414,7 @@ type clientConfigTimestampError struct {
 
 func (e *clientConfigTimestampError) Error() string { return e.detail }
 
-// resolveClientConfigTimestamps resolves notBefore/notAfter for the admin client certificate.
+// resolveClientConfigTimestamps resolves notBefore/notAfter for the generated client certificate.
 // When notBeforeStr is non-empty it parses the RFC3339 timestamp and adds crtTTLStr (default 87600h).
 // When notBeforeStr is empty it reads the timestamps from the OS CA PEM.
 func resolveClientConfigTimestamps(notBeforeStr, crtTTLStr string, osCACert []byte) (notBefore, notAfter time.Time, tsErr *clientConfigTimestampError) {
@@ -504,6 +552,67 @@ func machineSecretsToSecretsBundle(model talosMachineSecretsResourceModelV1) (*s
 	return secretsBundle, nil
 }
 
+// machineSecretsToSecretsYAML serializes the machine secrets into the secrets.yaml format of `talosctl gen secrets`,
+// which is accepted by `talosctl gen config --with-secrets` and by ImportState.
+func machineSecretsToSecretsYAML(model talosMachineSecretsResourceModelV1) (string, error) {
+	secretsBundle, err := machineSecretsToSecretsBundle(model)
+	if err != nil {
+		return "", err
+	}
+
+	// secrets generated for talos < 1.2 have no aggregator CA
+	if len(secretsBundle.Certs.K8sAggregator.Crt) == 0 {
+		secretsBundle.Certs.K8sAggregator = nil
+	}
+
+	secretsYAML, err := yaml.Marshal(secretsBundle)
+	if err != nil {
+		return "", err
+	}
+
+	return string(secretsYAML), nil
+}
+
+// secretsBundleFromYAML parses a secrets.yaml as generated by `talosctl gen secrets`.
+func secretsBundleFromYAML(data []byte) (*secrets.Bundle, error) {
+	var secretsBundle *secrets.Bundle
+	if err := yaml.Unmarshal(data, &secretsBundle); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal machine secrets: %w", err)
+	}
+
+	if err := validateSecretsBundle(secretsBundle); err != nil {
+		return nil, err
+	}
+
+	return secretsBundle, nil
+}
+
+// validateSecretsBundle checks that the bundle holds all secrets secretsBundleTomachineSecrets needs.
+func validateSecretsBundle(secretsBundle *secrets.Bundle) error {
+	if secretsBundle == nil || secretsBundle.Cluster == nil || secretsBundle.Secrets == nil || secretsBundle.TrustdInfo == nil || secretsBundle.Certs == nil {
+		return errors.New("machine secrets are incomplete")
+	}
+
+	for _, ca := range []struct {
+		name string
+		ca   *x509.PEMEncodedCertificateAndKey
+	}{
+		{"etcd", secretsBundle.Certs.Etcd},
+		{"k8s", secretsBundle.Certs.K8s},
+		{"os", secretsBundle.Certs.OS},
+	} {
+		if ca.ca == nil || len(ca.ca.Crt) == 0 || len(ca.ca.Key) == 0 {
+			return fmt.Errorf("machine secrets have no %s CA key", ca.name)
+		}
+	}
+
+	if secretsBundle.Certs.K8sServiceAccount == nil || len(secretsBundle.Certs.K8sServiceAccount.Key) == 0 {
+		return errors.New("machine secrets have no service account key")
+	}
+
+	return nil
+}
+
 func validateVersionContract(version string) (*config.VersionContract, error) {
 	versionContract, err := config.ParseContractFromVersion(version)
 	if err != nil {
@@ -589,6 +698,28 @@ func (f *talosClientFactory) Nodes() []string {
 	return f.nodes
 }
 
+// resolveClientConfiguration builds the Talos client config from the write-only client_configuration_wo,
+// if set, or the regular client_configuration attribute.
+func resolveClientConfiguration(ctx context.Context, clientConfigurationWO, clientConfiguration basetypes.ObjectValue) (*clientconfig.Config, error) {
+	var clientObj basetypes.ObjectValue
+
+	switch {
+	case !clientConfigurationWO.IsNull() && !clientConfigurationWO.IsUnknown():
+		clientObj = clientConfigurationWO
+	case !clientConfiguration.IsNull():
+		clientObj = clientConfiguration
+	default:
+		return nil, errors.New("no client configuration available")
+	}
+
+	ca, cert, key, errMsg, ok := getClientConfigurationValues(ctx, clientObj)
+	if !ok {
+		return nil, errors.New(errMsg)
+	}
+
+	return talosClientTFConfigToTalosClientConfig("dynamic", ca, cert, key)
+}
+
 type talosVersionValidator struct{}
 
 func talosVersionValid() talosVersionValidator {